@@ -0,0 +1,48 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// ErrAppIgnored is returned when a failed app is left stopped by its OnFailure policy
+var ErrAppIgnored = errors.New("app failure ignored")
+
+// Action is what the systemd service does about a failed app
+type Action int
+
+const (
+	// ActionRestart restarts the failed app
+	ActionRestart Action = iota
+	// ActionIgnore leaves the failed app stopped, the other apps keep running
+	ActionIgnore
+	// ActionShutdown gracefully shuts down all apps, Start returns the app error
+	ActionShutdown
+)
+
+// FailureFunc decides what to do about a failed app, the error is either returned by the
+// app Start or by its status check
+type FailureFunc func(ctx context.Context, app string, err error) Action
+
+// OnFailureFunc returns an OnFailure calling fn on every failure of the app, either
+// returning from Start with an error or failing a status check, to decide what to do
+func OnFailureFunc(fn FailureFunc) *OnFailure {
+	return &OnFailure{name: "func", retry: math.MaxInt, fn: fn}
+}
+
+// action returns what to do about the app failure
+func (o *OnFailure) action(ctx context.Context, app string, err error) Action {
+	if o.fn != nil {
+		return o.fn(ctx, app, err)
+	}
+
+	switch {
+	case o.policy != nil, o.Equal(OnFailureRestart):
+		return ActionRestart
+	case o.Equal(OnFailureIgnore):
+		return ActionIgnore
+	default:
+		return ActionShutdown
+	}
+}
@@ -0,0 +1,60 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestOnFailureShutdownAll(t *testing.T) {
+	boom := errors.New("boom")
+	h := sysdtest.NewHarness(t)
+	other := h.NewApp("other")
+	h.NewApp("postgres", sysd.WithOnFailure(sysd.OnFailureShutdownAll), sysd.WithPriority(1)).FailStarts(1, boom)
+	h.Start()
+
+	if err := h.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("Start returned %v, want the error of the critical app %v", err, boom)
+	}
+	if other.Running() {
+		t.Error("other app still running after the critical app failed")
+	}
+	if !errors.Is(other.Cause(), sysd.ErrShutdown) {
+		t.Errorf("other app cancelled with %v, want a graceful shutdown", other.Cause())
+	}
+}
+
+func TestOnFailureFunc(t *testing.T) {
+	boom := errors.New("boom")
+	rec := &recorder{}
+	failures := 0
+	// restart on the first failure, escalate on the second
+	onFailure := sysd.OnFailureFunc(func(_ context.Context, app string, err error) sysd.Action {
+		rec.record(app + ": " + err.Error())
+		if failures++; failures > 1 {
+			return sysd.ActionShutdown
+		}
+		return sysd.ActionRestart
+	})
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app", sysd.WithOnFailure(onFailure)).FailStarts(2, boom)
+	h.Start()
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !h.Returned() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := h.Wait(); !errors.Is(err, boom) {
+		t.Fatalf("Start returned %v, want %v once the callback escalated", err, boom)
+	}
+	if n := app.Starts(); n != 2 {
+		t.Errorf("app started %d times, want 2", n)
+	}
+	if calls := rec.take(); len(calls) != 2 || calls[0] != "app: boom" {
+		t.Errorf("callback called with %q, want twice with the app name and error", calls)
+	}
+}
@@ -0,0 +1,532 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errAppNotRunning is the reply to a stop command for an app which is not running
+var errAppNotRunning = errors.New("app not running")
+
+// commandKind is what an app actor is asked to do
+type commandKind int
+
+const (
+	// cmdStart starts the app
+	cmdStart commandKind = iota
+	// cmdStop stops the app, waiting for it to return
+	cmdStop
+	// cmdRestart stops the app if it is running and starts it again
+	cmdRestart
+	// cmdStatus acts on a failed status check of the app
+	cmdStatus
+	// cmdPause stops the app until cmdResume
+	cmdPause
+	// cmdResume starts a paused app again
+	cmdResume
+	// cmdUnquarantine clears the quarantine of the app and starts it again
+	cmdUnquarantine
+	// cmdRemove stops the app and ends its actor
+	cmdRemove
+)
+
+// command is sent to the actor of an app, which replies once it has handled it
+type command struct {
+	kind commandKind
+	// ctx bounds waiting for the app to stop
+	ctx context.Context
+	// cause is the cancellation cause of a stopped app, or the error of a failed status check
+	cause error
+	// restored starts the app with a restored context, see IsRestored
+	restored bool
+	// hold keeps a stopped app counted as running by the run, so it can be started again
+	hold bool
+	// onlyRunning makes a stop command reply errAppNotRunning for an app which is not running
+	onlyRunning bool
+	// drain waits for a removed app to stop
+	drain bool
+	reply chan error
+}
+
+// actor manages an app during a run. it handles the commands sent to the app one by one,
+// so starting, stopping and restarting the app never interleave
+type actor struct {
+	app  *appItem
+	run  *runState
+	cmds chan command
+	// done is closed once the actor returned
+	done chan struct{}
+
+	// the fields below are only used by the actor goroutine
+
+	// exited is closed once the started app returns, nil while the app is not running
+	exited <-chan struct{}
+	// held is set for apps paused or stopped by StopApp, the run waits for them to start again
+	held bool
+	// live is whether the app is counted as running by the run
+	live bool
+}
+
+// spawnActor starts the actor of the app for the run, a held app counts as running until it is
+// started. it returns false if the run is ending or the app is no longer registered
+func (s *Systemd) spawnActor(run *runState, app *appItem, held bool) bool {
+	a := &actor{app: app, run: run, cmds: make(chan command), done: make(chan struct{}), held: held}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.apps[app.name] != app {
+		return false
+	}
+	a.updateLive()
+	if !run.spawn(func() { s.runActor(a) }) {
+		a.held = false
+		a.updateLive()
+		return false
+	}
+	app.actor = a
+	return true
+}
+
+// runActor handles the commands of the app until the app is removed, or the run ends
+// and the app has stopped
+func (s *Systemd) runActor(a *actor) {
+	defer close(a.done)
+	defer func() {
+		// a removed app may still be stopping, it no longer counts for the run either way
+		a.held, a.exited = false, nil
+		a.updateLive()
+		s.mu.Lock()
+		if a.app.actor == a {
+			a.app.actor = nil
+		}
+		s.mu.Unlock()
+	}()
+
+	ending := a.run.ctx.Done()
+	for {
+		if ending == nil && !a.running() {
+			return
+		}
+		select {
+		case cmd := <-a.cmds:
+			if s.handleCommand(a, cmd) {
+				return
+			}
+		case <-a.exited:
+			a.updateLive()
+		case <-ending:
+			ending = nil
+		}
+	}
+}
+
+// sendCommand sends the command to the actor of the app and waits for the reply.
+// sent is false if the app has no actor, because the systemd service is not running
+func (s *Systemd) sendCommand(app *appItem, cmd command) (sent bool, err error) {
+	s.mu.RLock()
+	a := app.actor
+	s.mu.RUnlock()
+
+	if a == nil {
+		return false, nil
+	}
+	if cmd.ctx == nil {
+		cmd.ctx = context.Background()
+	}
+	cmd.reply = make(chan error, 1)
+	select {
+	case a.cmds <- cmd:
+	case <-a.done:
+		return false, nil
+	}
+	return true, <-cmd.reply
+}
+
+// handleCommand handles a command in the actor goroutine, it returns true if the actor ends
+func (s *Systemd) handleCommand(a *actor, cmd command) bool {
+	app := a.app
+	// the run is ending, the app is not started again
+	ending := a.run.ctx.Err() != nil
+	switch cmd.kind {
+	case cmdStart:
+		// a restart is overtaken by commands handled since the app was stopped, like
+		// RestartApp starting it already or Pause keeping it stopped
+		if a.running() || cmd.restored && s.appState(app) != AppRestarting {
+			cmd.reply <- nil
+			break
+		}
+		s.startByActor(a, cmd.restored)
+		cmd.reply <- nil
+	case cmdStop:
+		running := s.appState(app).running()
+		if cmd.onlyRunning && !running {
+			cmd.reply <- errAppNotRunning
+			break
+		}
+		// held before stopping, so the run does not see the app as stopped for good meanwhile
+		a.held = a.held || cmd.hold && running
+		err := s.stopApp(cmd.ctx, app, cmd.cause)
+		a.updateLive()
+		cmd.reply <- err
+	case cmdRestart:
+		if ending {
+			cmd.reply <- ErrNotRunning
+			break
+		}
+		cmd.reply <- s.restartByActor(a, cmd.ctx)
+	case cmdStatus:
+		s.handleStatusFailure(a, cmd.cause, cmd.reply)
+	case cmdPause:
+		if ending {
+			cmd.reply <- ErrNotRunning
+			break
+		}
+		cmd.reply <- s.pauseByActor(a, cmd.ctx)
+	case cmdResume:
+		s.mu.Lock()
+		if app.state != AppPaused {
+			s.mu.Unlock()
+			cmd.reply <- ErrAppNotPaused
+			break
+		}
+		if ending {
+			s.mu.Unlock()
+			cmd.reply <- ErrNotRunning
+			break
+		}
+		app.state = AppStarting
+		app.failures = 0
+		s.mu.Unlock()
+
+		s.logger.Info("Resuming app %q", app.Name())
+		s.startByActor(a, true)
+		cmd.reply <- nil
+	case cmdUnquarantine:
+		if s.clearQuarantine(app) {
+			s.logger.Info("Unquarantining app %q", app.Name())
+			if ending {
+				s.saveState()
+			} else {
+				s.startByActor(a, true)
+			}
+		}
+		cmd.reply <- nil
+	case cmdRemove:
+		if cmd.drain {
+			_ = s.stopApp(context.Background(), app, cmd.cause)
+		} else {
+			s.mu.RLock()
+			cancel := app.cancel
+			s.mu.RUnlock()
+			if cancel != nil {
+				cancel(cmd.cause)
+			}
+		}
+		cmd.reply <- nil
+		return true
+	}
+	return false
+}
+
+// startByActor starts the app, it is no longer held
+func (s *Systemd) startByActor(a *actor, restored bool) {
+	ctx := a.run.ctx
+	if restored {
+		ctx = restoredContext(ctx)
+	}
+	a.held = false
+	if exited := s.startApp(ctx, a.app, a.run); exited != nil {
+		a.exited = exited
+	}
+	a.updateLive()
+}
+
+// restartByActor stops the app if it is running and starts it again. once the app is cancelled
+// it is waited for up to its shutdown timeout regardless of the context, and started again even
+// if it did not stop in time, in which case the error is returned
+func (s *Systemd) restartByActor(a *actor, ctx context.Context) error {
+	app := a.app
+	_, end := s.startSpan(ctx, OperationRestart, app.Name())
+	// the caller going away must not leave the app cancelled but never started again
+	err := s.stopApp(context.WithoutCancel(ctx), app, ErrAppRestarted)
+	defer end(err)
+
+	s.mu.Lock()
+	app.restarts++
+	app.failures = 0
+	s.mu.Unlock()
+	s.setState(app, AppRestarting, nil)
+	s.emit(EventAppRestarted, app.Name(), nil)
+
+	// a restarted paused or stopped app runs again, it is no longer held
+	s.startByActor(a, true)
+	return err
+}
+
+// pauseByActor stops the app and keeps it paused, held by the run until it is resumed
+func (s *Systemd) pauseByActor(a *actor, ctx context.Context) error {
+	if s.appState(a.app) == AppPaused {
+		return nil
+	}
+
+	held := a.held
+	a.held = true
+	if err := s.stopApp(ctx, a.app, ErrAppPaused); err != nil {
+		a.held = held
+		a.updateLive()
+		return err
+	}
+	a.updateLive()
+	s.setState(a.app, AppPaused, nil)
+	return nil
+}
+
+// handleStatusFailure acts on a failed status check of the app and replies once done
+func (s *Systemd) handleStatusFailure(a *actor, err error, reply chan<- error) {
+	app, run := a.app, a.run
+	s.logger.with("app", app.Name(), "error", err).Error("app %q status check failed: %v", app.Name(), err)
+	s.mu.Lock()
+	app.lastErr = err
+	s.mu.Unlock()
+	s.recordFailure(app, err)
+	s.emit(EventAppFailed, app.Name(), err)
+
+	// the app was stopped or paused since it was checked, it is not acted on behind the operator's back
+	if !s.appState(app).running() {
+		reply <- nil
+		return
+	}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		s.logger.Error("app %q panicked in its status check: %v\n%s", app.Name(), panicErr.Value, panicErr.Stack)
+		switch s.appPanicPolicy(app) {
+		case FailFastOnPanic:
+			run.fail(newAppError(app.Name(), PhaseStatus, err))
+			reply <- nil
+			return
+		case IgnorePanic:
+			_ = s.stopApp(context.Background(), app, err)
+			s.setState(app, AppFailed, err)
+			a.updateLive()
+			reply <- nil
+			return
+		}
+	}
+
+	if s.inMaintenance(app) {
+		s.logger.Warn("app %q is in maintenance, not acting on its failed status check", app.Name())
+		reply <- nil
+		return
+	}
+	s.mu.RLock()
+	onFailure := app.onFailure
+	s.mu.RUnlock()
+
+	cause := fmt.Errorf("%w: %v", ErrStatusCheckFailed, err)
+	switch onFailure.action(run.ctx, app.Name(), err) {
+	case ActionRestart:
+		if !s.restartAllowed(app, err) {
+			s.logger.Error("app %q is not restarted anymore by its restart policy", app.Name())
+			_ = s.stopApp(context.Background(), app, cause)
+			s.setState(app, AppFailed, err)
+			a.updateLive()
+			break
+		}
+		if err := s.checkCrashLoop(app); err != nil {
+			_ = s.stopApp(context.Background(), app, cause)
+			// stopping the app moved it to stopped, keep it quarantined
+			s.setState(app, AppQuarantined, nil)
+			a.updateLive()
+			break
+		}
+		s.logger.with("app", app.Name(), "state", AppRestarting.String()).Info("Restarting app %q", app.Name())
+		if err := s.recordRestart(app); err != nil {
+			run.fail(newAppError(app.Name(), PhaseRestart, err))
+			break
+		}
+		s.mu.Lock()
+		app.failures++
+		s.mu.Unlock()
+
+		group := s.restartGroup(app)
+		if len(group) == 1 {
+			s.restartFailed(a, cause)
+			break
+		}
+		// the other actors of the group are asked to restart their apps, which this actor must
+		// not wait for. the status check waits for the reply so the app is not checked meanwhile
+		go func() {
+			s.restartApps(run, app, group, cause)
+			reply <- nil
+		}()
+		return
+	case ActionShutdown:
+		s.logger.with("app", app.Name(), "error", err).Error("Critical app %q failed, shutting down", app.Name())
+		run.fail(newAppError(app.Name(), PhaseStatus, cause))
+	case ActionIgnore:
+		s.logger.Info("Ignoring app %q failure", app.Name())
+		// the app stays registered as failed, like an ignored app failing in Start
+		_ = s.stopApp(context.Background(), app, cause)
+		s.setState(app, AppFailed, err)
+		a.updateLive()
+	}
+	reply <- nil
+}
+
+// restartFailed restarts the app which failed its status check on its own
+func (s *Systemd) restartFailed(a *actor, cause error) {
+	app := a.app
+	_, end := s.startSpan(a.run.ctx, OperationRestart, app.Name())
+	defer end(cause)
+
+	_ = s.stopApp(context.Background(), app, cause)
+	s.setState(app, AppRestarting, nil)
+	s.emit(EventAppRestarted, app.Name(), cause)
+	s.startByActor(a, true)
+}
+
+// running returns true if the started app has not returned yet
+func (a *actor) running() bool {
+	if a.exited == nil {
+		return false
+	}
+	select {
+	case <-a.exited:
+		a.exited = nil
+		return false
+	default:
+		return true
+	}
+}
+
+// updateLive counts the app as running by the run while it runs or is held
+func (a *actor) updateLive() {
+	live := a.held || a.running()
+	if live == a.live {
+		return
+	}
+	a.live = live
+	if live {
+		a.run.addLive(1)
+	} else {
+		a.run.addLive(-1)
+	}
+}
+
+// runState holds what is needed to start apps while the systemd service is running
+type runState struct {
+	ctx context.Context
+	// actors counts the running app actors
+	actors sync.WaitGroup
+	// done is closed once Start returns err
+	done chan struct{}
+	err  error
+
+	mu sync.Mutex
+	// closed is set once the run waits for its actors to end, no actor is spawned afterwards
+	closed bool
+	// live counts the apps running or held, idle is closed while there are none
+	live int
+	idle chan struct{}
+	// failures are the app failures ending the run, failed is closed on the first one
+	failures []error
+	failed   chan struct{}
+}
+
+func newRunState(ctx context.Context) *runState {
+	idle := make(chan struct{})
+	close(idle)
+	return &runState{ctx: ctx, done: make(chan struct{}), idle: idle, failed: make(chan struct{})}
+}
+
+// spawn runs f in a goroutine counted by actors, unless the run is closed
+func (r *runState) spawn(f func()) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return false
+	}
+	r.actors.Add(1)
+	go func() {
+		defer r.actors.Done()
+		f()
+	}()
+	return true
+}
+
+// close stops spawning actors, so the actors can be waited for
+func (r *runState) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+}
+
+// addLive adds delta to the apps running or held
+func (r *runState) addLive(delta int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.live == 0 && delta > 0 {
+		r.idle = make(chan struct{})
+	}
+	r.live += delta
+	if r.live == 0 && delta < 0 {
+		close(r.idle)
+	}
+}
+
+// allStopped returns a channel closed once no app is running or held
+func (r *runState) allStopped() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.idle
+}
+
+// fail records a failure of an app which ends the run
+func (r *runState) fail(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures = append(r.failures, err)
+	if len(r.failures) == 1 {
+		close(r.failed)
+	}
+}
+
+// firstFailure returns the failure which ended the run, nil if none
+func (r *runState) firstFailure() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.failures) == 0 {
+		return nil
+	}
+	return r.failures[0]
+}
+
+// joinFailures returns err joined with the other failures of the run, so all apps failed
+// at once are reported
+func (r *runState) joinFailures(err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	all := []error{err}
+	for _, other := range r.failures {
+		if other != err {
+			all = append(all, other)
+		}
+	}
+	if len(all) == 1 {
+		return err
+	}
+	return errors.Join(all...)
+}
@@ -0,0 +1,176 @@
+package sysd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAlertDebounce is how long alerts of the same event of an app are held back after one is sent
+	DefaultAlertDebounce = time.Minute
+	// alertTimeout bounds delivering an alert
+	alertTimeout = 10 * time.Second
+)
+
+// Alert is a failure the notifiers are told about
+type Alert struct {
+	Time  time.Time `json:"time"`
+	Type  string    `json:"type"`
+	App   string    `json:"app,omitempty"`
+	Error string    `json:"error,omitempty"`
+	// Suppressed is the number of alerts of the same event of the app held back since the previous one
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// String returns a human readable form of the alert
+func (a Alert) String() string {
+	msg := a.Type
+	if a.App != "" {
+		msg = fmt.Sprintf("app %q %s", a.App, a.Type)
+	}
+	if a.Error != "" {
+		msg += ": " + a.Error
+	}
+	if a.Suppressed > 0 {
+		msg += fmt.Sprintf(" (%d similar alerts suppressed)", a.Suppressed)
+	}
+	return msg
+}
+
+// Notifier delivers alerts, e.g. to a chat or an incident management system
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// NotifierFunc is a function implementing Notifier
+type NotifierFunc func(ctx context.Context, a Alert) error
+
+func (f NotifierFunc) Notify(ctx context.Context, a Alert) error {
+	return f(ctx, a)
+}
+
+// alertKey identifies alerts which are debounced together
+type alertKey struct {
+	typ EventType
+	app string
+}
+
+type alertState struct {
+	last       time.Time
+	suppressed int
+}
+
+// alerter sends alerts for failure events to the notifiers, holding back repeated alerts
+// of the same event of an app for the debounce period so a crash loop does not cause an alert storm
+type alerter struct {
+	mu        sync.Mutex
+	notifiers []Notifier
+	debounce  time.Duration
+	sent      map[alertKey]*alertState
+}
+
+// NotifyOnFailure adds a notifier alerted when an app fails or is quarantined and when apps do not
+// stop within the graceful shutdown timeout, e.g. WebhookNotifier or SlackNotifier.
+// alerts are debounced, see SetAlertDebounce, and delivery errors are logged
+func (s *Systemd) NotifyOnFailure(n Notifier) {
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+
+	s.alerts.notifiers = append(s.alerts.notifiers, n)
+}
+
+// SetAlertDebounce sets how long alerts of the same event of an app are held back after one is sent,
+// the next alert tells how many were held back. DefaultAlertDebounce is used if zero
+func (s *Systemd) SetAlertDebounce(d time.Duration) {
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+
+	s.alerts.debounce = d
+}
+
+// alerting returns true if the event is a failure notifiers are told about
+func alerting(typ EventType) bool {
+	return typ == EventAppFailed || typ == EventAppQuarantined || typ == EventShutdownTimeout
+}
+
+// record sends an alert for the event to the notifiers unless it is debounced
+func (a *alerter) record(s *Systemd, e Event) {
+	if !alerting(e.Type) {
+		return
+	}
+
+	a.mu.Lock()
+	if len(a.notifiers) == 0 {
+		a.mu.Unlock()
+		return
+	}
+	debounce := a.debounce
+	if debounce == 0 {
+		debounce = DefaultAlertDebounce
+	}
+	if a.sent == nil {
+		a.sent = make(map[alertKey]*alertState)
+	}
+	key := alertKey{typ: e.Type, app: e.App}
+	state, ok := a.sent[key]
+	if !ok {
+		state = &alertState{}
+		a.sent[key] = state
+	}
+	if !state.last.IsZero() && e.Time.Sub(state.last) < debounce {
+		state.suppressed++
+		a.mu.Unlock()
+		return
+	}
+	alert := Alert{Time: e.Time, Type: e.Type.String(), App: e.App, Suppressed: state.suppressed}
+	if e.Err != nil {
+		alert.Error = e.Err.Error()
+	}
+	state.last, state.suppressed = e.Time, 0
+	notifiers := append([]Notifier{}, a.notifiers...)
+	a.mu.Unlock()
+
+	// alerts are delivered in the background, a slow notifier must not hold up the apps
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+		defer cancel()
+		for _, n := range notifiers {
+			if err := n.Notify(ctx, alert); err != nil {
+				s.logger.Error("Failed to send alert %q: %v", alert, err)
+			}
+		}
+	}()
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// WebhookNotifier posts each alert as JSON to the url
+func WebhookNotifier(url string) Notifier {
+	return &webhookNotifier{url: url, client: &http.Client{}}
+}
+
+func (w *webhookNotifier) Notify(ctx context.Context, a Alert) error {
+	return postJSON(ctx, w.client, w.url, a)
+}
+
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// SlackNotifier posts each alert as a message to a Slack incoming webhook url
+func SlackNotifier(webhookURL string) Notifier {
+	return &slackNotifier{url: webhookURL, client: &http.Client{}}
+}
+
+func (n *slackNotifier) Notify(ctx context.Context, a Alert) error {
+	return postJSON(ctx, n.client, n.url, map[string]string{
+		"text": fmt.Sprintf(":rotating_light: %s at %s", a, a.Time.Format(time.RFC3339)),
+	})
+}
@@ -0,0 +1,85 @@
+package sysd_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestAlertsAreDebounced(t *testing.T) {
+	alerts := make(chan sysd.Alert, 10)
+	h := sysdtest.NewHarness(t)
+	h.Systemd.NotifyOnFailure(sysd.NotifierFunc(func(_ context.Context, a sysd.Alert) error {
+		alerts <- a
+		return nil
+	}))
+	app := h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureRestart.Retry(10).RetryTimeout(time.Second)))
+	app.FailStarts(3, errors.New("boom"))
+	h.Start()
+
+	// a crash loop alerts once
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !app.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !app.Running() {
+		t.Fatal("app not running after failing to start")
+	}
+	a := <-alerts
+	if a.Type != sysd.EventAppFailed.String() || a.App != "app" || a.Error != "boom" || a.Suppressed != 0 {
+		t.Errorf("first alert is %+v", a)
+	}
+	select {
+	case a := <-alerts:
+		t.Fatalf("alert %q sent within the debounce period", a)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// the next failure once the debounce period passed tells about the held back alerts
+	h.Clock.Advance(sysd.DefaultAlertDebounce)
+	app.FlapStatus(errors.New("unhealthy"))
+	// the failed start events are still buffered, wait for the alert of the status check instead
+	deadline := time.After(sysdtest.WaitTimeout)
+	for {
+		select {
+		case a := <-alerts:
+			if a.Suppressed != 2 || a.Error != "unhealthy" {
+				t.Errorf("alert after the debounce period is %+v, want 2 suppressed", a)
+			}
+			return
+		case <-time.After(5 * time.Millisecond):
+			h.Clock.Advance(time.Second)
+		case <-deadline:
+			t.Fatal("no alert after the debounce period")
+		}
+	}
+}
+
+func TestSlackNotifierPostsText(t *testing.T) {
+	posted := make(chan map[string]string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		posted <- body
+	}))
+	defer srv.Close()
+
+	alert := sysd.Alert{Time: time.Now(), Type: "app_failed", App: "db", Error: "boom", Suppressed: 3}
+	if err := sysd.SlackNotifier(srv.URL).Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify returned %v", err)
+	}
+	text := (<-posted)["text"]
+	for _, want := range []string{`app "db" app_failed: boom`, "3 similar alerts suppressed"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("slack text %q does not contain %q", text, want)
+		}
+	}
+}
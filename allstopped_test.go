@@ -0,0 +1,56 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func oneShot(name string) sysd.App {
+	return sysd.AppFunc(name, func(ctx context.Context) error { return nil }, nil)
+}
+
+func TestStartReturnsWhenAllAppsStopped(t *testing.T) {
+	tests := []struct {
+		policy sysd.AllStoppedPolicy
+		want   error
+	}{
+		{sysd.AllStoppedReturn, nil},
+		{sysd.AllStoppedError, sysd.ErrAllAppsStopped},
+	}
+	for _, tt := range tests {
+		h := sysdtest.NewHarness(t)
+		h.Systemd.SetAllStoppedPolicy(tt.policy)
+		for _, name := range []string{"migrate", "seed"} {
+			if err := h.Systemd.Add(oneShot(name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		h.Start()
+		if err := h.Wait(); !errors.Is(err, tt.want) {
+			t.Fatalf("policy %d: Start returned %v, want %v", tt.policy, err, tt.want)
+		}
+	}
+}
+
+func TestStartWaitsWhenAllAppsStopped(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.Systemd.SetAllStoppedPolicy(sysd.AllStoppedWait)
+	if err := h.Systemd.Add(oneShot("migrate")); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("migrate", sysd.AppStopped)
+
+	time.Sleep(50 * time.Millisecond)
+	if h.Returned() {
+		t.Fatal("Start returned while the context is alive")
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Start returned %v, want nil", err)
+	}
+}
@@ -0,0 +1,43 @@
+package sysd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Phase is the lifecycle phase an app failed in
+type Phase string
+
+const (
+	// PhaseStart is a failure returned from, or a panic in, the app Start
+	PhaseStart Phase = "start"
+	// PhaseStatus is a failed status check
+	PhaseStatus Phase = "status"
+	// PhaseRestart is a failure to restart the app, e.g. ErrRestartBudgetExceeded
+	PhaseRestart Phase = "restart"
+)
+
+// AppError is the error returned by Start for a failure of an app, use errors.As to find the failed app
+type AppError struct {
+	App   string
+	Phase Phase
+	Err   error
+}
+
+// Error implements error
+func (e *AppError) Error() string {
+	return fmt.Sprintf("app %q %s failed: %v", e.App, e.Phase, e.Err)
+}
+
+// Unwrap returns the error of the app
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// newAppError returns an AppError for the app, restart budget failures are always in the restart phase
+func newAppError(app string, phase Phase, err error) *AppError {
+	if errors.Is(err, ErrRestartBudgetExceeded) {
+		phase = PhaseRestart
+	}
+	return &AppError{App: app, Phase: phase, Err: err}
+}
@@ -0,0 +1,81 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStartReturnsAppError(t *testing.T) {
+	boom := errors.New("boom")
+	h := sysdtest.NewHarness(t)
+	h.NewApp("cache")
+	h.NewApp("db", sysd.WithOnFailure(sysd.OnFailureShutdownAll)).FailStarts(1, boom)
+	h.Start()
+
+	err := h.Wait()
+	var appErr *sysd.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Start returned %v, want an AppError", err)
+	}
+	if appErr.App != "db" || appErr.Phase != sysd.PhaseStart || !errors.Is(err, boom) {
+		t.Errorf("Start returned %#v, want the start failure of db with %v", appErr, boom)
+	}
+}
+
+func TestRestartBudgetAppErrorPhase(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithDefaultOnFailure(sysd.OnFailureRestart.Retry(10).RetryTimeout(time.Second)))
+	h.Systemd.SetRestartBudget(1, time.Hour)
+	h.NewApp("flaky").FailStarts(2, errors.New("flap"))
+	h.Start()
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !h.Returned() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	var appErr *sysd.AppError
+	if err := h.Wait(); !errors.As(err, &appErr) || appErr.Phase != sysd.PhaseRestart || appErr.App != "flaky" {
+		t.Errorf("Start returned %v, want a restart failure of flaky", err)
+	}
+}
+
+func TestStartJoinsFailuresDuringShutdown(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	// the slow app is stopped first, the other apps keep running meanwhile
+	h.NewApp("slow", sysd.WithPriority(10)).SetStopDelay(10 * time.Second)
+	h.NewApp("a", sysd.WithOnFailure(sysd.OnFailureShutdownAll)).FailStarts(1, errors.New("boom"))
+	release := make(chan struct{})
+	err := h.Systemd.Add(sysd.AppFunc("b", func(ctx context.Context) error {
+		<-release
+		return errors.New("boom")
+	}, nil), sysd.WithOnFailure(sysd.OnFailureShutdownAll))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForEvent(sysd.EventShutdownBegun, "")
+	close(release)
+	h.WaitForState("b", sysd.AppFailed)
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !h.Returned() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	err = h.Wait()
+	failed := make(map[string]sysd.Phase)
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			var appErr *sysd.AppError
+			if errors.As(e, &appErr) {
+				failed[appErr.App] = appErr.Phase
+			}
+		}
+	}
+	if len(failed) != 2 || failed["a"] != sysd.PhaseStart || failed["b"] != sysd.PhaseStart {
+		t.Errorf("Start returned %v, want the start failures of a and b joined", err)
+	}
+}
@@ -0,0 +1,280 @@
+package admind
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &Admind{}
+
+const (
+	// shutdownTimeout bounds waiting for in-flight requests on shutdown
+	shutdownTimeout = 5 * time.Second
+	// actionTimeout bounds waiting for an app to stop, restart or pause
+	actionTimeout = time.Minute
+)
+
+// Option configures the admin server
+type Option func(a *Admind)
+
+// WithToken requires requests to carry the token as "Authorization: Bearer <token>"
+func WithToken(token string) Option {
+	return func(a *Admind) {
+		a.token = token
+	}
+}
+
+// Admind serves a REST API to operate the systemd service:
+//
+//	GET  /apps                  the status of all apps
+//	POST /apps/{name}/restart   restart the app
+//	POST /apps/{name}/stop      stop the app
+//	POST /apps/{name}/pause     pause the app
+//	POST /apps/{name}/resume    resume the paused app
+//	GET  /events                lifecycle events as server-sent events
+type Admind struct {
+	Host string
+	Port int
+
+	sysd  *sysd.Systemd
+	token string
+
+	mu     sync.Mutex
+	server *http.Server
+	// streams is done on shutdown to end the event streams, which never go idle on their own
+	streams context.Context
+}
+
+func New(Host string, Port int, s *sysd.Systemd, opts ...Option) *Admind {
+	a := &Admind{Host: Host, Port: Port, sysd: s, streams: context.Background()}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Handler returns the handler serving the API, to mount it on another server
+func (a *Admind) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps", a.serveApps)
+	mux.HandleFunc("/apps/", a.serveAppAction)
+	mux.HandleFunc("/events", a.serveEvents)
+	return a.authenticate(mux)
+}
+
+func (a *Admind) Start(ctx context.Context) error {
+	streams, stopStreams := context.WithCancel(context.Background())
+	defer stopStreams()
+
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(a.Host, strconv.Itoa(a.Port)),
+		Handler: a.Handler(),
+	}
+
+	ln, err := sysd.Listen(ctx, a.Name(), "tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.server = srv
+	a.streams = streams
+	a.mu.Unlock()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopStreams()
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func (a *Admind) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// appStatus is the JSON form of sysd.AppStatus
+type appStatus struct {
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Uptime    string `json:"uptime"`
+	Restarts  int    `json:"restarts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+func (a *Admind) serveApps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	snapshot := a.sysd.Snapshot()
+	apps := make([]appStatus, 0, len(snapshot))
+	for _, app := range snapshot {
+		status := appStatus{
+			Name:     app.Name,
+			State:    app.State.String(),
+			Uptime:   app.Uptime.Round(time.Second).String(),
+			Restarts: app.Restarts,
+		}
+		if app.LastError != nil {
+			status.LastError = app.LastError.Error()
+		}
+		apps = append(apps, status)
+	}
+	writeJSON(w, http.StatusOK, apps)
+}
+
+// serveAppAction serves POST /apps/{name}/{action}
+func (a *Admind) serveAppAction(w http.ResponseWriter, r *http.Request) {
+	name, action, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/apps/"), "/")
+	if !ok || name == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.URL.Path))
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	// the action goes on if the client goes away, an app is not left halfway stopped
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), actionTimeout)
+	defer cancel()
+
+	var err error
+	switch action {
+	case "restart":
+		err = a.sysd.RestartApp(ctx, name)
+	case "stop":
+		err = a.sysd.StopApp(ctx, name)
+	case "pause":
+		err = a.sysd.Pause(ctx, name)
+	case "resume":
+		err = a.sysd.Resume(name)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown action %q", action))
+		return
+	}
+
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, map[string]string{"app": name, "action": action})
+	case errors.Is(err, sysd.ErrAppNotExists):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, sysd.ErrNotRunning), errors.Is(err, sysd.ErrAppPaused), errors.Is(err, sysd.ErrAppNotPaused):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// event is the JSON form of sysd.Event
+type event struct {
+	Type  string    `json:"type"`
+	App   string    `json:"app,omitempty"`
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+// serveEvents streams lifecycle events as server-sent events until the client goes away
+func (a *Admind) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	a.mu.Lock()
+	streams := a.streams
+	a.mu.Unlock()
+
+	events := a.sysd.Subscribe()
+	defer a.sysd.Unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-streams.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data := event{Type: e.Type.String(), App: e.App, Time: e.Time}
+			if e.Err != nil {
+				data.Error = e.Err.Error()
+			}
+			b, err := json.Marshal(data)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", data.Type, b); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func (a *Admind) Status(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.server == nil {
+		return errors.New("admind server is not running")
+	}
+	return nil
+}
+
+func (a *Admind) Name() string {
+	return "admind"
+}
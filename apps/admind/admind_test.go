@@ -0,0 +1,182 @@
+package admind
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// newServer returns an admin API server over a running harness with the app "app"
+func newServer(t *testing.T, opts ...Option) (*sysdtest.Harness, *httptest.Server) {
+	t.Helper()
+
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app")
+	h.NewApp("other")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	srv := httptest.NewServer(New("127.0.0.1", 0, h.Systemd, opts...).Handler())
+	t.Cleanup(srv.Close)
+	return h, srv
+}
+
+func do(t *testing.T, method, url, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestTokenRequired(t *testing.T) {
+	_, srv := newServer(t, WithToken("secret"))
+
+	if resp := do(t, http.MethodGet, srv.URL+"/apps", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request without token answered %s", resp.Status)
+	}
+	if resp := do(t, http.MethodGet, srv.URL+"/apps", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request with a wrong token answered %s", resp.Status)
+	}
+	if resp := do(t, http.MethodGet, srv.URL+"/apps", "secret"); resp.StatusCode != http.StatusOK {
+		t.Errorf("request with the token answered %s", resp.Status)
+	}
+}
+
+func TestListApps(t *testing.T) {
+	_, srv := newServer(t)
+
+	resp := do(t, http.MethodGet, srv.URL+"/apps", "")
+	var apps []appStatus
+	if err := json.NewDecoder(resp.Body).Decode(&apps); err != nil {
+		t.Fatal(err)
+	}
+	states := make(map[string]string)
+	for _, app := range apps {
+		states[app.Name] = app.State
+	}
+	if states["app"] != sysd.AppRunning.String() || states["other"] != sysd.AppRunning.String() {
+		t.Errorf("apps are %+v, want both running", apps)
+	}
+	if resp := do(t, http.MethodPost, srv.URL+"/apps", ""); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /apps answered %s", resp.Status)
+	}
+}
+
+func TestAppActions(t *testing.T) {
+	h, srv := newServer(t)
+
+	if resp := do(t, http.MethodPost, srv.URL+"/apps/app/pause", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("pause answered %s", resp.Status)
+	}
+	h.WaitForState("app", sysd.AppPaused)
+	if resp := do(t, http.MethodPost, srv.URL+"/apps/app/resume", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("resume answered %s", resp.Status)
+	}
+	h.WaitForState("app", sysd.AppRunning)
+
+	for path, code := range map[string]int{
+		"/apps/app/resume":    http.StatusConflict,
+		"/apps/missing/stop":  http.StatusNotFound,
+		"/apps/app/explode":   http.StatusNotFound,
+		"/apps/app":           http.StatusNotFound,
+		"/apps/other/restart": http.StatusOK,
+	} {
+		if resp := do(t, http.MethodPost, srv.URL+path, ""); resp.StatusCode != code {
+			t.Errorf("POST %s answered %s, want %d", path, resp.Status, code)
+		}
+	}
+	if resp := do(t, http.MethodGet, srv.URL+"/apps/app/stop", ""); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET of an action answered %s", resp.Status)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	_, srv := newServer(t)
+
+	resp := do(t, http.MethodGet, srv.URL+"/events", "")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("events content type is %q", ct)
+	}
+	if resp := do(t, http.MethodPost, srv.URL+"/apps/app/restart", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("restart answered %s", resp.Status)
+	}
+
+	// end the stream if the event never comes
+	timer := time.AfterFunc(sysdtest.WaitTimeout, func() { resp.Body.Close() })
+	defer timer.Stop()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var e event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatalf("event %q: %v", data, err)
+		}
+		if e.Type == sysd.EventAppRestarted.String() && e.App == "app" {
+			return
+		}
+	}
+	t.Fatalf("stream ended without the restart event: %v", scanner.Err())
+}
+
+func TestActionOutlivesClient(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app").SetStopDelay(time.Second)
+	h.NewApp("other")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	handler := New("127.0.0.1", 0, h.Systemd).Handler()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/apps/app/pause", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		handler.ServeHTTP(rec, req)
+	}()
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); app.Cause() == nil; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("app not cancelled by the pause")
+		}
+	}
+	// the client disconnects while the app is still stopping
+	cancel()
+
+	deadline := time.After(sysdtest.WaitTimeout)
+	for done := false; !done; {
+		select {
+		case <-served:
+			done = true
+		case <-time.After(5 * time.Millisecond):
+			h.Clock.Advance(100 * time.Millisecond)
+		case <-deadline:
+			t.Fatal("pause not served once the app stopped")
+		}
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("pause answered %d: %s", rec.Code, rec.Body)
+	}
+	h.WaitForState("app", sysd.AppPaused)
+}
@@ -0,0 +1,176 @@
+package amqp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mirzakhany/sysd"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var _ sysd.App = &Consumer{}
+
+// defaultPrefetch is how many deliveries are handed to the consumer before they are acknowledged
+const defaultPrefetch = 10
+
+// Handler processes a delivery, it is acknowledged if the handler returns nil
+// and rejected and requeued otherwise
+type Handler func(ctx context.Context, delivery amqp.Delivery) error
+
+// Queue is a queue declared on Start and consumed by the handler
+type Queue struct {
+	Name       string
+	Durable    bool
+	AutoDelete bool
+	Exclusive  bool
+	Args       amqp.Table
+}
+
+type consumer struct {
+	queue   Queue
+	handler Handler
+}
+
+// Consumer keeps a connection and a channel to the broker and dispatches the deliveries of
+// the declared queues to their handlers. a closed connection or channel fails Start, so the
+// app is restarted per its OnFailure policy
+type Consumer struct {
+	name     string
+	url      string
+	prefetch int
+
+	consumers []consumer
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func New(url string) *Consumer {
+	return &Consumer{name: "amqp", url: url, prefetch: defaultPrefetch}
+}
+
+// SetName sets the name of the app, amqp by default, to run several consumers in one systemd service
+func (c *Consumer) SetName(name string) {
+	c.name = name
+}
+
+// SetPrefetch sets how many deliveries are handed to the consumer before they are acknowledged
+func (c *Consumer) SetPrefetch(prefetch int) {
+	c.prefetch = prefetch
+}
+
+// AddQueue declares the queue on Start and dispatches its deliveries to the handler,
+// it must be called before Start
+func (c *Consumer) AddQueue(queue Queue, handler Handler) {
+	c.consumers = append(c.consumers, consumer{queue: queue, handler: handler})
+}
+
+func (c *Consumer) Start(ctx context.Context) error {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return fmt.Errorf("unable to connect to amqp broker: %w", err)
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("unable to open amqp channel: %w", err)
+	}
+	defer ch.Close()
+
+	if err := ch.Qos(c.prefetch, 0, false); err != nil {
+		return fmt.Errorf("unable to set amqp prefetch: %w", err)
+	}
+
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	var wg sync.WaitGroup
+	tags := make([]string, 0, len(c.consumers))
+	for _, cons := range c.consumers {
+		q := cons.queue
+		if _, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, q.Args); err != nil {
+			return fmt.Errorf("unable to declare queue %q: %w", q.Name, err)
+		}
+
+		tag := fmt.Sprintf("%s-%s", c.Name(), q.Name)
+		deliveries, err := ch.Consume(q.Name, tag, false, q.Exclusive, false, false, nil)
+		if err != nil {
+			return fmt.Errorf("unable to consume queue %q: %w", q.Name, err)
+		}
+		tags = append(tags, tag)
+
+		wg.Add(1)
+		go func(queue string, handler Handler) {
+			defer wg.Done()
+			c.dispatch(ctx, queue, deliveries, handler)
+		}(q.Name, cons.handler)
+	}
+
+	c.mu.Lock()
+	c.conn, c.channel = conn, ch
+	c.mu.Unlock()
+
+	select {
+	case err := <-connClosed:
+		return fmt.Errorf("amqp connection closed: %w", err)
+	case err := <-chClosed:
+		return fmt.Errorf("amqp channel closed: %w", err)
+	case <-ctx.Done():
+	}
+
+	// stop new deliveries, the deliveries channels are closed once the consumers are cancelled
+	for _, tag := range tags {
+		if err := ch.Cancel(tag, false); err != nil {
+			sysd.LoggerFrom(ctx).Error("amqp consumer %q cancel failed: %v", tag, err)
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+// dispatch hands the deliveries to the handler until the deliveries channel is closed.
+// deliveries received after the context is done are requeued without being handled
+func (c *Consumer) dispatch(ctx context.Context, queue string, deliveries <-chan amqp.Delivery, handler Handler) {
+	logger := sysd.LoggerFrom(ctx)
+	for d := range deliveries {
+		if ctx.Err() != nil {
+			if err := d.Nack(false, true); err != nil {
+				logger.Error("amqp requeue of delivery %d failed: %v", d.DeliveryTag, err)
+			}
+			continue
+		}
+
+		if err := handler(ctx, d); err != nil {
+			logger.Warn("amqp handler of queue %q failed, requeueing: %v", queue, err)
+			_ = d.Nack(false, true)
+			continue
+		}
+		if err := d.Ack(false); err != nil {
+			logger.Error("amqp ack of delivery %d failed: %v", d.DeliveryTag, err)
+		}
+	}
+}
+
+// Status reports a closed connection or channel
+func (c *Consumer) Status(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || c.channel == nil {
+		return fmt.Errorf("amqp connection is nil")
+	}
+	if c.conn.IsClosed() {
+		return fmt.Errorf("amqp connection is closed")
+	}
+	if c.channel.IsClosed() {
+		return fmt.Errorf("amqp channel is closed")
+	}
+	return nil
+}
+
+func (c *Consumer) Name() string {
+	return c.name
+}
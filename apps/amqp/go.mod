@@ -0,0 +1,10 @@
+module github.com/mirzakhany/sysd/apps/amqp
+
+go 1.21.3
+
+require (
+	github.com/mirzakhany/sysd v0.1.2
+	github.com/rabbitmq/amqp091-go v1.9.0
+)
+
+replace github.com/mirzakhany/sysd => ../..
@@ -0,0 +1,262 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &Cron{}
+
+// ErrJobExists is returned when a job with the same name is already registered
+var ErrJobExists = errors.New("job already exists")
+
+// OverlapPolicy decides what happens when a job is due while its previous run is still running
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the run, the default
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue runs the job once the previous runs have finished
+	OverlapQueue
+	// OverlapConcurrent runs the job alongside the previous runs
+	OverlapConcurrent
+)
+
+// Job is the function run on schedule
+type Job func(ctx context.Context) error
+
+// JobOption configures a job added with AddJob
+type JobOption func(j *job)
+
+// WithOverlap sets what happens when the job is due while it is still running
+func WithOverlap(policy OverlapPolicy) JobOption {
+	return func(j *job) {
+		j.overlap = policy
+	}
+}
+
+// WithTimeout cancels a run of the job which takes longer than the timeout
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(j *job) {
+		j.timeout = timeout
+	}
+}
+
+type job struct {
+	name     string
+	schedule Schedule
+	fn       Job
+	overlap  OverlapPolicy
+	timeout  time.Duration
+
+	// serial is held by the running run of jobs which do not run concurrently
+	serial chan struct{}
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+// Option configures the cron app
+type Option func(c *Cron)
+
+// WithName sets the name of the app, to run more than one cron app in the same systemd service
+func WithName(name string) Option {
+	return func(c *Cron) {
+		c.name = name
+	}
+}
+
+// WithLocation sets the time zone the cron expressions are evaluated in, the local time zone by default
+func WithLocation(loc *time.Location) Option {
+	return func(c *Cron) {
+		c.location = loc
+	}
+}
+
+// Cron runs registered jobs on their schedules. a job which failed its last run makes Status
+// report the app as degraded until it succeeds again
+type Cron struct {
+	name     string
+	location *time.Location
+
+	mu   sync.Mutex
+	jobs []*job
+	// ctx and wg belong to the running Start call, ctx is nil if not running
+	ctx context.Context
+	wg  *sync.WaitGroup
+}
+
+func New(opts ...Option) *Cron {
+	c := &Cron{name: "cron", location: time.Local}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// AddJob adds a job which runs on the cron expression spec, see Parse. jobs may be added while running
+func (c *Cron) AddJob(name, spec string, fn Job, opts ...JobOption) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return c.AddSchedule(name, schedule, fn, opts...)
+}
+
+// AddSchedule adds a job which runs on the given schedule
+func (c *Cron) AddSchedule(name string, schedule Schedule, fn Job, opts ...JobOption) error {
+	j := &job{name: name, schedule: schedule, fn: fn, serial: make(chan struct{}, 1)}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.jobs {
+		if existing.name == name {
+			return ErrJobExists
+		}
+	}
+	c.jobs = append(c.jobs, j)
+
+	if c.ctx != nil {
+		c.wg.Add(1)
+		go c.schedule(c.ctx, c.wg, j)
+	}
+	return nil
+}
+
+func (c *Cron) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	c.mu.Lock()
+	c.ctx, c.wg = ctx, &wg
+	for _, j := range c.jobs {
+		wg.Add(1)
+		go c.schedule(ctx, &wg, j)
+	}
+	c.mu.Unlock()
+
+	return sysd.ShutdownGracefully(ctx, func() error {
+		c.mu.Lock()
+		c.ctx, c.wg = nil, nil
+		c.mu.Unlock()
+
+		// running jobs see their context cancelled, wait for them to return
+		wg.Wait()
+		return nil
+	})
+}
+
+// schedule runs the job each time it is due until the context is done
+func (c *Cron) schedule(ctx context.Context, wg *sync.WaitGroup, j *job) {
+	defer wg.Done()
+
+	logger := sysd.LoggerFrom(ctx)
+	for {
+		next := j.schedule.Next(time.Now().In(c.location))
+		if next.IsZero() {
+			logger.Info("cron job %q has no next run, unscheduling it", j.name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		switch j.overlap {
+		case OverlapConcurrent:
+		case OverlapQueue:
+			// the run waits for the previous runs in its own goroutine, the schedule keeps going
+		default:
+			select {
+			case j.serial <- struct{}{}:
+				<-j.serial
+			default:
+				logger.Warn("cron job %q is still running, skipping run at %s", j.name, next.Format(time.RFC3339))
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.run(ctx, j)
+		}()
+	}
+}
+
+// run runs the job once, honoring its overlap policy and timeout, and records its result
+func (c *Cron) run(ctx context.Context, j *job) {
+	if j.overlap != OverlapConcurrent {
+		select {
+		case j.serial <- struct{}{}:
+			defer func() { <-j.serial }()
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	err := runJob(ctx, j.fn)
+	if err != nil {
+		sysd.LoggerFrom(ctx).Error("cron job %q failed: %v", j.name, err)
+	}
+
+	j.mu.Lock()
+	j.lastErr = err
+	j.mu.Unlock()
+}
+
+// runJob runs the job, turning a panic into an error so it does not take down the app
+func runJob(ctx context.Context, fn Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// Status reports the app as degraded with the jobs which failed their last run, failed jobs
+// are retried on schedule and do not need the app restarted
+func (c *Cron) Status(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ctx == nil {
+		return errors.New("cron is not running")
+	}
+
+	var errs []error
+	for _, j := range c.jobs {
+		j.mu.Lock()
+		if j.lastErr != nil {
+			errs = append(errs, fmt.Errorf("job %q failed: %w", j.name, j.lastErr))
+		}
+		j.mu.Unlock()
+	}
+	if len(errs) > 0 {
+		return sysd.Degraded(errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c *Cron) Name() string {
+	return c.name
+}
@@ -0,0 +1,69 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+// every is a schedule running the job every interval
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+func TestFailedJobDegradesStatus(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	runs := make(chan struct{}, 100)
+	c := New()
+	err := c.AddSchedule("job", every(5*time.Millisecond), func(context.Context) error {
+		defer func() { runs <- struct{}{} }()
+		if fail.Load() {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitStatus := func(want sysd.Health) error {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case <-runs:
+				// the result is recorded once the job returned
+				time.Sleep(time.Millisecond)
+				if err := c.Status(ctx); sysd.HealthOf(err) == want {
+					return err
+				}
+			case <-deadline:
+				t.Fatalf("status is not %s", want)
+				return nil
+			}
+		}
+	}
+
+	if err := waitStatus(sysd.HealthDegraded); !errors.Is(err, sysd.ErrDegraded) {
+		t.Errorf("Status after a failed run returned %v, want degraded", err)
+	}
+	fail.Store(false)
+	if err := waitStatus(sysd.HealthHealthy); err != nil {
+		t.Errorf("Status after a successful run returned %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package dashboard
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"errors"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &Dashboard{}
+
+const (
+	// shutdownTimeout bounds waiting for in-flight requests on shutdown
+	shutdownTimeout = 5 * time.Second
+	// recentEvents is the number of events shown on the page
+	recentEvents = 50
+	// actionTimeout bounds waiting for an app to stop or restart
+	actionTimeout = time.Minute
+	// refreshInterval is how often the page reloads itself
+	refreshInterval = 5
+)
+
+//go:embed dashboard.html
+var page string
+
+var pageTemplate = template.Must(template.New("dashboard").Parse(page))
+
+// Option configures the dashboard
+type Option func(d *Dashboard)
+
+// WithBasicAuth requires the browser to log in with the username and password
+func WithBasicAuth(username, password string) Option {
+	return func(d *Dashboard) {
+		d.username, d.password = username, password
+	}
+}
+
+// WithUnauthenticatedActions serves the restart and stop buttons without basic auth, which are
+// refused otherwise. only for a dashboard reachable by trusted users alone
+func WithUnauthenticatedActions() Option {
+	return func(d *Dashboard) {
+		d.unauthenticatedActions = true
+	}
+}
+
+// Dashboard serves a web page listing the apps of the systemd service, their state, uptime, restarts
+// and the recent lifecycle events, with buttons to restart and stop the apps behind basic auth
+type Dashboard struct {
+	Host string
+	Port int
+
+	sysd                   *sysd.Systemd
+	username, password     string
+	unauthenticatedActions bool
+
+	mu     sync.Mutex
+	server *http.Server
+	events []event
+}
+
+func New(Host string, Port int, s *sysd.Systemd, opts ...Option) *Dashboard {
+	d := &Dashboard{Host: Host, Port: Port, sysd: s}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Handler returns the handler serving the dashboard, to mount it on another server
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.servePage)
+	mux.HandleFunc("/apps/", d.serveAction)
+	return d.authenticate(mux)
+}
+
+func (d *Dashboard) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Handler: d.Handler(),
+	}
+
+	ln, err := sysd.Listen(ctx, d.Name(), "tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	events := d.sysd.Subscribe()
+	defer d.sysd.Unsubscribe(events)
+	go d.record(events)
+
+	d.mu.Lock()
+	d.server = srv
+	d.mu.Unlock()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// event is a lifecycle event as shown on the page
+type event struct {
+	Time  time.Time
+	App   string
+	Type  string
+	Error string
+}
+
+// record keeps the most recent events, newest first, until the channel is closed
+func (d *Dashboard) record(events <-chan sysd.Event) {
+	for e := range events {
+		ev := event{Time: e.Time, App: e.App, Type: e.Type.String()}
+		if e.Err != nil {
+			ev.Error = e.Err.Error()
+		}
+
+		d.mu.Lock()
+		d.events = append([]event{ev}, d.events...)
+		if len(d.events) > recentEvents {
+			d.events = d.events[:recentEvents]
+		}
+		d.mu.Unlock()
+	}
+}
+
+func (d *Dashboard) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.username != "" {
+			username, password, ok := r.BasicAuth()
+			if !ok || subtle.ConstantTimeCompare([]byte(username), []byte(d.username)) != 1 ||
+				subtle.ConstantTimeCompare([]byte(password), []byte(d.password)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="sysd"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// appRow is an app as shown on the page
+type appRow struct {
+	Name      string
+	State     string
+	Uptime    time.Duration
+	Restarts  int
+	LastError string
+}
+
+func (d *Dashboard) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var apps []appRow
+	for _, app := range d.sysd.Snapshot() {
+		row := appRow{
+			Name:     app.Name,
+			State:    app.State.String(),
+			Uptime:   app.Uptime.Round(time.Second),
+			Restarts: app.Restarts,
+		}
+		if app.LastError != nil {
+			row.LastError = app.LastError.Error()
+		}
+		apps = append(apps, row)
+	}
+
+	d.mu.Lock()
+	events := append([]event{}, d.events...)
+	d.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := pageTemplate.Execute(w, struct {
+		Refresh int
+		Actions bool
+		Apps    []appRow
+		Events  []event
+	}{Refresh: refreshInterval, Actions: d.actionsAllowed(), Apps: apps, Events: events})
+	if err != nil {
+		log.Printf("dashboard render failed: %v", err)
+	}
+}
+
+// actionsAllowed returns true if the buttons are served, behind basic auth or explicitly without
+func (d *Dashboard) actionsAllowed() bool {
+	return d.username != "" || d.unauthenticatedActions
+}
+
+// sameOrigin returns false for a request a browser sent from another site, a page elsewhere must not
+// use the credentials the browser resends to stop apps. other clients send neither header
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" {
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return false
+		}
+	}
+	return true
+}
+
+// serveAction serves the buttons, POST /apps/{name}/restart and /apps/{name}/stop,
+// redirecting back to the page
+func (d *Dashboard) serveAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !d.actionsAllowed() {
+		http.Error(w, "actions need basic auth, see WithBasicAuth", http.StatusForbidden)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-site request", http.StatusForbidden)
+		return
+	}
+	name, action, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/apps/"), "/")
+	if !ok || name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// the action goes on if the browser goes away, a restart is not left halfway
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), actionTimeout)
+	defer cancel()
+
+	var err error
+	switch action {
+	case "restart":
+		err = d.sysd.RestartApp(ctx, name)
+	case "stop":
+		err = d.sysd.StopApp(ctx, name)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case errors.Is(err, sysd.ErrAppNotExists):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Redirect(w, r, "../../", http.StatusSeeOther)
+	}
+}
+
+func (d *Dashboard) Status(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.server == nil {
+		return errors.New("dashboard server is not running")
+	}
+	return nil
+}
+
+func (d *Dashboard) Name() string {
+	return "dashboard"
+}
@@ -0,0 +1,232 @@
+package dashboard
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// noRedirect is a client returning redirects instead of following them
+var noRedirect = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}}
+
+// newDashboard returns a dashboard server over a running harness recording its events
+func newDashboard(t *testing.T, opts ...Option) (*sysdtest.Harness, *httptest.Server) {
+	t.Helper()
+
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app")
+	// keeps the service running while app is stopped
+	h.NewApp("other")
+	d := New("127.0.0.1", 0, h.Systemd, opts...)
+	events := h.Systemd.Subscribe()
+	go d.record(events)
+	t.Cleanup(func() { h.Systemd.Unsubscribe(events) })
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	srv := httptest.NewServer(d.Handler())
+	t.Cleanup(srv.Close)
+	return h, srv
+}
+
+func body(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestPageListsAppsAndEvents(t *testing.T) {
+	_, srv := newDashboard(t, WithUnauthenticatedActions())
+
+	var page string
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page = body(t, resp); strings.Contains(page, sysd.EventAppStarted.String()) {
+			break
+		}
+	}
+	for _, want := range []string{
+		"<td>app</td>",
+		`<td class="running">running</td>`,
+		`action="apps/app/restart"`,
+		"<td>" + sysd.EventAppStarted.String() + "</td>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q:\n%s", want, page)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown page answered %s", resp.Status)
+	}
+}
+
+func TestActions(t *testing.T) {
+	h, srv := newDashboard(t, WithUnauthenticatedActions())
+
+	resp, err := noRedirect.Post(srv.URL+"/apps/app/stop", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("stop answered %s, want a redirect to the page", resp.Status)
+	}
+	h.WaitForState("app", sysd.AppStopped)
+
+	resp, err = noRedirect.Post(srv.URL+"/apps/app/restart", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("restart answered %s, want a redirect to the page", resp.Status)
+	}
+	h.WaitForState("app", sysd.AppRunning)
+
+	for path, code := range map[string]int{
+		"/apps/missing/restart": http.StatusNotFound,
+		"/apps/app/explode":     http.StatusNotFound,
+	} {
+		resp, err := noRedirect.Post(srv.URL+path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Errorf("POST %s answered %s, want %d", path, resp.Status, code)
+		}
+	}
+	resp, err = http.Get(srv.URL + "/apps/app/stop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET of an action answered %s", resp.Status)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	_, srv := newDashboard(t, WithBasicAuth("admin", "secret"))
+
+	for _, tt := range []struct {
+		user, password string
+		code           int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"admin", "wrong", http.StatusUnauthorized},
+		{"admin", "secret", http.StatusOK},
+	} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, tt.password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("login as %q:%q answered %s, want %d", tt.user, tt.password, resp.Status, tt.code)
+		}
+	}
+}
+
+func TestActionsNeedAuth(t *testing.T) {
+	h, srv := newDashboard(t)
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page := body(t, resp); strings.Contains(page, "apps/app/stop") {
+		t.Errorf("page without basic auth shows the buttons:\n%s", page)
+	}
+	resp, err = noRedirect.Post(srv.URL+"/apps/app/stop", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("stop without basic auth answered %s, want %d", resp.Status, http.StatusForbidden)
+	}
+	if s := appState(h, "app"); s != sysd.AppRunning {
+		t.Errorf("app is %s after a refused stop", s)
+	}
+}
+
+func TestCrossSiteActionsRejected(t *testing.T) {
+	h, srv := newDashboard(t, WithBasicAuth("admin", "secret"))
+
+	post := func(header, value string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/apps/app/restart", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("admin", "secret")
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := noRedirect.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for _, tt := range []struct {
+		header, value string
+		code          int
+	}{
+		{"Origin", "https://evil.example", http.StatusForbidden},
+		{"Origin", "null", http.StatusForbidden},
+		{"Sec-Fetch-Site", "cross-site", http.StatusForbidden},
+		{"Sec-Fetch-Site", "same-site", http.StatusForbidden},
+		{"Origin", srv.URL, http.StatusSeeOther},
+		{"Sec-Fetch-Site", "same-origin", http.StatusSeeOther},
+		{"", "", http.StatusSeeOther},
+	} {
+		if code := post(tt.header, tt.value); code != tt.code {
+			t.Errorf("restart with %s %q answered %d, want %d", tt.header, tt.value, code, tt.code)
+		}
+		h.WaitForState("app", sysd.AppRunning)
+	}
+}
+
+// appState returns the state of the app in the snapshot of the service
+func appState(h *sysdtest.Harness, name string) sysd.AppState {
+	for _, app := range h.Systemd.Snapshot() {
+		if app.Name == name {
+			return app.State
+		}
+	}
+	return sysd.AppState(0)
+}
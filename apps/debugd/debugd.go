@@ -0,0 +1,147 @@
+package debugd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &Debugd{}
+
+// shutdownTimeout bounds waiting for in-flight requests, like a running CPU profile, on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// Debugd serves pprof profiles under /debug/pprof/, expvar variables under /debug/vars and
+// runtime stats under /debug/runtime. it exposes the internals of the process, so it
+// should listen on localhost only, which is the default of NewLocal
+type Debugd struct {
+	Host string
+	Port int
+
+	mu      sync.Mutex
+	server  *http.Server
+	started time.Time
+}
+
+func New(Host string, Port int) *Debugd {
+	return &Debugd{Host: Host, Port: Port}
+}
+
+// NewLocal returns a debug server listening on localhost only
+func NewLocal(Port int) *Debugd {
+	return New("localhost", Port)
+}
+
+// Handler returns the handler serving the debug endpoints, to mount them on another server
+func (d *Debugd) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/runtime", d.serveRuntime)
+	return mux
+}
+
+func (d *Debugd) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Handler: d.Handler(),
+	}
+
+	ln, err := sysd.Listen(ctx, d.Name(), "tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.server = srv
+	d.started = time.Now()
+	d.mu.Unlock()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// runtimeStats is the body of /debug/runtime
+type runtimeStats struct {
+	GoVersion    string        `json:"go_version"`
+	Uptime       time.Duration `json:"uptime_ns"`
+	NumCPU       int           `json:"num_cpu"`
+	GOMAXPROCS   int           `json:"gomaxprocs"`
+	NumGoroutine int           `json:"num_goroutine"`
+	NumCgoCall   int64         `json:"num_cgo_call"`
+	HeapAlloc    uint64        `json:"heap_alloc"`
+	HeapInuse    uint64        `json:"heap_inuse"`
+	HeapObjects  uint64        `json:"heap_objects"`
+	Sys          uint64        `json:"sys"`
+	NumGC        uint32        `json:"num_gc"`
+	PauseTotal   time.Duration `json:"gc_pause_total_ns"`
+}
+
+func (d *Debugd) serveRuntime(w http.ResponseWriter, _ *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	d.mu.Lock()
+	started := d.started
+	d.mu.Unlock()
+
+	stats := runtimeStats{
+		GoVersion:    runtime.Version(),
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		NumGoroutine: runtime.NumGoroutine(),
+		NumCgoCall:   runtime.NumCgoCall(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapObjects:  mem.HeapObjects,
+		Sys:          mem.Sys,
+		NumGC:        mem.NumGC,
+		PauseTotal:   time.Duration(mem.PauseTotalNs),
+	}
+	if !started.IsZero() {
+		stats.Uptime = time.Since(started)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
+func (d *Debugd) Status(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.server == nil {
+		return errors.New("debugd server is not running")
+	}
+	return nil
+}
+
+func (d *Debugd) Name() string {
+	return "debugd"
+}
@@ -0,0 +1,68 @@
+package debugd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerServesDebugEndpoints(t *testing.T) {
+	srv := httptest.NewServer(NewLocal(0).Handler())
+	defer srv.Close()
+
+	for path, want := range map[string]string{
+		"/debug/pprof/":        "goroutine",
+		"/debug/vars":          `"memstats"`,
+		"/debug/pprof/cmdline": "debugd.test",
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		var body strings.Builder
+		_, _ = io.Copy(&body, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(body.String(), want) {
+			t.Errorf("GET %s answered %s without %q", path, resp.Status, want)
+		}
+	}
+}
+
+func TestRuntimeStats(t *testing.T) {
+	d := NewLocal(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- d.Start(ctx) }()
+	for deadline := time.Now().Add(5 * time.Second); d.Status(ctx) != nil; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("debug server not running")
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	var stats runtimeStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("decoding the runtime stats: %v", err)
+	}
+	if stats.GoVersion != runtime.Version() || stats.NumGoroutine == 0 || stats.HeapAlloc == 0 || stats.Uptime <= 0 {
+		t.Errorf("runtime stats are %+v", stats)
+	}
+
+	cancel()
+	if err := <-started; err != nil {
+		t.Fatalf("Start returned %v", err)
+	}
+}
+
+func TestStatusBeforeStart(t *testing.T) {
+	if err := NewLocal(0).Status(context.Background()); err == nil {
+		t.Error("Status of a server not started returned no error")
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/mirzakhany/sysd/apps/debugd
+
+go 1.21.3
+
+require github.com/mirzakhany/sysd v0.1.2
+
+replace github.com/mirzakhany/sysd => ../..
@@ -0,0 +1,232 @@
+package grpcd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+var (
+	_ sysd.App              = &GRPCd{}
+	_ sysd.ListenerOwner    = &GRPCd{}
+	_ grpc.ServiceRegistrar = &GRPCd{}
+)
+
+// Option configures the gRPC server
+type Option func(g *GRPCd)
+
+// WithTLS serves over TLS with the given config
+func WithTLS(config *tls.Config) Option {
+	return func(g *GRPCd) {
+		g.serverOpts = append(g.serverOpts, grpc.Creds(credentials.NewTLS(config)))
+	}
+}
+
+// WithUnaryInterceptors adds interceptors for unary calls, they run in the given order
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(g *GRPCd) {
+		g.unary = append(g.unary, interceptors...)
+	}
+}
+
+// WithStreamInterceptors adds interceptors for streaming calls, they run in the given order
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(g *GRPCd) {
+		g.stream = append(g.stream, interceptors...)
+	}
+}
+
+// WithReflection registers the server reflection service, for tools like grpcurl
+func WithReflection() Option {
+	return func(g *GRPCd) {
+		g.reflection = true
+	}
+}
+
+// WithServerOptions adds options passed to grpc.NewServer
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(g *GRPCd) {
+		g.serverOpts = append(g.serverOpts, opts...)
+	}
+}
+
+// WithStopTimeout bounds how long the server waits for in-flight calls on shutdown
+// before closing them, zero waits until they finish
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(g *GRPCd) {
+		g.stopTimeout = timeout
+	}
+}
+
+type service struct {
+	desc *grpc.ServiceDesc
+	impl any
+}
+
+// GRPCd serves gRPC services along with the standard health checking service,
+// which Status reports from
+type GRPCd struct {
+	Host string
+	Port int
+
+	serverOpts  []grpc.ServerOption
+	unary       []grpc.UnaryServerInterceptor
+	stream      []grpc.StreamServerInterceptor
+	reflection  bool
+	stopTimeout time.Duration
+
+	mu       sync.Mutex
+	services []service
+	server   *grpc.Server
+	health   *health.Server
+	listener net.Listener
+}
+
+func New(Host string, Port int, opts ...Option) *GRPCd {
+	g := &GRPCd{
+		Host:   Host,
+		Port:   Port,
+		health: health.NewServer(),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// RegisterService registers a service to serve, generated RegisterXServer functions accept
+// the GRPCd in place of a grpc.Server. services must be registered before Start
+func (g *GRPCd) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.services = append(g.services, service{desc: desc, impl: impl})
+}
+
+func (g *GRPCd) Start(ctx context.Context) error {
+	opts := append([]grpc.ServerOption{}, g.serverOpts...)
+	if len(g.unary) > 0 {
+		opts = append(opts, grpc.ChainUnaryInterceptor(g.unary...))
+	}
+	if len(g.stream) > 0 {
+		opts = append(opts, grpc.ChainStreamInterceptor(g.stream...))
+	}
+	srv := grpc.NewServer(opts...)
+
+	g.mu.Lock()
+	for _, s := range g.services {
+		srv.RegisterService(s.desc, s.impl)
+		g.health.SetServingStatus(s.desc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	}
+	g.mu.Unlock()
+	healthpb.RegisterHealthServer(srv, g.health)
+	if g.reflection {
+		reflection.Register(srv)
+	}
+	g.health.Resume()
+	g.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	// take over the listener of the previous process on upgrade, or of socket activation
+	ln, err := sysd.Listen(ctx, g.Name(), "tcp", net.JoinHostPort(g.Host, strconv.Itoa(g.Port)))
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	g.server = srv
+	g.listener = ln
+	g.mu.Unlock()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		g.health.Shutdown()
+		return err
+	case <-ctx.Done():
+		return g.stop(srv)
+	}
+}
+
+// stop reports the server not serving to health checks and stops it gracefully,
+// closing the calls still running once the stop timeout expires
+func (g *GRPCd) stop(srv *grpc.Server) error {
+	g.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	var expired <-chan time.Time
+	if g.stopTimeout > 0 {
+		timer := time.NewTimer(g.stopTimeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-stopped:
+		return nil
+	case <-expired:
+		srv.Stop()
+		return fmt.Errorf("grpcd calls did not finish in %s and were closed", g.stopTimeout)
+	}
+}
+
+// Status returns an error unless the health checking service reports the server serving
+func (g *GRPCd) Status(ctx context.Context) error {
+	g.mu.Lock()
+	srv := g.server
+	g.mu.Unlock()
+
+	if srv == nil {
+		return errors.New("grpcd server is not running")
+	}
+
+	resp, err := g.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("grpcd server is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// SetServingStatus sets the health status of a service, the empty name is the whole server
+// which Status reports from
+func (g *GRPCd) SetServingStatus(service string, status healthpb.HealthCheckResponse_ServingStatus) {
+	g.health.SetServingStatus(service, status)
+}
+
+func (g *GRPCd) Name() string {
+	return "grpcd"
+}
+
+// Listeners returns the listener of the server, it is handed over to the new process on upgrade
+func (g *GRPCd) Listeners() map[string]net.Listener {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.listener == nil {
+		return nil
+	}
+	return map[string]net.Listener{g.Name(): g.listener}
+}
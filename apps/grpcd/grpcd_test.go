@@ -0,0 +1,178 @@
+package grpcd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// waitDesc is a service with a streaming call returning once release is closed
+func waitDesc(release <-chan struct{}) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "test.Waiter",
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Wait",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				if err := stream.SendHeader(nil); err != nil {
+					return err
+				}
+				select {
+				case <-release:
+				case <-stream.Context().Done():
+				}
+				return nil
+			},
+		}},
+	}
+}
+
+// startServer starts the server in a systemd service on an in-memory listener and returns a connection to it
+func startServer(t *testing.T, g *GRPCd) (*sysdtest.Harness, *grpc.ClientConn) {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	h := sysdtest.NewHarness(t)
+	h.Systemd.SetListenerProvider(sysd.ListenerProviderFunc(func(name string) (net.Listener, bool) {
+		return lis, name == g.Name()
+	}))
+	if err := h.Systemd.Add(g); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState(g.Name(), sysd.AppRunning)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return h, conn
+}
+
+// openWait opens a Wait call, it blocks until the server released it
+func openWait(t *testing.T, conn *grpc.ClientConn) grpc.ClientStream {
+	t.Helper()
+
+	stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, "/test.Waiter/Wait")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	// the handler sends the headers once it runs
+	if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+func TestStatusFollowsHealth(t *testing.T) {
+	g := New("127.0.0.1", 0)
+	g.RegisterService(waitDesc(nil), struct{}{})
+	if err := g.Status(context.Background()); err == nil {
+		t.Error("Status before start returned nil, want an error")
+	}
+	_, conn := startServer(t, g)
+
+	client := healthpb.NewHealthClient(conn)
+	for _, service := range []string{"", "test.Waiter"} {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("service %q is %s, want SERVING", service, resp.GetStatus())
+		}
+	}
+	if err := g.Status(context.Background()); err != nil {
+		t.Errorf("Status returned %v, want nil while serving", err)
+	}
+
+	g.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	if err := g.Status(context.Background()); err == nil || !strings.Contains(err.Error(), "NOT_SERVING") {
+		t.Errorf("Status returned %v, want the server not serving", err)
+	}
+}
+
+func TestShutdownDrainsCalls(t *testing.T) {
+	release := make(chan struct{})
+	g := New("127.0.0.1", 0)
+	g.RegisterService(waitDesc(release), struct{}{})
+	h, conn := startServer(t, g)
+	stream := openWait(t, conn)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- h.Systemd.Shutdown(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Shutdown returned %v while a call was running", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(sysdtest.WaitTimeout):
+		t.Fatal("Shutdown did not return once the call finished")
+	}
+	if err := stream.RecvMsg(new(struct{})); !errors.Is(err, io.EOF) {
+		t.Errorf("call ended with %v, want it finished", err)
+	}
+}
+
+func TestStopTimeoutClosesCalls(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	g := New("127.0.0.1", 0, WithStopTimeout(50*time.Millisecond))
+	g.RegisterService(waitDesc(release), struct{}{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- g.Start(ctx) }()
+	var addr net.Addr
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); addr == nil; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("server not listening")
+		}
+		if l, ok := g.Listeners()[g.Name()]; ok {
+			addr = l.Addr()
+		}
+	}
+
+	conn, err := grpc.Dial(addr.String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	openWait(t, conn)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "did not finish") {
+			t.Errorf("Start returned %v, want the calls closed after the stop timeout", err)
+		}
+	case <-time.After(sysdtest.WaitTimeout):
+		t.Fatal("Start did not return after the stop timeout")
+	}
+}
@@ -0,0 +1,29 @@
+// Package autocert serves httpd over HTTPS with certificates obtained from Let's Encrypt
+package autocert
+
+import (
+	"github.com/mirzakhany/sysd/apps/httpd"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// New returns an httpd option serving certificates for the hosts from Let's Encrypt, cached in
+// cacheDir so they survive restarts. certificates are requested on the first TLS handshake of a host
+// and renewed in the background, the challenge is answered over TLS so port 443 is enough
+func New(cacheDir string, hosts ...string) httpd.Option {
+	return WithHostPolicy(cacheDir, autocert.HostWhitelist(hosts...))
+}
+
+// WithHostPolicy is like New but lets the policy decide which hosts get certificates
+func WithHostPolicy(cacheDir string, policy autocert.HostPolicy) httpd.Option {
+	return WithManager(&autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: policy,
+	})
+}
+
+// WithManager serves the certificates of the manager, e.g. to set the account email
+// or use another ACME directory
+func WithManager(m *autocert.Manager) httpd.Option {
+	return httpd.WithTLSConfig(m.TLSConfig())
+}
@@ -0,0 +1,18 @@
+module github.com/mirzakhany/sysd/apps/httpd/autocert
+
+go 1.21.3
+
+require (
+	github.com/mirzakhany/sysd/apps/httpd v0.0.0
+	golang.org/x/crypto v0.14.0
+)
+
+require (
+	github.com/mirzakhany/sysd v0.1.2 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+)
+
+replace github.com/mirzakhany/sysd/apps/httpd => ../
+
+replace github.com/mirzakhany/sysd => ../../..
@@ -3,3 +3,5 @@ module github.com/mirzakhany/sysd/apps/httpd
 go 1.21.3
 
 require github.com/mirzakhany/sysd v0.1.2
+
+replace github.com/mirzakhany/sysd => ../..
@@ -2,14 +2,25 @@ package httpd
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mirzakhany/sysd"
 )
 
-var _ sysd.App = &HTTPd{}
+var (
+	_ sysd.App           = &HTTPd{}
+	_ sysd.ListenerOwner = &HTTPd{}
+	_ sysd.Reloader      = &HTTPd{}
+)
 
 type HTTPd struct {
 	Host string
@@ -17,39 +28,199 @@ type HTTPd struct {
 
 	handler http.Handler
 
-	server *http.Server
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+	baseContext       func(net.Listener) context.Context
+	errorLog          *log.Logger
+
+	shutdownTimeout time.Duration
+	tls             *tls.Config
+	cert            *certificate
+
+	mu       sync.Mutex
+	server   *http.Server
+	listener net.Listener
+
+	// activeConns is the number of open connections, including idle keep-alive ones
+	activeConns atomic.Int64
+	// inFlight is the number of requests being served
+	inFlight atomic.Int64
+	// draining is set while the server waits for the in-flight requests on shutdown
+	draining atomic.Bool
 }
 
-func New(Host string, Port int, handler http.Handler) *HTTPd {
-	return &HTTPd{
-		Host:    Host,
-		Port:    Port,
-		handler: handler,
+// defaultShutdownTimeout bounds draining the in-flight requests on shutdown
+const defaultShutdownTimeout = 10 * time.Second
+
+func New(Host string, Port int, handler http.Handler, opts ...Option) *HTTPd {
+	h := &HTTPd{
+		Host:            Host,
+		Port:            Port,
+		handler:         handler,
+		shutdownTimeout: defaultShutdownTimeout,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 func (h *HTTPd) Start(ctx context.Context) error {
 	srv := &http.Server{
-		Addr:    net.JoinHostPort(h.Host, strconv.Itoa(h.Port)),
-		Handler: h.handler,
+		Addr:              net.JoinHostPort(h.Host, strconv.Itoa(h.Port)),
+		Handler:           h.trackRequests(h.handler),
+		ConnState:         h.trackConnState,
+		ReadTimeout:       h.readTimeout,
+		ReadHeaderTimeout: h.readHeaderTimeout,
+		WriteTimeout:      h.writeTimeout,
+		IdleTimeout:       h.idleTimeout,
+		MaxHeaderBytes:    h.maxHeaderBytes,
+		BaseContext:       h.baseContext,
+		ErrorLog:          h.errorLog,
 	}
+	if srv.BaseContext == nil {
+		// requests keep the values of the app context but are not cancelled with it, so they can drain
+		base := context.WithoutCancel(ctx)
+		srv.BaseContext = func(net.Listener) context.Context { return base }
+	}
+	if srv.ErrorLog == nil {
+		srv.ErrorLog = log.New(errorLogWriter{sysd.LoggerFrom(ctx)}, "", 0)
+	}
+
+	tlsConfig, err := h.tlsConfig()
+	if err != nil {
+		return err
+	}
+	srv.TLSConfig = tlsConfig
 
-	if err := srv.ListenAndServe(); err != nil {
+	// take over the listener of the previous process on upgrade, or of socket activation
+	ln, err := sysd.Listen(ctx, h.Name(), "tcp", srv.Addr)
+	if err != nil {
 		return err
 	}
 
+	h.mu.Lock()
 	h.server = srv
+	h.listener = ln
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		h.server = nil
+		h.mu.Unlock()
+	}()
 
-	return sysd.ShutdownGracefully(ctx, func() error {
-		return srv.Shutdown(ctx)
-	})
+	served := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			served <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	// the app context is done already, give the in-flight requests a deadline of their own
+	logger := sysd.LoggerFrom(ctx)
+	inFlight := h.InFlightRequests()
+	logger.Info("draining %d in-flight requests and %d connections", inFlight, h.ActiveConnections())
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.shutdownTimeout)
+	defer cancel()
+	h.draining.Store(true)
+	defer h.draining.Store(false)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("drained %d of %d in-flight requests: %v", inFlight-h.InFlightRequests(), inFlight, err)
+		return err
+	}
+	logger.Info("drained %d in-flight requests", inFlight)
+	return nil
 }
 
+// Status returns an error if the server is not running, and reports the server as degraded
+// with the number of in-flight requests and open connections while it is draining
 func (h *HTTPd) Status(ctx context.Context) error {
-	// TODO: check if server is running and return error if not
+	h.mu.Lock()
+	running := h.server != nil
+	h.mu.Unlock()
+
+	inFlight, conns := h.InFlightRequests(), h.ActiveConnections()
+	if !running {
+		return fmt.Errorf("httpd server is not running, %d in-flight requests and %d connections", inFlight, conns)
+	}
+	if h.draining.Load() {
+		return sysd.Degraded(fmt.Errorf("httpd server is draining %d in-flight requests and %d connections", inFlight, conns))
+	}
+	return nil
+}
+
+// Reload reads the certificate files set by WithTLS again, the current certificate
+// is kept if they are invalid
+func (h *HTTPd) Reload(ctx context.Context) error {
+	if h.cert == nil {
+		return nil
+	}
+	if err := h.cert.load(); err != nil {
+		return err
+	}
+	sysd.LoggerFrom(ctx).Info("reloaded certificate %s", h.cert.certFile)
 	return nil
 }
 
 func (h *HTTPd) Name() string {
 	return "httpd"
 }
+
+// Listeners returns the listener of the server, it is handed over to the new process on upgrade
+func (h *HTTPd) Listeners() map[string]net.Listener {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.listener == nil {
+		return nil
+	}
+	return map[string]net.Listener{h.Name(): h.listener}
+}
+
+// ActiveConnections returns the number of open connections to the server
+func (h *HTTPd) ActiveConnections() int64 {
+	return h.activeConns.Load()
+}
+
+// InFlightRequests returns the number of requests being served
+func (h *HTTPd) InFlightRequests() int64 {
+	return h.inFlight.Load()
+}
+
+func (h *HTTPd) trackRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.inFlight.Add(1)
+		defer h.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *HTTPd) trackConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		h.activeConns.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		h.activeConns.Add(-1)
+	}
+}
+
+// errorLogWriter writes the error log of the server to the logger of the systemd service
+type errorLogWriter struct {
+	l *sysd.AppLogger
+}
+
+func (w errorLogWriter) Write(p []byte) (int, error) {
+	w.l.Error("%s", strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,190 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+// waitFor polls cond until it is true, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDrainCountsInFlightRequests(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := New("127.0.0.1", 0, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan error, 1)
+	go func() { started <- h.Start(ctx) }()
+	waitFor(t, "listener", func() bool { return h.Listeners() != nil })
+	url := "http://" + h.Listeners()[h.Name()].Addr().String()
+
+	if err := h.Status(ctx); err != nil {
+		t.Fatalf("Status of the running server returned %v", err)
+	}
+
+	const requests = 2
+	responses := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			resp, err := http.Get(url)
+			if err == nil {
+				resp.Body.Close()
+			}
+			responses <- err
+		}()
+		<-entered
+	}
+	if n := h.InFlightRequests(); n != requests {
+		t.Errorf("InFlightRequests returned %d, want %d", n, requests)
+	}
+	if n := h.ActiveConnections(); n != requests {
+		t.Errorf("ActiveConnections returned %d, want %d", n, requests)
+	}
+
+	cancel()
+	waitFor(t, "drain", func() bool { return h.draining.Load() })
+	err := h.Status(context.Background())
+	if !errors.Is(err, sysd.ErrDegraded) || !strings.Contains(err.Error(), "2 in-flight requests") {
+		t.Errorf("Status while draining returned %v, want degraded with the in-flight requests", err)
+	}
+
+	close(release)
+	for i := 0; i < requests; i++ {
+		if err := <-responses; err != nil {
+			t.Errorf("in-flight request failed: %v", err)
+		}
+	}
+	if err := <-started; err != nil {
+		t.Fatalf("Start returned %v", err)
+	}
+	if n := h.InFlightRequests(); n != 0 {
+		t.Errorf("InFlightRequests returned %d after drain, want 0", n)
+	}
+	waitFor(t, "connections to close", func() bool { return h.ActiveConnections() == 0 })
+}
+
+func TestNewAppliesServerOptions(t *testing.T) {
+	var errorLog bytes.Buffer
+	type key struct{}
+	h := New("127.0.0.1", 0, http.NotFoundHandler(),
+		WithReadTimeout(time.Second),
+		WithReadHeaderTimeout(2*time.Second),
+		WithWriteTimeout(3*time.Second),
+		WithIdleTimeout(4*time.Second),
+		WithMaxHeaderBytes(1024),
+		WithBaseContext(func(net.Listener) context.Context {
+			return context.WithValue(context.Background(), key{}, "base")
+		}),
+		WithErrorLog(log.New(&errorLog, "", 0)),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- h.Start(ctx) }()
+	waitFor(t, "listener", func() bool { return h.Listeners() != nil })
+
+	h.mu.Lock()
+	srv := h.server
+	h.mu.Unlock()
+	if srv.ReadTimeout != time.Second || srv.ReadHeaderTimeout != 2*time.Second ||
+		srv.WriteTimeout != 3*time.Second || srv.IdleTimeout != 4*time.Second || srv.MaxHeaderBytes != 1024 {
+		t.Errorf("server timeouts are not the configured ones: %+v", srv)
+	}
+	if v := srv.BaseContext(nil).Value(key{}); v != "base" {
+		t.Errorf("base context value is %v, want the configured base context", v)
+	}
+	srv.ErrorLog.Print("boom")
+	if !strings.Contains(errorLog.String(), "boom") {
+		t.Error("server errors not written to the configured error log")
+	}
+
+	cancel()
+	if err := <-started; err != nil {
+		t.Fatalf("Start returned %v", err)
+	}
+}
+
+func TestServerErrorsLoggedThroughSysd(t *testing.T) {
+	var out bytes.Buffer
+	s := sysd.New(sysd.WithLogger(log.New(&out, "", 0)))
+	h := New("127.0.0.1", 0, http.NotFoundHandler())
+	if err := s.Add(h); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Start(ctx) }()
+	waitFor(t, "listener", func() bool { return h.Listeners() != nil })
+
+	h.mu.Lock()
+	srv := h.server
+	h.mu.Unlock()
+	srv.ErrorLog.Print("http: TLS handshake error")
+
+	cancel()
+	<-done
+	if !strings.Contains(out.String(), "[httpd] http: TLS handshake error") {
+		t.Errorf("server error not logged through sysd:\n%s", out.String())
+	}
+}
+
+func TestDrainDeadline(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	h := New("127.0.0.1", 0, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(entered)
+		<-release
+	}), WithShutdownTimeout(50*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- h.Start(ctx) }()
+	waitFor(t, "listener", func() bool { return h.Listeners() != nil })
+
+	go func() {
+		resp, err := http.Get("http://" + h.Listeners()[h.Name()].Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	// the cancelled app context does not cut the drain short, the shutdown timeout does
+	cancelled := time.Now()
+	cancel()
+	select {
+	case err := <-started:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Start returned %v, want %v", err, context.DeadlineExceeded)
+		}
+		if took := time.Since(cancelled); took < 50*time.Millisecond {
+			t.Errorf("Start returned %s after cancellation, before the shutdown timeout", took)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after the shutdown timeout")
+	}
+}
@@ -0,0 +1,86 @@
+package httpd
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"time"
+)
+
+// Option configures the http server
+type Option func(h *HTTPd)
+
+// WithReadTimeout sets the maximum duration for reading an entire request, including the body
+func WithReadTimeout(d time.Duration) Option {
+	return func(h *HTTPd) {
+		h.readTimeout = d
+	}
+}
+
+// WithReadHeaderTimeout sets the maximum duration for reading the request headers
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(h *HTTPd) {
+		h.readHeaderTimeout = d
+	}
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of the response
+func WithWriteTimeout(d time.Duration) Option {
+	return func(h *HTTPd) {
+		h.writeTimeout = d
+	}
+}
+
+// WithIdleTimeout sets how long keep-alive connections wait for the next request
+func WithIdleTimeout(d time.Duration) Option {
+	return func(h *HTTPd) {
+		h.idleTimeout = d
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum size of the request headers
+func WithMaxHeaderBytes(n int) Option {
+	return func(h *HTTPd) {
+		h.maxHeaderBytes = n
+	}
+}
+
+// WithBaseContext sets the function returning the base context of the requests, by default
+// it carries the values of the context passed to Start, like the app name and logger
+func WithBaseContext(fn func(net.Listener) context.Context) Option {
+	return func(h *HTTPd) {
+		h.baseContext = fn
+	}
+}
+
+// WithErrorLog sets the logger of connection and handler errors, by default they are
+// written to the logger of the systemd service
+func WithErrorLog(l *log.Logger) Option {
+	return func(h *HTTPd) {
+		h.errorLog = l
+	}
+}
+
+// WithShutdownTimeout sets how long in-flight requests are given to finish on shutdown, 10s by default
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(h *HTTPd) {
+		h.shutdownTimeout = d
+	}
+}
+
+// WithTLS serves HTTPS with the certificate and key files, they are read again on Reload,
+// e.g. on SIGHUP, so renewed certificates are served without a restart
+func WithTLS(certFile, keyFile string) Option {
+	return func(h *HTTPd) {
+		h.cert = &certificate{certFile: certFile, keyFile: keyFile}
+	}
+}
+
+// WithTLSConfig serves HTTPS with the config, e.g. with client authentication or certificates
+// from a certificate manager. combined with WithTLS the certificate files take precedence
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(h *HTTPd) {
+		h.tls = cfg
+	}
+}
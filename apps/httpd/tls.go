@@ -0,0 +1,54 @@
+package httpd
+
+import (
+	"crypto/tls"
+	"sync"
+)
+
+// certificate is a certificate loaded from files, reloaded on Reload so renewed
+// certificates are served without a restart
+type certificate struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// load reads the certificate files, the current certificate is kept if they are invalid
+func (c *certificate) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	return nil
+}
+
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// tlsConfig returns the TLS config of the server, nil to serve plain HTTP
+func (h *HTTPd) tlsConfig() (*tls.Config, error) {
+	if h.tls == nil && h.cert == nil {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if h.tls != nil {
+		cfg = h.tls.Clone()
+	}
+	if h.cert != nil {
+		if err := h.cert.load(); err != nil {
+			return nil, err
+		}
+		cfg.GetCertificate = h.cert.get
+	}
+	return cfg, nil
+}
@@ -0,0 +1,134 @@
+package httpd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for 127.0.0.1 with the common name and its key
+func writeCert(t *testing.T, certFile, keyFile, name string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// servedName returns the common name of the certificate the server presents
+func servedName(t *testing.T, addr string) string {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("TLS dial: %v", err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestTLSCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "first")
+
+	h := New("127.0.0.1", 0, http.NotFoundHandler(), WithTLS(certFile, keyFile))
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- h.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-started
+	}()
+	waitFor(t, "listener", func() bool { return h.Listeners() != nil })
+	addr := h.Listeners()[h.Name()].Addr().String()
+
+	if name := servedName(t, addr); name != "first" {
+		t.Fatalf("served certificate %q, want first", name)
+	}
+
+	// invalid files keep the current certificate
+	if err := os.WriteFile(keyFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Reload(ctx); err == nil {
+		t.Error("Reload of an invalid key returned no error")
+	}
+	if name := servedName(t, addr); name != "first" {
+		t.Fatalf("served certificate %q after a failed reload, want first", name)
+	}
+
+	writeCert(t, certFile, keyFile, "renewed")
+	if err := h.Reload(ctx); err != nil {
+		t.Fatalf("Reload returned %v", err)
+	}
+	if name := servedName(t, addr); name != "renewed" {
+		t.Fatalf("served certificate %q after reload, want renewed", name)
+	}
+}
+
+func TestTLSInvalidCertificateFailsStart(t *testing.T) {
+	dir := t.TempDir()
+	h := New("127.0.0.1", 0, http.NotFoundHandler(), WithTLS(filepath.Join(dir, "missing.pem"), filepath.Join(dir, "missing.key")))
+	if err := h.Start(context.Background()); !os.IsNotExist(err) {
+		t.Errorf("Start returned %v, want the missing certificate error", err)
+	}
+}
+
+func TestTLSConfigIsUsed(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "configured")
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := New("127.0.0.1", 0, http.NotFoundHandler(), WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}))
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- h.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-started
+	}()
+	waitFor(t, "listener", func() bool { return h.Listeners() != nil })
+
+	if name := servedName(t, h.Listeners()[h.Name()].Addr().String()); name != "configured" {
+		t.Fatalf("served certificate %q, want the one of the TLS config", name)
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/mirzakhany/sysd/apps/kafka
+
+go 1.21.3
+
+require (
+	github.com/mirzakhany/sysd v0.1.2
+	github.com/twmb/franz-go v1.15.2
+	github.com/twmb/franz-go/pkg/kadm v1.10.0
+	github.com/twmb/franz-go/pkg/kfake v0.0.0-20231206062516-c09dc92d2db1
+)
+
+require (
+	github.com/klauspost/compress v1.17.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.18 // indirect
+	github.com/twmb/franz-go/pkg/kmsg v1.7.0 // indirect
+	golang.org/x/crypto v0.14.0 // indirect
+)
+
+replace github.com/mirzakhany/sysd => ../..
@@ -0,0 +1,237 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+var _ sysd.App = &Consumer{}
+
+// commitTimeout bounds committing the processed offsets on shutdown
+const commitTimeout = 10 * time.Second
+
+// Handler processes a single record
+type Handler func(ctx context.Context, record *kgo.Record) error
+
+// BatchHandler processes the records of one poll at once
+type BatchHandler func(ctx context.Context, records []*kgo.Record) error
+
+// Option configures the consumer
+type Option func(c *Consumer)
+
+// WithName sets the name of the app, the group name by default
+func WithName(name string) Option {
+	return func(c *Consumer) {
+		c.name = name
+	}
+}
+
+// WithMaxLag reports the app degraded while the lag of the group is over max records, zero disables the check
+func WithMaxLag(max int64) Option {
+	return func(c *Consumer) {
+		c.maxLag = max
+	}
+}
+
+// WithClientOptions adds options passed to kgo.NewClient, e.g. for TLS or SASL
+func WithClientOptions(opts ...kgo.Opt) Option {
+	return func(c *Consumer) {
+		c.clientOpts = append(c.clientOpts, opts...)
+	}
+}
+
+// Consumer consumes topics as a member of a consumer group with cooperative rebalancing.
+// offsets are committed only for processed records, when partitions are revoked and on shutdown,
+// so a handler error fails Start and the records are consumed again once the app is restarted
+type Consumer struct {
+	name    string
+	brokers []string
+	group   string
+	topics  []string
+
+	handler      Handler
+	batchHandler BatchHandler
+
+	maxLag     int64
+	clientOpts []kgo.Opt
+
+	mu     sync.Mutex
+	client *kgo.Client
+}
+
+// New returns a consumer calling the handler for each record
+func New(brokers []string, group string, topics []string, handler Handler, opts ...Option) *Consumer {
+	c := newConsumer(brokers, group, topics, opts)
+	c.handler = handler
+	return c
+}
+
+// NewBatch returns a consumer calling the handler with the records of each poll
+func NewBatch(brokers []string, group string, topics []string, handler BatchHandler, opts ...Option) *Consumer {
+	c := newConsumer(brokers, group, topics, opts)
+	c.batchHandler = handler
+	return c
+}
+
+func newConsumer(brokers []string, group string, topics []string, opts []Option) *Consumer {
+	c := &Consumer{name: group, brokers: brokers, group: group, topics: topics}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Consumer) Start(ctx context.Context) error {
+	logger := sysd.LoggerFrom(ctx)
+	opts := append([]kgo.Opt{
+		kgo.SeedBrokers(c.brokers...),
+		kgo.ConsumerGroup(c.group),
+		kgo.ConsumeTopics(c.topics...),
+		kgo.Balancers(kgo.CooperativeStickyBalancer()),
+		kgo.AutoCommitMarks(),
+		kgo.BlockRebalanceOnPoll(),
+		kgo.OnPartitionsRevoked(func(ctx context.Context, client *kgo.Client, _ map[string][]int32) {
+			if err := client.CommitMarkedOffsets(ctx); err != nil {
+				logger.Error("kafka consumer %q commit on revoke failed: %v", c.group, err)
+			}
+		}),
+	}, c.clientOpts...)
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return fmt.Errorf("unable to create kafka client: %w", err)
+	}
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return fmt.Errorf("unable to reach kafka brokers: %w", err)
+	}
+
+	c.mu.Lock()
+	c.client = client
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.client = nil
+		c.mu.Unlock()
+	}()
+
+	err = c.consume(ctx, client)
+
+	// commit what was processed before leaving the group, the context is done already
+	commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commitTimeout)
+	defer cancel()
+	if commitErr := client.CommitMarkedOffsets(commitCtx); commitErr != nil {
+		err = errors.Join(err, fmt.Errorf("unable to commit offsets: %w", commitErr))
+	}
+	client.Close()
+	return err
+}
+
+// consume polls and handles records until the context is done or handling fails
+func (c *Consumer) consume(ctx context.Context, client *kgo.Client) error {
+	for {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return nil
+		}
+
+		var fetchErr error
+		fetches.EachError(func(topic string, partition int32, err error) {
+			fetchErr = errors.Join(fetchErr, fmt.Errorf("fetching %s/%d: %w", topic, partition, err))
+		})
+		if fetchErr != nil {
+			client.AllowRebalance()
+			return fetchErr
+		}
+
+		records := fetches.Records()
+		if err := c.handle(ctx, client, records); err != nil {
+			client.AllowRebalance()
+			return err
+		}
+		client.AllowRebalance()
+	}
+}
+
+// handle passes the records to the handler and marks the processed ones for commit
+func (c *Consumer) handle(ctx context.Context, client *kgo.Client, records []*kgo.Record) error {
+	if c.batchHandler != nil {
+		if err := c.batchHandler(ctx, records); err != nil {
+			return err
+		}
+		client.MarkCommitRecords(records...)
+		return nil
+	}
+
+	for _, record := range records {
+		if err := c.handler(ctx, record); err != nil {
+			return fmt.Errorf("handling %s/%d@%d: %w", record.Topic, record.Partition, record.Offset, err)
+		}
+		client.MarkCommitRecords(record)
+	}
+	return nil
+}
+
+// Status checks the brokers are reachable and reports the app degraded if the lag is over the max lag
+func (c *Consumer) Status(ctx context.Context) error {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+
+	if client == nil {
+		return fmt.Errorf("kafka client is nil")
+	}
+	if err := client.Ping(ctx); err != nil {
+		return err
+	}
+	if c.maxLag == 0 {
+		return nil
+	}
+
+	lag, err := c.lag(ctx, client)
+	if err != nil {
+		return sysd.Degraded(err)
+	}
+	if lag > c.maxLag {
+		return sysd.Degraded(fmt.Errorf("kafka consumer %q lag %d is over %d", c.group, lag, c.maxLag))
+	}
+	return nil
+}
+
+// Lag returns the number of records the group has not consumed yet, across all its partitions
+func (c *Consumer) Lag(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+
+	if client == nil {
+		return 0, fmt.Errorf("kafka client is nil")
+	}
+	return c.lag(ctx, client)
+}
+
+func (c *Consumer) lag(ctx context.Context, client *kgo.Client) (int64, error) {
+	lags, err := kadm.NewClient(client).Lag(ctx, c.group)
+	if err != nil {
+		return 0, err
+	}
+	described, ok := lags[c.group]
+	if !ok {
+		return 0, fmt.Errorf("kafka consumer %q lag not found", c.group)
+	}
+	if err := described.Error(); err != nil {
+		return 0, err
+	}
+	return described.Lag.Total(), nil
+}
+
+func (c *Consumer) Name() string {
+	return c.name
+}
@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const topic = "orders"
+
+// newCluster returns a fake kafka cluster with the records produced to the topic
+func newCluster(t *testing.T, values ...string) *kfake.Cluster {
+	t.Helper()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, topic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+
+	client, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.DefaultProduceTopic(topic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	for _, v := range values {
+		if err := client.ProduceSync(context.Background(), kgo.StringRecord(v)).FirstErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cluster
+}
+
+// committed returns the offset committed by the group on the only partition of the topic
+func committed(t *testing.T, cluster *kfake.Cluster, group string) int64 {
+	t.Helper()
+
+	client, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	offsets, err := kadm.NewClient(client).FetchOffsets(context.Background(), group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, ok := offsets.Lookup(topic, 0)
+	if !ok {
+		return -1
+	}
+	return o.At
+}
+
+// start runs the consumer until the test ends, returning the result of Start
+func start(t *testing.T, c *Consumer) (context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done, returned := make(chan error, 1), make(chan struct{})
+	go func() {
+		defer close(returned)
+		done <- c.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-returned
+	})
+	return cancel, done
+}
+
+func TestConsumerCommitsHandledRecordsOnShutdown(t *testing.T) {
+	cluster := newCluster(t, "a", "b", "c")
+	handled := make(chan string, 3)
+	c := New(cluster.ListenAddrs(), "billing", []string{topic}, func(_ context.Context, r *kgo.Record) error {
+		handled <- string(r.Value)
+		return nil
+	})
+	if c.Name() != "billing" {
+		t.Errorf("app named %q, want the group name", c.Name())
+	}
+	if err := c.Status(context.Background()); err == nil {
+		t.Error("Status before start returned nil, want an error")
+	}
+
+	cancel, done := start(t, c)
+	for _, want := range []string{"a", "b", "c"} {
+		select {
+		case got := <-handled:
+			if got != want {
+				t.Fatalf("handled %q, want %q", got, want)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("record %q not handled", want)
+		}
+	}
+	if err := c.Status(context.Background()); err != nil {
+		t.Errorf("Status returned %v, want nil while consuming", err)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start returned %v, want nil on shutdown", err)
+	}
+	if got := committed(t, cluster, "billing"); got != 3 {
+		t.Errorf("committed offset %d, want 3", got)
+	}
+}
+
+func TestConsumerHandlerErrorFailsStart(t *testing.T) {
+	cluster := newCluster(t, "a", "b")
+	boom := errors.New("boom")
+	c := NewBatch(cluster.ListenAddrs(), "billing", []string{topic}, func(context.Context, []*kgo.Record) error {
+		return boom
+	}, WithName("billing-consumer"))
+	if c.Name() != "billing-consumer" {
+		t.Errorf("app named %q, want the name option", c.Name())
+	}
+
+	_, done := start(t, c)
+	select {
+	case err := <-done:
+		if !errors.Is(err, boom) {
+			t.Fatalf("Start returned %v, want the handler error", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Start did not return on the handler error")
+	}
+	// the records are consumed again once the app is restarted
+	if got := committed(t, cluster, "billing"); got > 0 {
+		t.Errorf("committed offset %d, want nothing committed for the failed batch", got)
+	}
+}
+
+func TestConsumerUnreachableBrokers(t *testing.T) {
+	c := New([]string{"127.0.0.1:1"}, "billing", []string{topic}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.Start(ctx); err == nil {
+		t.Fatal("Start returned nil, want an error for unreachable brokers")
+	}
+}
@@ -0,0 +1,110 @@
+package metricsd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	sysdprom "github.com/mirzakhany/sysd/prometheus"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var _ sysd.App = &Metricsd{}
+
+// shutdownTimeout bounds waiting for in-flight scrapes on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// Metricsd serves the metrics of the systemd service, the Go runtime and the process, along with
+// any registered collectors, on /metrics in the prometheus exposition format
+type Metricsd struct {
+	Host string
+	Port int
+
+	registry *prom.Registry
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+func New(Host string, Port int, s *sysd.Systemd) *Metricsd {
+	registry := prom.NewRegistry()
+	registry.MustRegister(
+		sysdprom.NewCollector(s),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return &Metricsd{Host: Host, Port: Port, registry: registry}
+}
+
+// Register registers collectors of other apps to be served along with the supervisor ones
+func (m *Metricsd) Register(cs ...prom.Collector) error {
+	for _, c := range cs {
+		if err := m.registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Registry returns the registry served on /metrics
+func (m *Metricsd) Registry() *prom.Registry {
+	return m.registry
+}
+
+func (m *Metricsd) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry}))
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(m.Host, strconv.Itoa(m.Port)),
+		Handler: mux,
+	}
+
+	ln, err := sysd.Listen(ctx, m.Name(), "tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.server = srv
+	m.mu.Unlock()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// Status checks the server is running and the registered collectors can be gathered
+func (m *Metricsd) Status(ctx context.Context) error {
+	m.mu.Lock()
+	srv := m.server
+	m.mu.Unlock()
+
+	if srv == nil {
+		return errors.New("metricsd server is not running")
+	}
+	_, err := m.registry.Gather()
+	return err
+}
+
+func (m *Metricsd) Name() string {
+	return "metricsd"
+}
@@ -0,0 +1,130 @@
+package metricsd
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// startServer starts the app in a systemd service on a local listener and returns its address
+func startServer(t *testing.T, m *Metricsd, h *sysdtest.Harness) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Systemd.SetListenerProvider(sysd.ListenerProviderFunc(func(name string) (net.Listener, bool) {
+		return lis, name == m.Name()
+	}))
+	if err := h.Systemd.Add(m); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState(m.Name(), sysd.AppRunning)
+	return lis.Addr().String()
+}
+
+func scrape(t *testing.T, addr string) string {
+	t.Helper()
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape returned %s: %s", resp.Status, body)
+	}
+	return string(body)
+}
+
+func TestServesSupervisorAndRegisteredMetrics(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	m := New("127.0.0.1", 0, h.Systemd)
+	if m.Name() != "metricsd" {
+		t.Errorf("app named %q, want metricsd", m.Name())
+	}
+	if err := m.Status(context.Background()); err == nil {
+		t.Error("Status before start returned nil, want an error")
+	}
+
+	orders := prom.NewCounter(prom.CounterOpts{Name: "orders_total", Help: "Orders placed."})
+	if err := m.Register(orders); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Register(orders); err == nil {
+		t.Error("Register returned nil for a collector registered already")
+	}
+	orders.Inc()
+	addr := startServer(t, m, h)
+
+	body := scrape(t, addr)
+	for _, metric := range []string{
+		`sysd_app_state{app="metricsd"`,
+		"orders_total 1",
+		"go_goroutines",
+		"process_start_time_seconds",
+	} {
+		if !strings.Contains(body, metric) {
+			t.Errorf("scrape is missing %s", metric)
+		}
+	}
+	if err := m.Status(context.Background()); err != nil {
+		t.Errorf("Status returned %v, want nil while serving", err)
+	}
+}
+
+// failingCollector cannot be gathered
+type failingCollector struct {
+	desc *prom.Desc
+}
+
+func (c failingCollector) Describe(ch chan<- *prom.Desc) { ch <- c.desc }
+
+func (c failingCollector) Collect(ch chan<- prom.Metric) {
+	ch <- prom.NewInvalidMetric(c.desc, io.ErrUnexpectedEOF)
+}
+
+func TestStatusFailsOnBrokenCollector(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	m := New("127.0.0.1", 0, h.Systemd)
+	startServer(t, m, h)
+
+	if err := m.Register(failingCollector{prom.NewDesc("broken", "Never gathered.", nil, nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Status(context.Background()); err == nil {
+		t.Error("Status returned nil, want the error of the broken collector")
+	}
+}
+
+func TestShutdownStopsServing(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	m := New("127.0.0.1", 0, h.Systemd)
+	addr := startServer(t, m, h)
+	scrape(t, addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), sysdtest.WaitTimeout)
+	defer cancel()
+	if err := h.Systemd.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	client := http.Client{Timeout: time.Second}
+	if resp, err := client.Get("http://" + addr + "/metrics"); err == nil {
+		resp.Body.Close()
+		t.Error("scrape succeeded after shutdown, want the server closed")
+	}
+}
@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+var _ sysd.App = &Mongo{}
+
+// disconnectTimeout bounds closing the connections on shutdown
+const disconnectTimeout = 10 * time.Second
+
+type Mongo struct {
+	clientOpts   *options.ClientOptions
+	databaseName string
+
+	mu     sync.Mutex
+	client *mongo.Client
+}
+
+func New(DatabaseName, Username, Password, Host string, Port int) *Mongo {
+	uri := fmt.Sprintf("mongodb://%s:%s@%s:%d", Username, Password, Host, Port)
+	return NewWithURI(uri, DatabaseName)
+}
+
+func NewWithURI(uri, DatabaseName string) *Mongo {
+	return &Mongo{
+		clientOpts:   options.Client().ApplyURI(uri),
+		databaseName: DatabaseName,
+	}
+}
+
+func (m *Mongo) Start(ctx context.Context) error {
+	client, err := mongo.Connect(ctx, m.clientOpts)
+	if err != nil {
+		return fmt.Errorf("unable to connect to mongo: %w", err)
+	}
+
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(context.WithoutCancel(ctx))
+		return fmt.Errorf("unable to ping mongo: %w", err)
+	}
+	m.mu.Lock()
+	m.client = client
+	m.mu.Unlock()
+
+	return sysd.ShutdownGracefully(ctx, func() error {
+		// the app context is done, disconnecting needs a live one
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), disconnectTimeout)
+		defer cancel()
+		return client.Disconnect(ctx)
+	})
+}
+
+func (m *Mongo) Status(ctx context.Context) error {
+	client, err := m.Client()
+	if err != nil {
+		return err
+	}
+	return client.Ping(ctx, readpref.Primary())
+}
+
+func (m *Mongo) Name() string {
+	return "mongo"
+}
+
+func (m *Mongo) Client() (*mongo.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.client != nil {
+		return m.client, nil
+	}
+	return nil, fmt.Errorf("mongo client is nil")
+}
+
+func (m *Mongo) Database() (*mongo.Database, error) {
+	client, err := m.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(m.databaseName), nil
+}
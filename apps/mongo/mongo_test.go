@@ -0,0 +1,48 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	m := New("orders", "app", "secret", "db.internal", 27017)
+	if m.Name() != "mongo" {
+		t.Errorf("app named %q, want mongo", m.Name())
+	}
+	if hosts := m.clientOpts.Hosts; len(hosts) != 1 || hosts[0] != "db.internal:27017" {
+		t.Errorf("hosts are %q, want db.internal:27017", hosts)
+	}
+	if auth := m.clientOpts.Auth; auth == nil || auth.Username != "app" || auth.Password != "secret" {
+		t.Errorf("auth is %+v, want the credentials", auth)
+	}
+	if m.databaseName != "orders" {
+		t.Errorf("database is %q, want orders", m.databaseName)
+	}
+}
+
+func TestNotStarted(t *testing.T) {
+	m := NewWithURI("mongodb://127.0.0.1:1", "orders")
+	if err := m.Status(context.Background()); err == nil {
+		t.Error("Status before start returned nil, want an error")
+	}
+	if _, err := m.Client(); err == nil {
+		t.Error("Client before start returned nil error, want an error")
+	}
+	if _, err := m.Database(); err == nil {
+		t.Error("Database before start returned nil error, want an error")
+	}
+}
+
+func TestStartUnreachableServer(t *testing.T) {
+	m := NewWithURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=100&connectTimeoutMS=100", "orders")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Start(ctx); err == nil {
+		t.Fatal("Start returned nil, want an error for an unreachable server")
+	}
+	if _, err := m.Client(); err == nil {
+		t.Error("Client after a failed start returned nil error, want an error")
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/mirzakhany/sysd/apps/mysql
+
+go 1.21.3
+
+require (
+	github.com/go-sql-driver/mysql v1.7.1
+	github.com/mirzakhany/sysd v0.1.2
+)
+
+replace github.com/mirzakhany/sysd => ../..
@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &MySQL{}
+
+type MySQL struct {
+	dsn string
+
+	mu sync.Mutex
+	db *sql.DB
+
+	// pool tuning applied on Start, zero values keep the database/sql defaults
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+}
+
+func New(DatabaseName, Username, Password, Host string, Port int) *MySQL {
+	conf := mysql.NewConfig()
+	conf.User = Username
+	conf.Passwd = Password
+	conf.Net = "tcp"
+	conf.Addr = net.JoinHostPort(Host, strconv.Itoa(Port))
+	conf.DBName = DatabaseName
+	conf.ParseTime = true
+
+	return &MySQL{dsn: conf.FormatDSN()}
+}
+
+func NewWithDSN(dsn string) (*MySQL, error) {
+	if _, err := mysql.ParseDSN(dsn); err != nil {
+		return nil, err
+	}
+
+	return &MySQL{dsn: dsn}, nil
+}
+
+// SetMaxOpenConns sets the maximum number of open connections of the pool
+func (m *MySQL) SetMaxOpenConns(n int) {
+	m.maxOpenConns = n
+}
+
+// SetMaxIdleConns sets the maximum number of idle connections kept in the pool
+func (m *MySQL) SetMaxIdleConns(n int) {
+	m.maxIdleConns = n
+}
+
+// SetConnMaxLifetime sets how long a connection may be reused, it should be shorter
+// than the wait_timeout of the server
+func (m *MySQL) SetConnMaxLifetime(d time.Duration) {
+	m.connMaxLifetime = d
+}
+
+// SetConnMaxIdleTime sets how long a connection may be idle before it is closed
+func (m *MySQL) SetConnMaxIdleTime(d time.Duration) {
+	m.connMaxIdleTime = d
+}
+
+func (m *MySQL) Start(ctx context.Context) error {
+	db, err := sql.Open("mysql", m.dsn)
+	if err != nil {
+		return fmt.Errorf("unable to open database: %w", err)
+	}
+	if m.maxOpenConns > 0 {
+		db.SetMaxOpenConns(m.maxOpenConns)
+	}
+	if m.maxIdleConns > 0 {
+		db.SetMaxIdleConns(m.maxIdleConns)
+	}
+	if m.connMaxLifetime > 0 {
+		db.SetConnMaxLifetime(m.connMaxLifetime)
+	}
+	if m.connMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(m.connMaxIdleTime)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return fmt.Errorf("unable to ping database: %w", err)
+	}
+	m.mu.Lock()
+	m.db = db
+	m.mu.Unlock()
+
+	return sysd.ShutdownGracefully(ctx, func() error {
+		return db.Close()
+	})
+}
+
+func (m *MySQL) Status(ctx context.Context) error {
+	db, err := m.Connection()
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
+
+func (m *MySQL) Name() string {
+	return "mysql"
+}
+
+func (m *MySQL) Connection() (*sql.DB, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.db != nil {
+		return m.db, nil
+	}
+	return nil, fmt.Errorf("mysql connection is nil")
+}
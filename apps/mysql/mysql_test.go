@@ -0,0 +1,202 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer speaks enough of the MySQL protocol to accept any login and answer pings
+type fakeServer struct {
+	l net.Listener
+
+	mu     sync.Mutex
+	closed bool
+	conns  []net.Conn
+	wg     sync.WaitGroup
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{l: l}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeServer) hostPort(t *testing.T) (string, int) {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(s.l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, p
+}
+
+// Close stops accepting and closes the open connections
+func (s *fakeServer) Close() {
+	_ = s.l.Close()
+	s.mu.Lock()
+	s.closed = true
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *fakeServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns = append(s.conns, conn)
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			_ = s.handle(conn)
+		}()
+	}
+}
+
+const (
+	capabilities = 0x1 | 0x8 | 0x200 | 0x2000 | 0x8000 | 0x80000 // long password, db, 4.1 protocol, transactions, secure connection, auth plugin
+	comQuit      = 0x01
+)
+
+// handle greets the client, accepts its login and answers every command with OK
+func (s *fakeServer) handle(conn net.Conn) error {
+	greeting := []byte{10}
+	greeting = append(greeting, "8.0.0-fake\x00"...)
+	greeting = binary.LittleEndian.AppendUint32(greeting, 1)
+	greeting = append(greeting, "abcdefgh\x00"...)
+	greeting = binary.LittleEndian.AppendUint16(greeting, capabilities&0xffff)
+	greeting = append(greeting, 33, 2, 0)
+	greeting = binary.LittleEndian.AppendUint16(greeting, capabilities>>16)
+	greeting = append(greeting, 21)
+	greeting = append(greeting, make([]byte, 10)...)
+	greeting = append(greeting, "ijklmnopqrst\x00"...)
+	greeting = append(greeting, "mysql_native_password\x00"...)
+	if err := writePacket(conn, 0, greeting); err != nil {
+		return err
+	}
+
+	for {
+		seq, payload, err := readPacket(conn)
+		if err != nil {
+			return err
+		}
+		if seq == 0 && len(payload) > 0 && payload[0] == comQuit {
+			return nil
+		}
+		if err := writePacket(conn, seq+1, []byte{0, 0, 0, 2, 0, 0, 0}); err != nil {
+			return err
+		}
+	}
+}
+
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	_, err := io.ReadFull(r, payload)
+	return header[3], payload, err
+}
+
+func writePacket(w io.Writer, seq byte, payload []byte) error {
+	n := len(payload)
+	_, err := w.Write(append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...))
+	return err
+}
+
+func TestStartAndStatus(t *testing.T) {
+	srv := newFakeServer(t)
+	host, port := srv.hostPort(t)
+	m := New("orders", "app", "secret", host, port)
+	m.SetMaxOpenConns(3)
+	if m.Name() != "mysql" {
+		t.Errorf("app named %q, want mysql", m.Name())
+	}
+	if err := m.Status(context.Background()); err == nil {
+		t.Error("Status before start returned nil, want an error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Start(ctx) }()
+	var db *sql.DB
+	for deadline := time.Now().Add(5 * time.Second); db == nil; time.Sleep(5 * time.Millisecond) {
+		db, _ = m.Connection()
+		if time.Now().After(deadline) {
+			t.Fatal("not connected")
+		}
+	}
+	if err := m.Status(context.Background()); err != nil {
+		t.Errorf("Status returned %v, want nil while connected", err)
+	}
+	if max := db.Stats().MaxOpenConnections; max != 3 {
+		t.Errorf("pool opens up to %d connections, want 3", max)
+	}
+
+	srv.Close()
+	if err := m.Status(context.Background()); err == nil {
+		t.Error("Status returned nil, want an error once the server is gone")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start returned %v, want nil on shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return on shutdown")
+	}
+}
+
+func TestNewWithDSN(t *testing.T) {
+	if _, err := NewWithDSN("app:secret@tcp(127.0.0.1:3306)/orders"); err != nil {
+		t.Errorf("NewWithDSN returned %v for a valid dsn", err)
+	}
+	if _, err := NewWithDSN("app:secret@tcp(127.0.0.1:3306"); err == nil {
+		t.Error("NewWithDSN returned nil error for an invalid dsn")
+	}
+}
+
+func TestStartUnreachableServer(t *testing.T) {
+	m := New("orders", "app", "secret", "127.0.0.1", 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Start(ctx); err == nil {
+		t.Fatal("Start returned nil, want an error for an unreachable server")
+	}
+}
@@ -0,0 +1,132 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/nats-io/nats.go"
+)
+
+var _ sysd.App = &NATS{}
+
+type NATS struct {
+	url  string
+	opts []nats.Option
+
+	// jetStream is set to create a JetStream context on Start, with jsOpts
+	jetStream bool
+	jsOpts    []nats.JSOpt
+
+	mu   sync.Mutex
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+func New(url string, opts ...nats.Option) *NATS {
+	return &NATS{url: url, opts: opts}
+}
+
+// EnableJetStream creates a JetStream context on Start, available through JetStream
+func (n *NATS) EnableJetStream(opts ...nats.JSOpt) {
+	n.jetStream = true
+	n.jsOpts = opts
+}
+
+// Start connects to the server and keeps the connection until the context is done, the client
+// reconnects on its own meanwhile. Start fails once the client gives up reconnecting. on shutdown
+// the subscriptions are drained before the connection is closed
+func (n *NATS) Start(ctx context.Context) error {
+	closed := make(chan struct{})
+	opts := append(append([]nats.Option{}, n.opts...),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("nats disconnected: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			log.Printf("nats reconnected to %s", conn.ConnectedUrlRedacted())
+		}),
+		nats.ClosedHandler(func(*nats.Conn) {
+			close(closed)
+		}),
+	)
+
+	conn, err := nats.Connect(n.url, opts...)
+	if err != nil {
+		return fmt.Errorf("unable to connect to nats: %w", err)
+	}
+
+	var js nats.JetStreamContext
+	if n.jetStream {
+		if js, err = conn.JetStream(n.jsOpts...); err != nil {
+			conn.Close()
+			return fmt.Errorf("unable to create jetstream context: %w", err)
+		}
+	}
+
+	n.mu.Lock()
+	n.conn, n.js = conn, js
+	n.mu.Unlock()
+
+	select {
+	case <-closed:
+		return fmt.Errorf("nats connection closed: %w", conn.LastError())
+	case <-ctx.Done():
+	}
+
+	if err := conn.Drain(); err != nil {
+		conn.Close()
+		return fmt.Errorf("unable to drain nats connection: %w", err)
+	}
+	// the connection is closed once draining is done or the drain timeout expired
+	<-closed
+	return nil
+}
+
+// Status reports the app degraded while the client is reconnecting
+func (n *NATS) Status(ctx context.Context) error {
+	n.mu.Lock()
+	conn := n.conn
+	n.mu.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("nats connection is nil")
+	}
+
+	switch status := conn.Status(); status {
+	case nats.CONNECTED, nats.DRAINING_SUBS, nats.DRAINING_PUBS:
+		return nil
+	case nats.RECONNECTING, nats.CONNECTING:
+		return sysd.Degraded(fmt.Errorf("nats is %s", status))
+	default:
+		return fmt.Errorf("nats is %s", status)
+	}
+}
+
+func (n *NATS) Name() string {
+	return "nats"
+}
+
+func (n *NATS) Conn() (*nats.Conn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn != nil {
+		return n.conn, nil
+	}
+	return nil, fmt.Errorf("nats connection is nil")
+}
+
+// JetStream returns the JetStream context, see EnableJetStream
+func (n *NATS) JetStream() (nats.JetStreamContext, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.js != nil {
+		return n.js, nil
+	}
+	return nil, fmt.Errorf("nats jetstream context is nil")
+}
@@ -0,0 +1,142 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+)
+
+// runServer runs an in-process nats server with JetStream until the test ends
+func runServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	opts := natsserver.DefaultTestOptions
+	opts.Port = server.RANDOM_PORT
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	srv := natsserver.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+// start runs the app until the test ends, returning the result of Start
+func start(t *testing.T, n *NATS) (context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done, returned := make(chan error, 1), make(chan struct{})
+	go func() {
+		defer close(returned)
+		done <- n.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-returned
+	})
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if _, err := n.Conn(); err == nil {
+			return cancel, done
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("not connected")
+		}
+	}
+}
+
+func TestStatusFollowsConnection(t *testing.T) {
+	srv := runServer(t)
+	n := New(srv.ClientURL(), nats.ReconnectWait(time.Hour))
+	if n.Name() != "nats" {
+		t.Errorf("app named %q, want nats", n.Name())
+	}
+	if err := n.Status(context.Background()); err == nil {
+		t.Error("Status before start returned nil, want an error")
+	}
+	start(t, n)
+
+	if err := n.Status(context.Background()); err != nil {
+		t.Errorf("Status returned %v, want nil while connected", err)
+	}
+
+	// the client reconnects on its own, so the app is only degraded meanwhile
+	srv.Shutdown()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		err := n.Status(context.Background())
+		if errors.Is(err, sysd.ErrDegraded) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Status returned %v, want the app degraded while reconnecting", err)
+		}
+	}
+}
+
+func TestShutdownDrainsSubscriptions(t *testing.T) {
+	srv := runServer(t)
+	n := New(srv.ClientURL())
+	cancel, done := start(t, n)
+
+	conn, _ := n.Conn()
+	received, release := make(chan struct{}), make(chan struct{})
+	if _, err := conn.Subscribe("orders", func(*nats.Msg) {
+		close(received)
+		<-release
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Publish("orders", []byte("order")); err != nil {
+		t.Fatal(err)
+	}
+	<-received
+
+	cancel()
+	select {
+	case err := <-done:
+		t.Fatalf("Start returned %v while a message was handled", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start returned %v, want nil once drained", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return once the message was handled")
+	}
+}
+
+func TestJetStream(t *testing.T) {
+	srv := runServer(t)
+	n := New(srv.ClientURL())
+	if _, err := n.JetStream(); err == nil {
+		t.Error("JetStream before start returned nil error, want an error")
+	}
+	n.EnableJetStream()
+	start(t, n)
+
+	js, err := n.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := js.Publish("orders.new", []byte("order")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartUnreachableServer(t *testing.T) {
+	n := New("nats://127.0.0.1:1")
+	if err := n.Start(context.Background()); err == nil {
+		t.Fatal("Start returned nil, want an error for an unreachable server")
+	}
+}
@@ -15,3 +15,5 @@ require (
 	golang.org/x/sync v0.5.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
+
+replace github.com/mirzakhany/sysd => ../..
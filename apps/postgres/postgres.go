@@ -2,7 +2,10 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mirzakhany/sysd"
@@ -10,9 +13,18 @@ import (
 
 var _ sysd.App = &Postgres{}
 
+// ErrNotConnected is returned when the app is not connected to the database
+var ErrNotConnected = errors.New("postgres connection is nil")
+
 type Postgres struct {
 	connConf *pgxpool.Config
 	conn     *pgxpool.Pool
+
+	// maxSaturation is how long the pool may have all connections acquired before
+	// Status reports it unhealthy, zero disables the check
+	maxSaturation  time.Duration
+	saturatedSince time.Time
+	mu             sync.Mutex
 }
 
 func New(DatabaseName, Username, Password, Host string, Port int) (*Postgres, error) {
@@ -52,7 +64,45 @@ func (p *Postgres) Start(ctx context.Context) error {
 }
 
 func (p *Postgres) Status(ctx context.Context) error {
-	return p.conn.Ping(ctx)
+	if p.conn == nil {
+		return ErrNotConnected
+	}
+	if err := p.conn.Ping(ctx); err != nil {
+		return err
+	}
+	stat := p.conn.Stat()
+	return p.checkSaturation(stat.AcquiredConns(), stat.MaxConns(), time.Now())
+}
+
+// SetMaxSaturation sets how long the pool may have all its connections acquired
+// before Status reports it unhealthy, zero disables the check
+func (p *Postgres) SetMaxSaturation(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxSaturation = d
+}
+
+// checkSaturation returns an error if the pool had all its connections acquired for
+// longer than the max saturation at the given time
+func (p *Postgres) checkSaturation(acquired, maxConns int32, now time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.maxSaturation == 0 || acquired < maxConns {
+		p.saturatedSince = time.Time{}
+		return nil
+	}
+
+	if p.saturatedSince.IsZero() {
+		p.saturatedSince = now
+		return nil
+	}
+
+	if saturated := now.Sub(p.saturatedSince); saturated > p.maxSaturation {
+		return fmt.Errorf("postgres pool exhausted for %s, %d of %d connections acquired",
+			saturated.Round(time.Second), acquired, maxConns)
+	}
+	return nil
 }
 
 func (p *Postgres) Name() string {
@@ -63,5 +113,5 @@ func (p *Postgres) Connection() (*pgxpool.Pool, error) {
 	if p.conn != nil {
 		return p.conn, nil
 	}
-	return nil, fmt.Errorf("postgres connection is nil")
+	return nil, ErrNotConnected
 }
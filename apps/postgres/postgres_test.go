@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckSaturation(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	type check struct {
+		acquired, max int32
+		after         time.Duration
+		wantErr       bool
+	}
+	tests := []struct {
+		name          string
+		maxSaturation time.Duration
+		checks        []check
+	}{
+		{
+			name:          "disabled",
+			maxSaturation: 0,
+			checks:        []check{{acquired: 10, max: 10}, {acquired: 10, max: 10, after: time.Hour}},
+		},
+		{
+			name:          "below max",
+			maxSaturation: time.Second,
+			checks:        []check{{acquired: 9, max: 10}, {acquired: 9, max: 10, after: time.Hour}},
+		},
+		{
+			name:          "saturated up to the limit",
+			maxSaturation: time.Minute,
+			checks:        []check{{acquired: 10, max: 10}, {acquired: 10, max: 10, after: time.Minute}},
+		},
+		{
+			name:          "saturated past the limit",
+			maxSaturation: time.Minute,
+			checks: []check{
+				{acquired: 10, max: 10},
+				{acquired: 10, max: 10, after: time.Minute + time.Nanosecond, wantErr: true},
+			},
+		},
+		{
+			name:          "more acquired than max",
+			maxSaturation: time.Minute,
+			checks: []check{
+				{acquired: 11, max: 10},
+				{acquired: 11, max: 10, after: 2 * time.Minute, wantErr: true},
+			},
+		},
+		{
+			name:          "released connection resets the saturation",
+			maxSaturation: time.Minute,
+			checks: []check{
+				{acquired: 10, max: 10},
+				{acquired: 9, max: 10, after: 30 * time.Second},
+				{acquired: 10, max: 10, after: 40 * time.Second},
+				{acquired: 10, max: 10, after: 90 * time.Second},
+				{acquired: 10, max: 10, after: 101 * time.Second, wantErr: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Postgres{}
+			p.SetMaxSaturation(tt.maxSaturation)
+			for i, c := range tt.checks {
+				err := p.checkSaturation(c.acquired, c.max, start.Add(c.after))
+				if (err != nil) != c.wantErr {
+					t.Errorf("check %d returned %v, want error %v", i, err, c.wantErr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package probes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &Probes{}
+
+const (
+	// shutdownTimeout bounds waiting for in-flight probes on shutdown
+	shutdownTimeout = 5 * time.Second
+	// probeTimeout bounds the status checks of the readiness probe
+	probeTimeout = 5 * time.Second
+)
+
+// Option configures the probes
+type Option func(p *Probes)
+
+// WithOptional leaves the apps out of the readiness probe, e.g. a cache the service works without
+func WithOptional(apps ...string) Option {
+	return func(p *Probes) {
+		p.optional = append(p.optional, apps...)
+	}
+}
+
+// Probes serves the kubernetes style probes of the systemd service:
+//
+//	/healthz  liveness, the supervisor is running and its status watcher keeps ticking
+//	/readyz   readiness, every required app is ready and the service is not shutting down
+//
+// they answer 200 when passing and 503 otherwise, with a line per check in the body
+type Probes struct {
+	Host string
+	Port int
+
+	sysd     *sysd.Systemd
+	optional []string
+
+	mu     sync.Mutex
+	server *http.Server
+	// ctx is the context the app was started with, to see the service shutting down
+	ctx context.Context
+}
+
+func New(Host string, Port int, s *sysd.Systemd, opts ...Option) *Probes {
+	p := &Probes{Host: Host, Port: Port, sysd: s}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Handler returns the handler serving the probes, to mount them on another server
+func (p *Probes) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", p.serveLiveness)
+	mux.HandleFunc("/readyz", p.serveReadiness)
+	return mux
+}
+
+func (p *Probes) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(p.Host, strconv.Itoa(p.Port)),
+		Handler: p.Handler(),
+	}
+
+	ln, err := sysd.Listen(ctx, p.Name(), "tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.server = srv
+	p.ctx = ctx
+	p.mu.Unlock()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func (p *Probes) serveLiveness(w http.ResponseWriter, _ *http.Request) {
+	if !p.sysd.Alive() {
+		writeProbe(w, false, "[-]supervisor not alive")
+		return
+	}
+	writeProbe(w, true, "[+]supervisor ok")
+}
+
+func (p *Probes) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	appCtx := p.ctx
+	p.mu.Unlock()
+
+	if appCtx != nil {
+		if cause := sysd.ShutdownCause(appCtx); cause != nil {
+			writeProbe(w, false, fmt.Sprintf("[-]shutting down: %v", cause))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+	defer cancel()
+
+	ready := true
+	var lines []string
+	for _, app := range p.sysd.Snapshot() {
+		if slices.Contains(p.optional, app.Name) {
+			continue
+		}
+		if ok, _ := p.sysd.IsAppReady(ctx, app.Name); !ok {
+			ready = false
+			lines = append(lines, fmt.Sprintf("[-]%s %s", app.Name, app.State))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("[+]%s ok", app.Name))
+	}
+	writeProbe(w, ready, lines...)
+}
+
+func writeProbe(w http.ResponseWriter, ok bool, lines ...string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprintln(w, strings.Join(lines, "\n"))
+}
+
+func (p *Probes) Status(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.server == nil {
+		return errors.New("probes server is not running")
+	}
+	return nil
+}
+
+func (p *Probes) Name() string {
+	return "probes"
+}
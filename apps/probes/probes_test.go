@@ -0,0 +1,59 @@
+package probes
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// probe returns the status code and body of the probe at path
+func probe(t *testing.T, p *Probes, path string) (int, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestLiveness(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app")
+	p := New("127.0.0.1", 0, h.Systemd)
+
+	if code, _ := probe(t, p, "/healthz"); code != http.StatusServiceUnavailable {
+		t.Errorf("liveness before start answered %d", code)
+	}
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	if code, body := probe(t, p, "/healthz"); code != http.StatusOK || !strings.Contains(body, "[+]supervisor ok") {
+		t.Errorf("liveness of the running service answered %d: %s", code, body)
+	}
+}
+
+func TestReadinessSkipsOptionalApps(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app")
+	h.NewApp("cache").SetStatus(errors.New("unreachable"))
+	p := New("127.0.0.1", 0, h.Systemd, WithOptional("cache"))
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	h.WaitForState("cache", sysd.AppRunning)
+
+	code, body := probe(t, p, "/readyz")
+	if code != http.StatusOK || !strings.Contains(body, "[+]app ok") || strings.Contains(body, "cache") {
+		t.Errorf("readiness with an unhealthy optional app answered %d: %s", code, body)
+	}
+
+	// a required app failing its status check makes the service not ready
+	app.SetStatus(errors.New("unhealthy"))
+	strict := New("127.0.0.1", 0, h.Systemd)
+	code, body = probe(t, strict, "/readyz")
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "[-]cache") {
+		t.Errorf("readiness with an unhealthy required app answered %d: %s", code, body)
+	}
+}
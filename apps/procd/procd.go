@@ -0,0 +1,200 @@
+package procd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &Procd{}
+
+// ErrExited is returned when the process exits while the app is not stopping,
+// so the OnFailure policy of the app decides whether it is started again
+var ErrExited = errors.New("process exited")
+
+// defaultKillTimeout is how long the process is given to exit after SIGTERM before it is killed
+const defaultKillTimeout = 10 * time.Second
+
+// Option configures the process
+type Option func(p *Procd)
+
+// WithName sets the name of the app, the base name of the command by default
+func WithName(name string) Option {
+	return func(p *Procd) {
+		p.name = name
+	}
+}
+
+// WithDir sets the working directory of the process
+func WithDir(dir string) Option {
+	return func(p *Procd) {
+		p.dir = dir
+	}
+}
+
+// WithEnv adds environment variables, in "key=value" form, to the environment inherited by the process
+func WithEnv(env ...string) Option {
+	return func(p *Procd) {
+		p.env = append(p.env, env...)
+	}
+}
+
+// WithKillTimeout sets how long the process is given to exit after SIGTERM before it is killed
+func WithKillTimeout(timeout time.Duration) Option {
+	return func(p *Procd) {
+		p.killTimeout = timeout
+	}
+}
+
+// WithLogger sets the logger the output of the process is written to. the logger of the
+// systemd service running the app is used by default, see sysd.LoggerFrom
+func WithLogger(l sysd.Logger) Option {
+	return func(p *Procd) {
+		p.logger = l
+	}
+}
+
+// Procd runs an external command as an app. stdout and stderr of the process are written
+// to the logger line by line, prefixed with the app name
+type Procd struct {
+	name        string
+	path        string
+	args        []string
+	dir         string
+	env         []string
+	killTimeout time.Duration
+	// logger is set by WithLogger, nil for the logger of the systemd service
+	logger sysd.Logger
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+func New(path string, args []string, opts ...Option) *Procd {
+	p := &Procd{
+		name:        filepath.Base(path),
+		path:        path,
+		args:        args,
+		killTimeout: defaultKillTimeout,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Start runs the process until it exits or the context is done, then the process is sent
+// SIGTERM and killed if it has not exited within the kill timeout
+func (p *Procd) Start(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, p.path, p.args...)
+	cmd.Dir = p.dir
+	if len(p.env) > 0 {
+		cmd.Env = append(os.Environ(), p.env...)
+	}
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = p.killTimeout
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to start %s: %w", p.path, err)
+	}
+	p.mu.Lock()
+	p.cmd = cmd
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.cmd = nil
+		p.mu.Unlock()
+	}()
+
+	// the output must be read before Wait closes the pipes
+	var wg sync.WaitGroup
+	wg.Add(2)
+	logger := sysd.LoggerFrom(ctx)
+	go p.forward(&wg, stdout, "INFO", logger.Info)
+	go p.forward(&wg, stderr, "ERROR", logger.Error)
+	wg.Wait()
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		// stopped on shutdown, exiting on SIGTERM is expected
+		if killed(cmd.ProcessState) {
+			return fmt.Errorf("%s did not exit in %s after SIGTERM and was killed", p.name, p.killTimeout)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrExited, err)
+	}
+	return ErrExited
+}
+
+// forward writes each line read from r to the logger set by WithLogger at the given level,
+// or with the log function of the systemd service logger
+func (p *Procd) forward(wg *sync.WaitGroup, r io.Reader, level string, log func(format string, args ...any)) {
+	defer wg.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if p.logger != nil {
+			p.logger.Println(level, fmt.Sprintf("[%s] %s", p.name, scanner.Text()))
+			continue
+		}
+		log("%s", scanner.Text())
+	}
+}
+
+// killed returns true if the process was killed with SIGKILL
+func killed(state *os.ProcessState) bool {
+	if state == nil {
+		return false
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// Status reports whether the process is running
+func (p *Procd) Status(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil {
+		return fmt.Errorf("process %s is not running", p.name)
+	}
+	return nil
+}
+
+func (p *Procd) Name() string {
+	return p.name
+}
+
+// Pid returns the process id of the running process, or 0 if it is not running
+func (p *Procd) Pid() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
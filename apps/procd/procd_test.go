@@ -0,0 +1,60 @@
+package procd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+// captureLogger keeps the lines logged by the systemd service
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *captureLogger) Println(v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+func (l *captureLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOutputGoesToServiceLogger(t *testing.T) {
+	logger := &captureLogger{}
+	s := sysd.New(sysd.WithLogger(logger))
+	p := New("/bin/sh", []string{"-c", "echo to stdout; echo to stderr >&2; exec sleep 60"})
+	if err := s.Add(p, sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !logger.contains("to stdout") || !logger.contains("to stderr") {
+		if time.Now().After(deadline) {
+			t.Fatalf("process output not logged by the systemd service: %q", logger.lines)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
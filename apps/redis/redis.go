@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var _ sysd.App = &Redis{}
+
+const (
+	defaultConnectRetries    = 5
+	defaultConnectRetryDelay = time.Second
+)
+
+type Redis struct {
+	opts *goredis.Options
+
+	mu     sync.Mutex
+	client *goredis.Client
+
+	// connectRetries is how many times connecting is retried on Start, doubling
+	// connectRetryDelay each time, before Start fails
+	connectRetries    int
+	connectRetryDelay time.Duration
+}
+
+func New(Host string, Port int, Password string, DB int) *Redis {
+	return &Redis{
+		opts: &goredis.Options{
+			Addr:     fmt.Sprintf("%s:%d", Host, Port),
+			Password: Password,
+			DB:       DB,
+		},
+		connectRetries:    defaultConnectRetries,
+		connectRetryDelay: defaultConnectRetryDelay,
+	}
+}
+
+func NewWithURI(uri string) (*Redis, error) {
+	opts, err := goredis.ParseURL(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Redis{
+		opts:              opts,
+		connectRetries:    defaultConnectRetries,
+		connectRetryDelay: defaultConnectRetryDelay,
+	}, nil
+}
+
+// SetConnectRetries sets how many times connecting is retried on Start, the delay
+// between attempts starts at delay and doubles each time
+func (r *Redis) SetConnectRetries(retries int, delay time.Duration) {
+	r.connectRetries = retries
+	r.connectRetryDelay = delay
+}
+
+// SetPoolSize sets the maximum number of connections of the pool
+func (r *Redis) SetPoolSize(size int) {
+	r.opts.PoolSize = size
+}
+
+func (r *Redis) Start(ctx context.Context) error {
+	client := goredis.NewClient(r.opts)
+	if err := r.connect(ctx, client); err != nil {
+		_ = client.Close()
+		return fmt.Errorf("unable to connect to redis: %w", err)
+	}
+	r.mu.Lock()
+	r.client = client
+	r.mu.Unlock()
+
+	return sysd.ShutdownGracefully(ctx, func() error {
+		return client.Close()
+	})
+}
+
+// connect pings the server until it answers, retrying with a doubling delay
+func (r *Redis) connect(ctx context.Context, client *goredis.Client) error {
+	delay := r.connectRetryDelay
+	for i := 0; ; i++ {
+		err := client.Ping(ctx).Err()
+		if err == nil || i >= r.connectRetries {
+			return err
+		}
+
+		log.Printf("redis ping failed, retrying in %s: %v", delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (r *Redis) Status(ctx context.Context) error {
+	client, err := r.Client()
+	if err != nil {
+		return err
+	}
+	return client.Ping(ctx).Err()
+}
+
+func (r *Redis) Name() string {
+	return "redis"
+}
+
+func (r *Redis) Client() (*goredis.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil {
+		return r.client, nil
+	}
+	return nil, fmt.Errorf("redis client is nil")
+}
@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mirzakhany/sysd"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestStartAndStatus(t *testing.T) {
+	srv := miniredis.RunT(t)
+	r, err := NewWithURI("redis://" + srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Name() != "redis" {
+		t.Errorf("app named %q, want redis", r.Name())
+	}
+	if err := r.Status(context.Background()); err == nil {
+		t.Error("Status before start returned nil, want an error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Start(ctx) }()
+	// Start sets the client once connected, Status is only called by sysd after that
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if _, err := r.Client(); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client not connected")
+		}
+	}
+	if err := r.Status(context.Background()); err != nil {
+		t.Errorf("Status returned %v, want nil while connected", err)
+	}
+
+	srv.SetError("LOADING")
+	if err := r.Status(context.Background()); err == nil {
+		t.Error("Status returned nil, want the error of the server")
+	}
+	srv.SetError("")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start returned %v, want nil on shutdown", err)
+	}
+	if err := r.Status(context.Background()); err == nil {
+		t.Error("Status after shutdown returned nil, want the closed client error")
+	}
+}
+
+func TestStartRetriesConnecting(t *testing.T) {
+	srv := miniredis.NewMiniRedis()
+	// reserve an address nothing listens on until the server starts
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	addr := srv.Addr()
+	srv.Close()
+
+	r, err := NewWithURI("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetConnectRetries(2, 10*time.Millisecond)
+	if err := r.Start(context.Background()); err == nil {
+		t.Fatal("Start returned nil, want an error once the retries are used up")
+	}
+
+	r.SetConnectRetries(50, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- r.Start(ctx) }()
+	time.Sleep(20 * time.Millisecond)
+	if err := srv.StartAddr(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if _, err := r.Client(); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client not connected once the server started")
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start returned %v, want nil on shutdown", err)
+	}
+}
+
+func TestStateStore(t *testing.T) {
+	srv := miniredis.RunT(t)
+	store := NewStateStore(goredis.NewClient(&goredis.Options{Addr: srv.Addr()}), "sysd:state")
+
+	state, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Apps) != 0 {
+		t.Errorf("loaded %+v before saving, want an empty state", state)
+	}
+
+	saved := sysd.SavedState{Apps: map[string]sysd.SavedApp{"api": {Paused: true, Restarts: 3, Failures: 1}}}
+	if err := store.Save(context.Background(), saved); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.Exists("sysd:state") {
+		t.Error("state not saved under its key")
+	}
+	state, err = store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state, saved) {
+		t.Errorf("loaded %+v, want %+v", state, saved)
+	}
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/mirzakhany/sysd"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var _ sysd.StateStore = &StateStore{}
+
+// StateStore persists the supervisor state as JSON under a redis key, see sysd.SetStateStore
+type StateStore struct {
+	client goredis.UniversalClient
+	key    string
+}
+
+// NewStateStore returns a state store keeping the state under key
+func NewStateStore(client goredis.UniversalClient, key string) *StateStore {
+	return &StateStore{client: client, key: key}
+}
+
+func (r *StateStore) Load(ctx context.Context) (sysd.SavedState, error) {
+	var state sysd.SavedState
+	b, err := r.client.Get(ctx, r.key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(b, &state)
+	return state, err
+}
+
+func (r *StateStore) Save(ctx context.Context, state sysd.SavedState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.key, b, 0).Err()
+}
@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	_ "modernc.org/sqlite"
+)
+
+var _ sysd.App = &SQLite{}
+
+// checkpointTimeout bounds the WAL checkpoint on shutdown
+const checkpointTimeout = 30 * time.Second
+
+type SQLite struct {
+	path    string
+	pragmas []string
+
+	// mu guards db and the integrity check state
+	mu sync.Mutex
+	db *sql.DB
+
+	// integrityCheckInterval is how often Status runs PRAGMA quick_check, zero disables it
+	integrityCheckInterval time.Duration
+	lastIntegrityCheck     time.Time
+}
+
+// New returns an app opening the database file at path, it is created if it does not exist
+func New(path string) *SQLite {
+	return &SQLite{path: path}
+}
+
+// SetPragmas sets the pragmas run on Start, e.g. "journal_mode=WAL" or "busy_timeout=5000"
+func (s *SQLite) SetPragmas(pragmas ...string) {
+	s.pragmas = pragmas
+}
+
+// SetIntegrityCheckInterval makes Status run PRAGMA quick_check at most once per interval,
+// zero disables it
+func (s *SQLite) SetIntegrityCheckInterval(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.integrityCheckInterval = d
+}
+
+func (s *SQLite) Start(ctx context.Context) error {
+	db, err := sql.Open("sqlite", s.path)
+	if err != nil {
+		return fmt.Errorf("unable to open database: %w", err)
+	}
+
+	for _, pragma := range s.pragmas {
+		if _, err := db.ExecContext(ctx, "PRAGMA "+pragma); err != nil {
+			_ = db.Close()
+			return fmt.Errorf("unable to set pragma %q: %w", pragma, err)
+		}
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return fmt.Errorf("unable to ping database: %w", err)
+	}
+	s.mu.Lock()
+	s.db = db
+	s.mu.Unlock()
+
+	return sysd.ShutdownGracefully(ctx, func() error {
+		// move the WAL into the database file so it is complete on its own, the context is done already
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), checkpointTimeout)
+		defer cancel()
+
+		var err error
+		if _, checkpointErr := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); checkpointErr != nil {
+			err = fmt.Errorf("unable to checkpoint database: %w", checkpointErr)
+		}
+		return errors.Join(err, db.Close())
+	})
+}
+
+func (s *SQLite) Status(ctx context.Context) error {
+	db, err := s.Connection()
+	if err != nil {
+		return err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+	return s.checkIntegrity(ctx, db)
+}
+
+// checkIntegrity runs PRAGMA quick_check if the integrity check interval has passed
+func (s *SQLite) checkIntegrity(ctx context.Context, db *sql.DB) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.integrityCheckInterval == 0 || time.Since(s.lastIntegrityCheck) < s.integrityCheckInterval {
+		return nil
+	}
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("unable to check database integrity: %w", err)
+	}
+	s.lastIntegrityCheck = time.Now()
+	if result != "ok" {
+		return fmt.Errorf("sqlite integrity check failed: %s", result)
+	}
+	return nil
+}
+
+func (s *SQLite) Name() string {
+	return "sqlite"
+}
+
+func (s *SQLite) Connection() (*sql.DB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db != nil {
+		return s.db, nil
+	}
+	return nil, fmt.Errorf("sqlite connection is nil")
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// start runs the app until the test ends, returning the result of Start
+func start(t *testing.T, s *SQLite) (context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done, returned := make(chan error, 1), make(chan struct{})
+	go func() {
+		defer close(returned)
+		done <- s.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-returned
+	})
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if _, err := s.Connection(); err == nil {
+			return cancel, done
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("database not opened")
+		}
+	}
+}
+
+func TestStartAndStatus(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "orders.db"))
+	s.SetIntegrityCheckInterval(time.Hour)
+	if s.Name() != "sqlite" {
+		t.Errorf("app named %q, want sqlite", s.Name())
+	}
+	if err := s.Status(context.Background()); err == nil {
+		t.Error("Status before start returned nil, want an error")
+	}
+	start(t, s)
+
+	if err := s.Status(context.Background()); err != nil {
+		t.Errorf("Status returned %v, want nil while open", err)
+	}
+	s.mu.Lock()
+	checked := s.lastIntegrityCheck
+	s.mu.Unlock()
+	if checked.IsZero() {
+		t.Error("integrity not checked, want it checked on the first Status")
+	}
+
+	// the next check waits for the interval
+	if err := s.Status(context.Background()); err != nil {
+		t.Errorf("Status returned %v, want nil while open", err)
+	}
+	s.mu.Lock()
+	again := s.lastIntegrityCheck
+	s.mu.Unlock()
+	if !again.Equal(checked) {
+		t.Error("integrity checked again before the interval passed")
+	}
+}
+
+func TestShutdownCheckpointsWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.db")
+	s := New(path)
+	s.SetPragmas("journal_mode=WAL")
+	cancel, done := start(t, s)
+
+	db, _ := s.Connection()
+	if _, err := db.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO orders (id) VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path + "-wal"); err != nil || info.Size() == 0 {
+		t.Fatalf("write-ahead log missing before shutdown: %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start returned %v, want nil on shutdown", err)
+	}
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() != 0 {
+		t.Errorf("write-ahead log holds %d bytes after shutdown, want it checkpointed", info.Size())
+	}
+
+	reopened, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	var count int
+	if err := reopened.QueryRow("SELECT COUNT(*) FROM orders").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("found %d orders after shutdown, want 1", count)
+	}
+}
+
+func TestStartInvalidPragma(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "orders.db"))
+	s.SetPragmas("journal_mode=WAL", "not a pragma")
+	if err := s.Start(context.Background()); err == nil {
+		t.Fatal("Start returned nil, want an error for an invalid pragma")
+	}
+	if _, err := s.Connection(); err == nil {
+		t.Error("Connection returned a database after Start failed")
+	}
+}
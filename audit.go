@@ -0,0 +1,180 @@
+package sysd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AuditRecord is a lifecycle event as written to the audit trail
+type AuditRecord struct {
+	Time  time.Time `json:"time"`
+	Type  string    `json:"type"`
+	App   string    `json:"app,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// AuditSink stores the audit trail of the lifecycle events, records are written one at a
+// time in the order the events happened
+type AuditSink interface {
+	WriteAudit(r AuditRecord) error
+}
+
+// auditor writes the lifecycle events to the audit sinks. unlike subscribers it never drops events,
+// they are queued and written by the goroutine running while the systemd service runs
+type auditor struct {
+	mu    sync.Mutex
+	sinks []AuditSink
+	queue []AuditRecord
+	// wake is signalled when records are queued
+	wake chan struct{}
+}
+
+// AddAuditSink adds a sink the lifecycle events are written to, e.g. NewAuditFile, JSONLinesAuditSink
+// or WebhookAuditSink. sink errors are logged
+func (s *Systemd) AddAuditSink(sink AuditSink) {
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+
+	s.audit.sinks = append(s.audit.sinks, sink)
+}
+
+// record queues the event if there are sinks
+func (a *auditor) record(e Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.sinks) == 0 {
+		return
+	}
+	r := AuditRecord{Time: e.Time, Type: e.Type.String(), App: e.App}
+	if e.Err != nil {
+		r.Error = e.Err.Error()
+	}
+	a.queue = append(a.queue, r)
+	if a.wake != nil {
+		select {
+		case a.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// startAudit starts writing the queued records, the returned function stops it once the
+// remaining records are written
+func (s *Systemd) startAudit() func() {
+	a := &s.audit
+	wake := make(chan struct{}, 1)
+	a.mu.Lock()
+	a.wake = wake
+	a.mu.Unlock()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-wake:
+				s.flushAudit()
+			case <-stop:
+				s.flushAudit()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		a.mu.Lock()
+		a.wake = nil
+		a.mu.Unlock()
+	}
+}
+
+// flushAudit writes the queued records to all sinks
+func (s *Systemd) flushAudit() {
+	a := &s.audit
+	a.mu.Lock()
+	queue, sinks := a.queue, append([]AuditSink{}, a.sinks...)
+	a.queue = nil
+	a.mu.Unlock()
+
+	for _, r := range queue {
+		for _, sink := range sinks {
+			if err := sink.WriteAudit(r); err != nil {
+				s.logger.Error("Failed to write %s event of app %q to the audit trail: %v", r.Type, r.App, err)
+			}
+		}
+	}
+}
+
+type jsonLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// JSONLinesAuditSink writes the audit trail to w, a JSON object per line
+func JSONLinesAuditSink(w io.Writer) AuditSink {
+	return &jsonLinesSink{w: w}
+}
+
+func (j *jsonLinesSink) WriteAudit(r AuditRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.w.Write(append(b, '\n'))
+	return err
+}
+
+// webhookTimeout bounds posting a record to a webhook
+const webhookTimeout = 10 * time.Second
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// WebhookAuditSink posts each record as JSON to the url
+func WebhookAuditSink(url string) AuditSink {
+	return &webhookSink{url: url, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (w *webhookSink) WriteAudit(r AuditRecord) error {
+	return postJSON(context.Background(), w.client, w.url, r)
+}
+
+// postJSON posts v as JSON to the url, a non 2xx response is an error
+func postJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", url, resp.Status)
+	}
+	return nil
+}
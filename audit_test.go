@@ -0,0 +1,129 @@
+package sysd_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestAuditTrailRecordsLifecycleEvents(t *testing.T) {
+	var out syncBuffer
+	h := sysdtest.NewHarness(t)
+	h.Systemd.AddAuditSink(sysd.JSONLinesAuditSink(&out))
+	app := h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureRestart.RetryTimeout(time.Second)))
+	app.FailStarts(1, errors.New("boom"))
+	h.Start()
+
+	h.WaitForEvent(sysd.EventAppFailed, "app")
+	// the status watcher waits on the clock too, advance until the restart delay is over
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !app.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !app.Running() {
+		t.Fatal("app not restarted")
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+
+	var types []string
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		var r sysd.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("audit line %q: %v", scanner.Text(), err)
+		}
+		if r.Type == sysd.EventAppFailed.String() && r.Error != "boom" {
+			t.Errorf("failed record has error %q, want boom", r.Error)
+		}
+		types = append(types, r.Type)
+	}
+	want := []string{
+		sysd.EventAppStarted.String(),
+		sysd.EventAppFailed.String(),
+		sysd.EventAppRestarted.String(),
+		sysd.EventAppStarted.String(),
+		sysd.EventShutdownBegun.String(),
+		sysd.EventAppStopped.String(),
+	}
+	if !containsInOrder(types, want) {
+		t.Errorf("audit trail is %v, want %v in order", types, want)
+	}
+}
+
+// containsInOrder returns true if want is a subsequence of got
+func containsInOrder(got, want []string) bool {
+	i := 0
+	for _, s := range got {
+		if i < len(want) && s == want[i] {
+			i++
+		}
+	}
+	return i == len(want)
+}
+
+func TestAuditFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := sysd.NewAuditFile(path, 200, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for i := 0; i < 20; i++ {
+		if err := f.WriteAudit(sysd.AuditRecord{Time: time.Now(), Type: "app_started", App: "app"}); err != nil {
+			t.Fatalf("WriteAudit returned %v", err)
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("rotated file missing: %v", err)
+		}
+		if info.Size() > 200 {
+			t.Errorf("%s is %d bytes, over the max size", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more backups than kept: %v", err)
+	}
+}
+
+func TestWebhookAuditSinkPostsRecords(t *testing.T) {
+	records := make(chan sysd.AuditRecord, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rec sysd.AuditRecord
+		if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		records <- rec
+	}))
+	defer srv.Close()
+
+	sink := sysd.WebhookAuditSink(srv.URL)
+	if err := sink.WriteAudit(sysd.AuditRecord{Type: "app_failed", App: "app", Error: "boom"}); err != nil {
+		t.Fatalf("WriteAudit returned %v", err)
+	}
+	if rec := <-records; rec.Type != "app_failed" || rec.App != "app" || rec.Error != "boom" {
+		t.Errorf("posted %+v, want the record", rec)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	failing := sysd.WebhookAuditSink(missing.URL)
+	if err := failing.WriteAudit(sysd.AuditRecord{Type: "app_failed"}); err == nil {
+		t.Error("WriteAudit to a failing webhook returned no error")
+	}
+}
@@ -0,0 +1,108 @@
+package sysd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// AuditFile writes the audit trail to a file as JSON lines, rotating it once it grows over
+// the max size. rotated files are named after the file with a .1, .2, ... suffix, .1 the newest
+type AuditFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewAuditFile opens the audit file at path for appending, it is rotated once it grows over maxSize
+// bytes keeping maxBackups rotated files. a zero maxSize never rotates
+func NewAuditFile(path string, maxSize int64, maxBackups int) (*AuditFile, error) {
+	a := &AuditFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *AuditFile) open() error {
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	a.f, a.size = f, info.Size()
+	return nil
+}
+
+func (a *AuditFile) WriteAudit(r AuditRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.f == nil {
+		return os.ErrClosed
+	}
+	if a.maxSize > 0 && a.size > 0 && a.size+int64(len(b)) > a.maxSize {
+		if err := a.rotate(); err != nil {
+			return fmt.Errorf("rotating audit file: %w", err)
+		}
+	}
+	n, err := a.f.Write(b)
+	a.size += int64(n)
+	return err
+}
+
+// rotate shifts the rotated files, dropping the oldest, and starts a new file
+func (a *AuditFile) rotate() error {
+	if err := a.f.Close(); err != nil {
+		return err
+	}
+	a.f = nil
+
+	if a.maxBackups <= 0 {
+		if err := os.Remove(a.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return a.open()
+	}
+	for i := a.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(a.backup(i), a.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(a.path, a.backup(1)); err != nil {
+		return err
+	}
+	return a.open()
+}
+
+func (a *AuditFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", a.path, i)
+}
+
+// Close closes the audit file
+func (a *AuditFile) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.f == nil {
+		return nil
+	}
+	err := a.f.Close()
+	a.f = nil
+	return err
+}
@@ -0,0 +1,41 @@
+package sysd
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// backoff grows the delay between restarts exponentially
+type backoff struct {
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+}
+
+// Backoff returns a copy of the OnFailure waiting exponentially longer between restarts,
+// starting from the retry timeout and multiplying it on every consecutive failure up to max.
+// jitter randomizes each delay by up to the given fraction of it, e.g. 0.1 for ±10%
+func (o *OnFailure) Backoff(max time.Duration, multiplier, jitter float64) *OnFailure {
+	c := o.clone()
+	c.backoff = &backoff{max: max, multiplier: multiplier, jitter: jitter}
+	return c
+}
+
+// delay returns how long to wait before restarting after the given number of consecutive failures,
+// starting from zero
+func (o *OnFailure) delay(failures int) time.Duration {
+	if o.backoff == nil || o.backoff.multiplier <= 1 {
+		return o.retryTimeout
+	}
+
+	d := float64(o.retryTimeout) * math.Pow(o.backoff.multiplier, float64(failures))
+	if o.backoff.max > 0 && d > float64(o.backoff.max) {
+		d = float64(o.backoff.max)
+	}
+	if o.backoff.jitter > 0 {
+		//nolint:gosec // jitter does not need a secure random source
+		d += d * o.backoff.jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,44 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestRestartBackoffRetriesIndefinitely(t *testing.T) {
+	for attempt := 0; attempt < 1000; attempt++ {
+		delay, ok := sysd.OnFailureRestartBackoff.NextDelay(attempt, errors.New("failed"))
+		if !ok {
+			t.Fatalf("attempt %d is not retried", attempt)
+		}
+		// one minute at most, plus the jitter
+		if delay <= 0 || delay > time.Minute+6*time.Second {
+			t.Fatalf("attempt %d waits %s", attempt, delay)
+		}
+	}
+}
+
+func TestRestartBackoffRestartsAfterManyFailures(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureRestartBackoff))
+	app.FailStarts(5, errors.New("failed"))
+	h.Start()
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !app.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(10 * time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !app.Running() {
+		t.Fatalf("app is not running after %d starts", app.Starts())
+	}
+	if app.Starts() != 6 {
+		t.Errorf("app started %d times, want 6", app.Starts())
+	}
+	if h.Returned() {
+		t.Errorf("Start returned %v", h.Stop())
+	}
+}
@@ -0,0 +1,34 @@
+package sysd
+
+import (
+	"sync"
+	"time"
+)
+
+// restartBudget counts restarts within a sliding window
+type restartBudget struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	restarts []time.Time
+}
+
+// record records a restart at the given time and returns false if the budget is exceeded,
+// a budget with no max is never exceeded
+func (b *restartBudget) record(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.max <= 0 {
+		return true
+	}
+
+	// drop restarts that left the window
+	i := 0
+	for i < len(b.restarts) && now.Sub(b.restarts[i]) > b.window {
+		i++
+	}
+	b.restarts = append(b.restarts[i:], now)
+
+	return len(b.restarts) <= b.max
+}
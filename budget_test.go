@@ -0,0 +1,36 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestRestartBudgetShutsDown(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithDefaultOnFailure(sysd.OnFailureRestart.Retry(10).RetryTimeout(time.Second)))
+	h.Systemd.SetRestartBudget(3, time.Hour)
+	// each app alone stays within the budget, together they exceed it
+	a := h.NewApp("a").FailStarts(2, errors.New("flap"))
+	b := h.NewApp("b").FailStarts(2, errors.New("flap"))
+	healthy := h.NewApp("healthy")
+	h.Start()
+
+	deadline := time.Now().Add(sysdtest.WaitTimeout)
+	for !h.Returned() && time.Now().Before(deadline) {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := h.Wait(); !errors.Is(err, sysd.ErrRestartBudgetExceeded) {
+		t.Fatalf("Start returned %v, want %v", err, sysd.ErrRestartBudgetExceeded)
+	}
+	// the restart exceeding the budget is not started
+	if restarts := a.Starts() + b.Starts() - 2; restarts != 3 {
+		t.Errorf("apps restarted %d times, want the budget of 3", restarts)
+	}
+	if !errors.Is(healthy.Cause(), sysd.ErrShutdown) {
+		t.Errorf("healthy app cancelled with %v, want %v", healthy.Cause(), sysd.ErrShutdown)
+	}
+}
@@ -0,0 +1,132 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSubscriptionClosed is returned when publishing to a topic whose subscription was closed meanwhile
+var ErrSubscriptionClosed = errors.New("subscription closed")
+
+// defaultBusBuffer is the number of messages a subscriber can fall behind before publishers block
+const defaultBusBuffer = 16
+
+// Message is a message published on the bus
+type Message struct {
+	Topic   string
+	Payload any
+}
+
+// Bus is an in process publish/subscribe bus apps use to talk to each other, e.g. for cache
+// invalidation or config updates. a publisher blocks while a subscriber of the topic is falling
+// behind, so slow subscribers slow down publishers instead of losing messages
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string][]*Subscription
+}
+
+// Bus returns the message bus of the systemd service
+func (s *Systemd) Bus() *Bus {
+	return &s.bus
+}
+
+// Subscription receives the messages published on a topic
+type Subscription struct {
+	bus   *Bus
+	topic string
+	c     chan Message
+
+	// mu is held by publishers sending to c, so c is only closed once none is
+	mu       sync.RWMutex
+	done     chan struct{}
+	closeOne sync.Once
+	closed   bool
+}
+
+// Subscribe subscribes to the topic, buffer is the number of messages the subscriber can fall behind
+// before publishers block, a default buffer is used if zero. Close must be called once done
+func (b *Bus) Subscribe(topic string, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = defaultBusBuffer
+	}
+	sub := &Subscription{bus: b, topic: topic, c: make(chan Message, buffer), done: make(chan struct{})}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs == nil {
+		b.subs = make(map[string][]*Subscription)
+	}
+	b.subs[topic] = append(b.subs[topic], sub)
+	return sub
+}
+
+// Publish sends the payload to all subscribers of the topic, blocking while any of them is
+// falling behind until the context is done
+func (b *Bus) Publish(ctx context.Context, topic string, payload any) error {
+	b.mu.RLock()
+	subs := append([]*Subscription{}, b.subs[topic]...)
+	b.mu.RUnlock()
+
+	msg := Message{Topic: topic, Payload: payload}
+	for _, sub := range subs {
+		if err := sub.send(ctx, msg); err != nil && !errors.Is(err, ErrSubscriptionClosed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// send sends the message unless the subscription is closed or the context is done
+func (sub *Subscription) send(ctx context.Context, msg Message) error {
+	sub.mu.RLock()
+	defer sub.mu.RUnlock()
+
+	if sub.closed {
+		return ErrSubscriptionClosed
+	}
+	select {
+	case sub.c <- msg:
+		return nil
+	case <-sub.done:
+		return ErrSubscriptionClosed
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// C returns the channel the messages are received on, it is closed by Close
+func (sub *Subscription) C() <-chan Message {
+	return sub.c
+}
+
+// Topic returns the topic of the subscription
+func (sub *Subscription) Topic() string {
+	return sub.topic
+}
+
+// Close unsubscribes from the topic, unblocking publishers waiting on the subscriber
+func (sub *Subscription) Close() {
+	sub.closeOne.Do(func() {
+		b := sub.bus
+		b.mu.Lock()
+		subs := b.subs[sub.topic]
+		for i, s := range subs {
+			if s == sub {
+				b.subs[sub.topic] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(b.subs[sub.topic]) == 0 {
+			delete(b.subs, sub.topic)
+		}
+		b.mu.Unlock()
+
+		close(sub.done)
+		sub.mu.Lock()
+		sub.closed = true
+		close(sub.c)
+		sub.mu.Unlock()
+	})
+}
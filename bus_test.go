@@ -0,0 +1,70 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestBusDeliversToSubscribersOfTopic(t *testing.T) {
+	bus := sysd.New().Bus()
+	first := bus.Subscribe("cache.invalidate", 0)
+	defer first.Close()
+	second := bus.Subscribe("cache.invalidate", 0)
+	defer second.Close()
+	other := bus.Subscribe("config", 0)
+	defer other.Close()
+
+	if err := bus.Publish(context.Background(), "cache.invalidate", "user:1"); err != nil {
+		t.Fatalf("Publish returned %v", err)
+	}
+	for _, sub := range []*sysd.Subscription{first, second} {
+		if msg := <-sub.C(); msg.Topic != "cache.invalidate" || msg.Payload != "user:1" {
+			t.Errorf("received %+v, want the published message", msg)
+		}
+	}
+	select {
+	case msg := <-other.C():
+		t.Errorf("subscriber of another topic received %+v", msg)
+	default:
+	}
+}
+
+func TestBusPublishBlocksOnSlowSubscriber(t *testing.T) {
+	bus := sysd.New().Bus()
+	sub := bus.Subscribe("config", 1)
+	if err := bus.Publish(context.Background(), "config", 1); err != nil {
+		t.Fatalf("Publish returned %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.Publish(ctx, "config", 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Publish to a full subscriber returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// closing the subscription releases blocked publishers
+	published := make(chan error, 1)
+	go func() { published <- bus.Publish(context.Background(), "config", 3) }()
+	time.Sleep(10 * time.Millisecond)
+	sub.Close()
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Errorf("Publish to a closed subscription returned %v", err)
+		}
+	case <-time.After(sysdtest.WaitTimeout):
+		t.Fatal("Publish still blocked after the subscription was closed")
+	}
+
+	if msg := <-sub.C(); msg.Payload != 1 {
+		t.Errorf("received %+v, want the buffered message", msg)
+	}
+	if _, ok := <-sub.C(); ok {
+		t.Error("subscription channel not closed by Close")
+	}
+}
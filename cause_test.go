@@ -0,0 +1,73 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestCancellationCause(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel func(h *sysdtest.Harness, app *sysdtest.FakeApp) error
+		want   error
+	}{
+		{
+			name: "stopped",
+			cancel: func(h *sysdtest.Harness, _ *sysdtest.FakeApp) error {
+				return h.Systemd.StopApp(context.Background(), "app")
+			},
+			want: sysd.ErrAppStopped,
+		},
+		{
+			name: "restarted",
+			cancel: func(h *sysdtest.Harness, _ *sysdtest.FakeApp) error {
+				return h.Systemd.RestartApp(context.Background(), "app")
+			},
+			want: sysd.ErrAppRestarted,
+		},
+		{
+			name: "removed",
+			cancel: func(h *sysdtest.Harness, _ *sysdtest.FakeApp) error {
+				return h.Systemd.Remove("app", true)
+			},
+			want: sysd.ErrAppRemoved,
+		},
+		{
+			name: "status check failed",
+			cancel: func(h *sysdtest.Harness, app *sysdtest.FakeApp) error {
+				app.FlapStatus(errors.New("unhealthy"))
+				h.Clock.Advance(sysd.StatusCheckInterval)
+				h.WaitForEvent(sysd.EventAppRestarted, "app")
+				return nil
+			},
+			want: sysd.ErrStatusCheckFailed,
+		},
+		{
+			name: "shutdown",
+			cancel: func(h *sysdtest.Harness, _ *sysdtest.FakeApp) error {
+				return h.Stop()
+			},
+			want: sysd.ErrShutdown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := sysdtest.NewHarness(t)
+			app := h.NewApp("app")
+			h.Start()
+			h.WaitForState("app", sysd.AppRunning)
+
+			if err := tt.cancel(h, app); err != nil {
+				t.Fatal(err)
+			}
+			if cause := app.Cause(); !errors.Is(cause, tt.want) {
+				t.Fatalf("cause is %v, want %v", cause, tt.want)
+			}
+		})
+	}
+}
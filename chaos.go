@@ -0,0 +1,81 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrChaos is the error of status checks failed by ChaosMiddleware
+var ErrChaos = errors.New("chaos: injected failure")
+
+// Chaos configures the faults injected by ChaosMiddleware, rates are probabilities from 0 to 1
+type Chaos struct {
+	// Seed makes the faults reproducible, the same seed injects the same faults for the same
+	// sequence of calls
+	Seed int64
+	// Apps are the apps faults are injected into, all apps if empty
+	Apps []string
+	// StatusFailureRate is the rate of status checks failing with ErrChaos
+	StatusFailureRate float64
+	// StartDelayRate is the rate of starts delayed by up to MaxStartDelay
+	StartDelayRate float64
+	MaxStartDelay  time.Duration
+	// PanicRate is the rate of starts panicking
+	PanicRate float64
+}
+
+// ChaosMiddleware returns a middleware randomly failing status checks, delaying starts and
+// panicking in starts of the selected apps, to verify OnFailure policies and draining work
+// before production does it. it is meant for staging and tests, e.g. s.Use(ChaosMiddleware(c))
+func ChaosMiddleware(c Chaos) AppMiddleware {
+	var mu sync.Mutex
+	rnd := rand.New(rand.NewSource(c.Seed))
+	roll := func(rate float64) (bool, float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		return rnd.Float64() < rate, rnd.Float64()
+	}
+	selected := func(ctx context.Context) bool {
+		return len(c.Apps) == 0 || slices.Contains(c.Apps, AppName(ctx))
+	}
+
+	return AppMiddleware{
+		Start: func(next StartFunc) StartFunc {
+			return func(ctx context.Context) error {
+				if !selected(ctx) {
+					return next(ctx)
+				}
+				if delay, f := roll(c.StartDelayRate); delay && c.MaxStartDelay > 0 {
+					d := time.Duration(f * float64(c.MaxStartDelay))
+					LoggerFrom(ctx).Warn("chaos: delaying start by %s", d)
+					select {
+					case <-ctx.Done():
+						return context.Cause(ctx)
+					case <-clockFrom(ctx).After(d):
+					}
+				}
+				if crash, _ := roll(c.PanicRate); crash {
+					LoggerFrom(ctx).Warn("chaos: panicking in start")
+					panic(ErrChaos)
+				}
+				return next(ctx)
+			}
+		},
+		Status: func(next StatusFunc) StatusFunc {
+			return func(ctx context.Context) error {
+				if !selected(ctx) {
+					return next(ctx)
+				}
+				if fail, _ := roll(c.StatusFailureRate); fail {
+					LoggerFrom(ctx).Warn("chaos: failing status check")
+					return ErrChaos
+				}
+				return next(ctx)
+			}
+		},
+	}
+}
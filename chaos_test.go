@@ -0,0 +1,97 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestChaosFailsStatusOfSelectedApps(t *testing.T) {
+	// checks still running when the clock is advanced do not time out
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second), sysd.WithStatusCheckTimeout(time.Hour))
+	h.Systemd.Use(sysd.ChaosMiddleware(sysd.Chaos{Seed: 1, Apps: []string{"victim"}, StatusFailureRate: 1}))
+	h.NewApp("victim", sysd.WithOnFailure(sysd.OnFailureIgnore))
+	bystander := h.NewApp("bystander")
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("victim", sysd.AppRunning)
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "victim")
+	if !errors.Is(e.Err, sysd.ErrChaos) {
+		t.Errorf("victim failed with %v, want %v", e.Err, sysd.ErrChaos)
+	}
+	// the checks run concurrently, the bystander may not be checked on the tick the victim failed
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); bystander.StatusChecks() == 0 && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if bystander.StatusChecks() == 0 || bystander.Starts() != 1 {
+		t.Errorf("bystander checked %d times and started %d times, want it left alone", bystander.StatusChecks(), bystander.Starts())
+	}
+}
+
+func TestChaosPanicsInStart(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithPanicPolicy(sysd.IgnorePanic))
+	h.Systemd.Use(sysd.ChaosMiddleware(sysd.Chaos{Seed: 1, Apps: []string{"victim"}, PanicRate: 1}))
+	victim := h.NewApp("victim")
+	h.NewApp("bystander")
+	h.Start()
+
+	e := h.WaitForEvent(sysd.EventAppFailed, "victim")
+	var panicErr *sysd.PanicError
+	if !errors.As(e.Err, &panicErr) || panicErr.Value != sysd.ErrChaos {
+		t.Fatalf("victim failed with %v, want a chaos panic", e.Err)
+	}
+	if victim.Starts() != 0 {
+		t.Errorf("victim started %d times, want the panic before its start", victim.Starts())
+	}
+}
+
+func TestChaosIsReproducibleBySeed(t *testing.T) {
+	run := func(seed int64) []bool {
+		status := sysd.ChaosMiddleware(sysd.Chaos{Seed: seed, StatusFailureRate: 0.5}).Status(func(context.Context) error {
+			return nil
+		})
+		failed := make([]bool, 100)
+		for i := range failed {
+			failed[i] = errors.Is(status(context.Background()), sysd.ErrChaos)
+		}
+		return failed
+	}
+
+	first, again, other := run(42), run(42), run(7)
+	same, differs := true, false
+	for i := range first {
+		same = same && first[i] == again[i]
+		differs = differs || first[i] != other[i]
+	}
+	if !same {
+		t.Error("the same seed injected different faults")
+	}
+	if !differs {
+		t.Error("different seeds injected the same faults")
+	}
+}
+
+func TestChaosDelaysStartOnTheClock(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.Systemd.Use(sysd.ChaosMiddleware(sysd.Chaos{Seed: 1, StartDelayRate: 1, MaxStartDelay: time.Hour}))
+	app := h.NewApp("app")
+	h.Start()
+
+	// the delay only passes on the clock of the service
+	time.Sleep(50 * time.Millisecond)
+	if app.Starts() != 0 {
+		t.Fatal("app started before the clock passed the start delay")
+	}
+	h.Clock.Advance(time.Hour)
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); app.Starts() == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("app not started after the clock passed the start delay")
+		}
+	}
+}
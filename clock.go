@@ -0,0 +1,89 @@
+package sysd
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the time source of the systemd service, used by the restart delays, the status
+// check ticker and the shutdown timeouts. tests can replace it, see sysdtest.Clock
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a timer created by a Clock, like time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker is a ticker created by a Clock, like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+// RealClock is the Clock backed by the time package, used by default
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// SetClock replaces the clock of the systemd service, it must be called before Start.
+// the clock of a running systemd service is not replaced, timers already started would never fire
+func (s *Systemd) SetClock(c Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.run != nil {
+		s.logger.Warn("Clock not replaced, the systemd service is running")
+		return
+	}
+	s.clock = c
+}
+
+// withClockTimeout is context.WithTimeout measured by the clock, the context is done with
+// context.DeadlineExceeded as its cause once the clock advanced by d
+func withClockTimeout(parent context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if clock == RealClock {
+		return context.WithTimeout(parent, d)
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	timer := clock.NewTimer(d)
+	go func() {
+		select {
+		case <-timer.C():
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// clockFrom returns the clock of the systemd service of the app owning the context, RealClock
+// outside of apps
+func clockFrom(ctx context.Context) Clock {
+	if hb, ok := ctx.Value(heartbeatKey{}).(heartbeat); ok {
+		return hb.clock
+	}
+	return RealClock
+}
@@ -0,0 +1,153 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// advanceUntilEvent advances the clock by step until an event of the type about the app comes
+func advanceUntilEvent(t *testing.T, h *sysdtest.Harness, events <-chan sysd.Event, step time.Duration, typ sysd.EventType, app string) sysd.Event {
+	t.Helper()
+
+	deadline := time.After(sysdtest.WaitTimeout)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == typ && e.App == app {
+				return e
+			}
+		case <-time.After(5 * time.Millisecond):
+			h.Clock.Advance(step)
+		case <-deadline:
+			t.Fatalf("no %s event of app %q in %s", typ, app, sysdtest.WaitTimeout)
+			return sysd.Event{}
+		}
+	}
+}
+
+func TestEventTimeUsesClock(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.Clock.Advance(24 * time.Hour)
+	h.NewApp("app")
+	h.Start()
+
+	e := h.WaitForEvent(sysd.EventAppStarted, "app")
+	if !e.Time.Equal(h.Clock.Now()) {
+		t.Errorf("event time is %s, want the clock time %s", e.Time, h.Clock.Now())
+	}
+}
+
+func TestMaintenanceUsesClock(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.Systemd.EnterMaintenance(time.Minute)
+	if !h.Systemd.InMaintenance() {
+		t.Fatal("not in maintenance after EnterMaintenance")
+	}
+	h.Clock.Advance(time.Minute)
+	if h.Systemd.InMaintenance() {
+		t.Error("still in maintenance once the clock passed its end")
+	}
+}
+
+func TestHeartbeatUsesClock(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	// the clock is far from the real time, the heartbeats must be measured by it
+	h.Clock.Advance(24 * time.Hour)
+	events := h.Systemd.Subscribe()
+	h.NewApp("app", sysd.WithHeartbeatTimeout(5*time.Second), sysd.WithOnFailure(sysd.OnFailureIgnore))
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "app")
+	if !errors.Is(e.Err, sysd.ErrHeartbeatMissed) {
+		t.Errorf("app failed with %v, want %v", e.Err, sysd.ErrHeartbeatMissed)
+	}
+}
+
+func TestStartTimeoutUsesClock(t *testing.T) {
+	// the failing status check makes the app not ready, the watcher must not act on it first
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Hour))
+	app := h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureIgnore))
+	app.SetStatus(errors.New("not ready"))
+	if err := h.Systemd.SetAppStartTimeout("app", 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app did not start")
+	}
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); app.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !errors.Is(app.Cause(), sysd.ErrStartTimeout) {
+		t.Errorf("app cancelled with %v, want %v", app.Cause(), sysd.ErrStartTimeout)
+	}
+}
+
+func TestFinalizerDeadlineUsesClock(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithGracefulShutdownTimeout(10*time.Second))
+	h.NewApp("app")
+	cause := make(chan error, 1)
+	h.Systemd.AddFinalizer("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		cause <- context.Cause(ctx)
+		return nil
+	})
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	// Stop advances the clock, the finalizer must be cancelled by the clock, not in real time
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	if err := <-cause; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("finalizer cancelled with %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSetClockWhileRunningIsIgnored(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	h.Systemd.SetClock(sysd.RealClock)
+	h.Clock.Advance(time.Hour)
+	if uptime := h.Systemd.Stats()[0].Uptime; uptime != time.Hour {
+		t.Errorf("uptime is %s, want the fake clock to be kept", uptime)
+	}
+}
+
+// hangingApp is a fake app whose status check hangs until its context is done
+type hangingApp struct {
+	*sysdtest.FakeApp
+}
+
+func (a *hangingApp) Status(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestStatusTimeoutUsesClock(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	// the timeout never expires in real time during the test
+	app := &hangingApp{FakeApp: sysdtest.NewFakeApp("app")}
+	if err := h.Systemd.Add(app, sysd.WithStatusTimeout(time.Hour), sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	e := advanceUntilEvent(t, h, events, time.Minute, sysd.EventAppFailed, "app")
+	if !errors.Is(e.Err, sysd.ErrStatusTimeout) {
+		t.Errorf("app failed with %v, want %v", e.Err, sysd.ErrStatusTimeout)
+	}
+}
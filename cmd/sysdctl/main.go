@@ -0,0 +1,172 @@
+// Command sysdctl inspects and controls a running systemd service through its control server,
+// see control.NewServer
+//
+//	sysdctl status
+//	sysdctl restart|stop|pause|resume <app>
+//	sysdctl logs [-f] [app]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mirzakhany/sysd/control"
+)
+
+// followInterval is how often new log entries are fetched with logs -f
+const followInterval = time.Second
+
+func main() {
+	socket := flag.String("socket", envOr("SYSD_CONTROL_SOCKET", control.DefaultSocketPath), "path of the control socket")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	client, err := control.Dial(*socket)
+	if err != nil {
+		fatalf("unable to connect to %s: %v", *socket, err)
+	}
+	defer client.Close()
+
+	args := flag.Args()
+	switch cmd := args[0]; cmd {
+	case "status":
+		err = status(client, os.Stdout)
+	case "restart", "stop", "pause", "resume":
+		if len(args) != 2 {
+			fatalf("usage: sysdctl %s <app>", cmd)
+		}
+		err = command(client, os.Stdout, cmd, args[1])
+	case "logs":
+		err = logs(client, os.Stdout, args[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: sysdctl [-socket path] <command> [args]
+
+commands:
+  status                  show the status of all apps
+  restart <app>           restart the app
+  stop <app>              stop the app
+  pause <app>             pause the app
+  resume <app>            resume the paused app
+  logs [-f] [app]         show the logs and lifecycle events of the app, or of all apps
+
+flags:
+`)
+	flag.PrintDefaults()
+}
+
+func status(client *control.Client, out io.Writer) error {
+	apps, err := client.Status()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APP\tSTATE\tUPTIME\tRESTARTS\tLAST ERROR")
+	for _, app := range apps {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", app.Name, app.State, app.Uptime.Round(time.Second), app.Restarts, app.LastError)
+	}
+	return w.Flush()
+}
+
+func command(client *control.Client, out io.Writer, cmd, app string) error {
+	var err error
+	switch cmd {
+	case "restart":
+		err = client.Restart(app)
+	case "stop":
+		err = client.Stop(app)
+	case "pause":
+		err = client.Pause(app)
+	case "resume":
+		err = client.Resume(app)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s %s\n", app, pastTense[cmd])
+	return nil
+}
+
+var pastTense = map[string]string{
+	"restart": "restarted",
+	"stop":    "stopped",
+	"pause":   "paused",
+	"resume":  "resumed",
+}
+
+func logs(client *control.Client, out io.Writer, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	follow := fs.Bool("f", false, "keep printing new entries")
+	_ = fs.Parse(args)
+
+	var app string
+	if fs.NArg() > 0 {
+		app = fs.Arg(0)
+	}
+
+	// entries are fetched after the last one printed, entries recorded at the same time are not lost
+	var after uint64
+	for {
+		entries, err := client.Logs(app, after)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			fmt.Fprintln(out, formatEntry(e))
+			after = e.Seq
+		}
+
+		if !*follow {
+			return nil
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// formatEntry formats a logged message as "time app LEVEL message", and a lifecycle event as
+// "time app type: error"
+func formatEntry(e control.LogEntry) string {
+	name := e.App
+	if name == "" {
+		name = "sysd"
+	}
+	line := fmt.Sprintf("%s %s ", e.Time.Format(time.RFC3339), name)
+	if e.Type == "" {
+		return line + e.Level + " " + e.Message
+	}
+	line += e.Type
+	if e.Error != "" {
+		line += ": " + e.Error
+	}
+	return line
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "sysdctl: "+format+"\n", args...)
+	os.Exit(1)
+}
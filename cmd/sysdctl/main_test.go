@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/control"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestCommands(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	err := h.Systemd.Add(sysd.AppFunc("cache", func(ctx context.Context) error {
+		sysd.LoggerFrom(ctx).Warn("evicting")
+		<-ctx.Done()
+		return nil
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "control.sock")
+	if err := h.Systemd.Add(control.NewServer(h.Systemd, path)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("cache", sysd.AppRunning)
+
+	var client *control.Client
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); client == nil; time.Sleep(5 * time.Millisecond) {
+		if client, err = control.Dial(path); err != nil && time.Now().After(deadline) {
+			t.Fatalf("control server not listening: %v", err)
+		}
+	}
+	defer client.Close()
+
+	var out bytes.Buffer
+	if err := status(client, &out); err != nil {
+		t.Fatalf("status returned %v", err)
+	}
+	if !strings.Contains(out.String(), "APP") || !strings.Contains(out.String(), "cache    running") {
+		t.Errorf("status printed:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := command(client, &out, "restart", "cache"); err != nil {
+		t.Fatalf("restart returned %v", err)
+	}
+	if out.String() != "cache restarted\n" {
+		t.Errorf("restart printed %q", out.String())
+	}
+	h.WaitForState("cache", sysd.AppRunning)
+	if err := command(client, &out, "pause", "missing"); err == nil {
+		t.Error("pause of a missing app returned no error")
+	}
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); ; time.Sleep(5 * time.Millisecond) {
+		out.Reset()
+		if err := logs(client, &out, []string{"cache"}); err != nil {
+			t.Fatalf("logs returned %v", err)
+		}
+		if strings.Contains(out.String(), "cache WARN evicting") && strings.Contains(out.String(), "cache app_restarted") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("logs printed:\n%s", out.String())
+		}
+	}
+}
+
+func TestFormatEntry(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tt := range []struct {
+		entry control.LogEntry
+		want  string
+	}{
+		{control.LogEntry{Time: at, App: "cache", Level: "INFO", Message: "warming up"}, "2024-01-02T03:04:05Z cache INFO warming up"},
+		{control.LogEntry{Time: at, App: "cache", Type: "app_failed", Error: "boom"}, "2024-01-02T03:04:05Z cache app_failed: boom"},
+		{control.LogEntry{Time: at, Type: "shutdown_begun"}, "2024-01-02T03:04:05Z sysd shutdown_begun"},
+	} {
+		if got := formatEntry(tt.entry); got != tt.want {
+			t.Errorf("formatEntry(%+v) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+}
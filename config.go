@@ -0,0 +1,283 @@
+package sysd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownConfigFormat is returned by FromConfig for files without a registered decoder
+var ErrUnknownConfigFormat = errors.New("unknown config format")
+
+// AppFactory builds an app from its config section, name is the name of the app in the config
+type AppFactory func(name string, config map[string]any) (App, error)
+
+// ConfigDecoder decodes a config file into v, e.g. json.Unmarshal
+type ConfigDecoder func(data []byte, v any) error
+
+var (
+	configDecodersMu sync.RWMutex
+	configDecoders   = map[string]ConfigDecoder{
+		".json": json.Unmarshal,
+	}
+)
+
+// RegisterConfigDecoder registers the decoder for config files with the given extension, e.g. ".yaml".
+// json is supported out of the box, importing github.com/mirzakhany/sysd/config registers yaml and toml
+func RegisterConfigDecoder(ext string, decoder ConfigDecoder) {
+	configDecodersMu.Lock()
+	defer configDecodersMu.Unlock()
+	configDecoders[strings.ToLower(ext)] = decoder
+}
+
+// Duration is a time.Duration read from config files in time.ParseDuration format, e.g. "5s"
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Config is the supervisor configuration read by FromConfig
+type Config struct {
+	GracefulShutdownTimeout Duration         `json:"graceful_shutdown_timeout" yaml:"graceful_shutdown_timeout" toml:"graceful_shutdown_timeout"`
+	StatusCheckInterval     Duration         `json:"status_check_interval" yaml:"status_check_interval" toml:"status_check_interval"`
+	StatusCheckTimeout      Duration         `json:"status_check_timeout" yaml:"status_check_timeout" toml:"status_check_timeout"`
+	StatusCheckConcurrency  int              `json:"status_check_concurrency" yaml:"status_check_concurrency" toml:"status_check_concurrency"`
+	DefaultOnFailure        *OnFailureConfig `json:"default_on_failure" yaml:"default_on_failure" toml:"default_on_failure"`
+	// RestartStrategy is one of one_for_one, one_for_all or rest_for_one
+	RestartStrategy string      `json:"restart_strategy" yaml:"restart_strategy" toml:"restart_strategy"`
+	Apps            []AppConfig `json:"apps" yaml:"apps" toml:"apps"`
+}
+
+// OnFailureConfig is the config of an OnFailure policy
+type OnFailureConfig struct {
+	// Policy is one of restart, ignore or shutdown_all
+	Policy       string         `json:"policy" yaml:"policy" toml:"policy"`
+	Retry        *int           `json:"retry" yaml:"retry" toml:"retry"`
+	RetryTimeout *Duration      `json:"retry_timeout" yaml:"retry_timeout" toml:"retry_timeout"`
+	ResetAfter   *Duration      `json:"reset_after" yaml:"reset_after" toml:"reset_after"`
+	Backoff      *BackoffConfig `json:"backoff" yaml:"backoff" toml:"backoff"`
+}
+
+// BackoffConfig is the config of an OnFailure backoff, see OnFailure.Backoff
+type BackoffConfig struct {
+	Max        Duration `json:"max" yaml:"max" toml:"max"`
+	Multiplier float64  `json:"multiplier" yaml:"multiplier" toml:"multiplier"`
+	Jitter     float64  `json:"jitter" yaml:"jitter" toml:"jitter"`
+}
+
+// AppConfig is the config of a single app
+type AppConfig struct {
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// Type is the registry key of the app factory, defaults to the name
+	Type             string           `json:"type" yaml:"type" toml:"type"`
+	Priority         int              `json:"priority" yaml:"priority" toml:"priority"`
+	OnFailure        *OnFailureConfig `json:"on_failure" yaml:"on_failure" toml:"on_failure"`
+	DependsOn        []string         `json:"depends_on" yaml:"depends_on" toml:"depends_on"`
+	ShutdownTimeout  Duration         `json:"shutdown_timeout" yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	StatusInterval   Duration         `json:"status_interval" yaml:"status_interval" toml:"status_interval"`
+	StatusTimeout    Duration         `json:"status_timeout" yaml:"status_timeout" toml:"status_timeout"`
+	StartTimeout     Duration         `json:"start_timeout" yaml:"start_timeout" toml:"start_timeout"`
+	StartupGrace     Duration         `json:"startup_grace" yaml:"startup_grace" toml:"startup_grace"`
+	HeartbeatTimeout Duration         `json:"heartbeat_timeout" yaml:"heartbeat_timeout" toml:"heartbeat_timeout"`
+	IdleTimeout      Duration         `json:"idle_timeout" yaml:"idle_timeout" toml:"idle_timeout"`
+	// Config is passed to the app factory as is
+	Config map[string]any `json:"config" yaml:"config" toml:"config"`
+}
+
+var onFailurePolicies = map[string]*OnFailure{
+	OnFailureRestart.name:     OnFailureRestart,
+	OnFailureIgnore.name:      OnFailureIgnore,
+	OnFailureShutdownAll.name: OnFailureShutdownAll,
+}
+
+var restartStrategies = map[string]RestartStrategy{
+	"one_for_one":  OneForOne,
+	"one_for_all":  OneForAll,
+	"rest_for_one": RestForOne,
+}
+
+// FromConfig builds a systemd service from the config file at path, the file format is chosen by its
+// extension. apps are built by the factory registered under their type, opts are applied before the config
+func FromConfig(path string, registry map[string]AppFactory, opts ...Option) (*Systemd, error) {
+	configDecodersMu.RLock()
+	decode, ok := configDecoders[strings.ToLower(filepath.Ext(path))]
+	configDecodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownConfigFormat, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := decode(data, &cfg); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	s, err := cfg.Build(registry, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Build builds a systemd service from the config, see FromConfig
+func (c *Config) Build(registry map[string]AppFactory, opts ...Option) (*Systemd, error) {
+	s := New(opts...)
+
+	if c.GracefulShutdownTimeout > 0 {
+		s.SetGraceFulShutdownTimeout(time.Duration(c.GracefulShutdownTimeout))
+	}
+	if c.StatusCheckInterval > 0 {
+		s.SetStatusCheckInterval(time.Duration(c.StatusCheckInterval))
+	}
+	if c.StatusCheckTimeout > 0 {
+		s.SetStatusCheckTimeout(time.Duration(c.StatusCheckTimeout))
+	}
+	if c.StatusCheckConcurrency > 0 {
+		s.SetStatusCheckConcurrency(c.StatusCheckConcurrency)
+	}
+	if c.DefaultOnFailure != nil {
+		onFailure, err := c.DefaultOnFailure.onFailure()
+		if err != nil {
+			return nil, fmt.Errorf("default_on_failure: %w", err)
+		}
+		s.SetDefaultOnFailure(onFailure)
+	}
+	if c.RestartStrategy != "" {
+		strategy, ok := restartStrategies[c.RestartStrategy]
+		if !ok {
+			return nil, fmt.Errorf("unknown restart strategy %q", c.RestartStrategy)
+		}
+		s.SetRestartStrategy(strategy)
+	}
+
+	for _, appCfg := range dependencyOrder(c.Apps) {
+		app, opts, err := appCfg.build(registry)
+		if err != nil {
+			return nil, fmt.Errorf("app %q: %w", appCfg.Name, err)
+		}
+		if err := s.Add(app, opts...); err != nil {
+			return nil, fmt.Errorf("app %q: %w", appCfg.Name, err)
+		}
+	}
+
+	if err := s.checkDependencies(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// dependencyOrder returns the app configs with dependencies before the apps depending on them,
+// otherwise in file order. unknown dependencies and cycles are left to Add to report
+func dependencyOrder(apps []AppConfig) []*AppConfig {
+	added := make(map[string]bool, len(apps))
+	done := make([]bool, len(apps))
+	ordered := make([]*AppConfig, 0, len(apps))
+
+	ready := func(c *AppConfig) bool {
+		for _, dep := range c.DependsOn {
+			if !added[dep] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for len(ordered) < len(apps) {
+		progress := false
+		for i := range apps {
+			if !done[i] && ready(&apps[i]) {
+				done[i], added[apps[i].Name], progress = true, true, true
+				ordered = append(ordered, &apps[i])
+			}
+		}
+		if progress {
+			continue
+		}
+		// the rest can not be ordered, keep them in file order
+		for i := range apps {
+			if !done[i] {
+				done[i] = true
+				ordered = append(ordered, &apps[i])
+			}
+		}
+	}
+	return ordered
+}
+
+// build builds the app with its factory and returns it with its add options
+func (c *AppConfig) build(registry map[string]AppFactory) (App, []AddOption, error) {
+	typ := c.Type
+	if typ == "" {
+		typ = c.Name
+	}
+	factory, ok := registry[typ]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown app type %q", typ)
+	}
+
+	app, err := factory(c.Name, c.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if app.Name() != c.Name {
+		return nil, nil, fmt.Errorf("factory %q built app named %q", typ, app.Name())
+	}
+
+	opts := []AddOption{
+		WithPriority(c.Priority),
+		WithDependsOn(c.DependsOn...),
+		WithShutdownTimeout(time.Duration(c.ShutdownTimeout)),
+		WithStatusInterval(time.Duration(c.StatusInterval)),
+		WithStatusTimeout(time.Duration(c.StatusTimeout)),
+		WithStartTimeout(time.Duration(c.StartTimeout)),
+		WithStartupGrace(time.Duration(c.StartupGrace)),
+		WithHeartbeatTimeout(time.Duration(c.HeartbeatTimeout)),
+		WithIdleTimeout(time.Duration(c.IdleTimeout)),
+	}
+	if c.OnFailure != nil {
+		onFailure, err := c.OnFailure.onFailure()
+		if err != nil {
+			return nil, nil, fmt.Errorf("on_failure: %w", err)
+		}
+		opts = append(opts, WithOnFailure(onFailure))
+	}
+	return app, opts, nil
+}
+
+// onFailure returns the OnFailure policy described by the config
+func (c *OnFailureConfig) onFailure() (*OnFailure, error) {
+	onFailure, ok := onFailurePolicies[c.Policy]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy %q", c.Policy)
+	}
+
+	if c.Retry != nil {
+		onFailure = onFailure.Retry(*c.Retry)
+	}
+	if c.RetryTimeout != nil {
+		onFailure = onFailure.RetryTimeout(time.Duration(*c.RetryTimeout))
+	}
+	if c.ResetAfter != nil {
+		onFailure = onFailure.ResetAfter(time.Duration(*c.ResetAfter))
+	}
+	if c.Backoff != nil {
+		onFailure = onFailure.Backoff(time.Duration(c.Backoff.Max), c.Backoff.Multiplier, c.Backoff.Jitter)
+	}
+	return onFailure, nil
+}
@@ -0,0 +1,16 @@
+// Package config registers the yaml and toml decoders of sysd.FromConfig, import it for its side effects:
+//
+//	import _ "github.com/mirzakhany/sysd/config"
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/mirzakhany/sysd"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	sysd.RegisterConfigDecoder(".yaml", yaml.Unmarshal)
+	sysd.RegisterConfigDecoder(".yml", yaml.Unmarshal)
+	sysd.RegisterConfigDecoder(".toml", toml.Unmarshal)
+}
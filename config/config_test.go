@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+const validYAML = `
+status_check_interval: 5s
+restart_strategy: one_for_all
+default_on_failure:
+  policy: restart
+  retry: 3
+apps:
+  - name: api
+    type: http
+    priority: 1
+    depends_on: [db]
+    shutdown_timeout: 30s
+    config:
+      addr: ":8080"
+  - name: db
+    on_failure:
+      policy: shutdown_all
+`
+
+const validTOML = `
+status_check_interval = "5s"
+restart_strategy = "one_for_all"
+
+[default_on_failure]
+policy = "restart"
+retry = 3
+
+[[apps]]
+name = "api"
+type = "http"
+priority = 1
+depends_on = ["db"]
+shutdown_timeout = "30s"
+
+[apps.config]
+addr = ":8080"
+
+[[apps]]
+name = "db"
+
+[apps.on_failure]
+policy = "shutdown_all"
+`
+
+func TestFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{name: "yaml", file: "sysd.yaml", content: validYAML},
+		{name: "yml", file: "sysd.yml", content: validYAML},
+		{name: "upper case extension", file: "sysd.YAML", content: validYAML},
+		{name: "toml", file: "sysd.toml", content: validTOML},
+		{name: "unknown extension", file: "sysd.ini", content: validYAML, wantErr: "unknown config format"},
+		{name: "malformed yaml", file: "sysd.yaml", content: "apps: [", wantErr: "yaml"},
+		{name: "malformed toml", file: "sysd.toml", content: "apps = [", wantErr: "toml"},
+		{name: "invalid duration", file: "sysd.yaml", content: "status_check_interval: soon", wantErr: "invalid duration"},
+		{name: "invalid toml duration", file: "sysd.toml", content: `status_check_interval = "soon"`, wantErr: "invalid duration"},
+		{
+			name:    "unknown policy",
+			file:    "sysd.yaml",
+			content: "default_on_failure:\n  policy: retry_forever",
+			wantErr: `unknown policy "retry_forever"`,
+		},
+		{
+			name:    "unknown restart strategy",
+			file:    "sysd.toml",
+			content: `restart_strategy = "all_for_one"`,
+			wantErr: `unknown restart strategy "all_for_one"`,
+		},
+		{
+			name:    "unknown app type",
+			file:    "sysd.yaml",
+			content: "apps:\n  - name: cache",
+			wantErr: `unknown app type "cache"`,
+		},
+		{
+			name:    "unknown dependency",
+			file:    "sysd.yaml",
+			content: "apps:\n  - name: db\n    depends_on: [disk]",
+			wantErr: "disk",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var gotConfig map[string]any
+			registry := map[string]sysd.AppFactory{
+				"http": func(name string, config map[string]any) (sysd.App, error) {
+					gotConfig = config
+					return sysdtest.NewFakeApp(name), nil
+				},
+				"db": func(name string, _ map[string]any) (sysd.App, error) {
+					return sysdtest.NewFakeApp(name), nil
+				},
+			}
+			s, err := sysd.FromConfig(path, registry)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FromConfig returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := map[string]any{"addr": ":8080"}; !reflect.DeepEqual(gotConfig, want) {
+				t.Errorf("factory got config %v, want %v", gotConfig, want)
+			}
+			want := []sysd.GraphNode{{Name: "db"}, {Name: "api", Priority: 1, DependsOn: []string{"db"}}}
+			if got := s.Graph().Nodes; !reflect.DeepEqual(got, want) {
+				t.Errorf("graph is %+v, want %+v", got, want)
+			}
+			for app, want := range map[string]*sysd.OnFailure{"api": sysd.OnFailureRestart, "db": sysd.OnFailureShutdownAll} {
+				got, err := s.AppOnFailure(app)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.String() != want.String() {
+					t.Errorf("app %s fails with %s, want %s", app, got, want)
+				}
+			}
+		})
+	}
+}
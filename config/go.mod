@@ -0,0 +1,11 @@
+module github.com/mirzakhany/sysd/config
+
+go 1.21.3
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/mirzakhany/sysd v0.1.2
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/mirzakhany/sysd => ../
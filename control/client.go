@@ -0,0 +1,63 @@
+package control
+
+import (
+	"net/rpc"
+	"net/rpc/jsonrpc"
+)
+
+// Client calls the control server of a running systemd service
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to the control server listening on the socket path, DefaultSocketPath if empty
+func Dial(path string) (*Client, error) {
+	if path == "" {
+		path = DefaultSocketPath
+	}
+	c, err := jsonrpc.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{rpc: c}, nil
+}
+
+// Close closes the connection to the control server
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Status returns the status of all apps
+func (c *Client) Status() ([]AppStatus, error) {
+	var reply []AppStatus
+	err := c.rpc.Call(serviceName+".Status", Empty{}, &reply)
+	return reply, err
+}
+
+// Restart restarts the app
+func (c *Client) Restart(name string) error {
+	return c.rpc.Call(serviceName+".Restart", AppArgs{Name: name}, &Empty{})
+}
+
+// Stop stops the app, it can be started again with Restart
+func (c *Client) Stop(name string) error {
+	return c.rpc.Call(serviceName+".Stop", AppArgs{Name: name}, &Empty{})
+}
+
+// Pause pauses the app until Resume
+func (c *Client) Pause(name string) error {
+	return c.rpc.Call(serviceName+".Pause", AppArgs{Name: name}, &Empty{})
+}
+
+// Resume resumes the paused app
+func (c *Client) Resume(name string) error {
+	return c.rpc.Call(serviceName+".Resume", AppArgs{Name: name}, &Empty{})
+}
+
+// Logs returns the logged messages and lifecycle events of the app, of all apps if name is empty,
+// recorded after the entry with the sequence number after, all entries kept if zero
+func (c *Client) Logs(name string, after uint64) ([]LogEntry, error) {
+	var reply []LogEntry
+	err := c.rpc.Call(serviceName+".Logs", LogsArgs{Name: name, After: after}, &reply)
+	return reply, err
+}
@@ -0,0 +1,74 @@
+package control
+
+import (
+	"sync"
+
+	"github.com/mirzakhany/sysd"
+)
+
+// eventLogSize is the number of log entries kept for sysdctl logs
+const eventLogSize = 1000
+
+// eventLog keeps the latest logged messages and lifecycle events in a ring buffer
+type eventLog struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	next    int
+	full    bool
+	seq     uint64
+}
+
+func newEventLog(size int) *eventLog {
+	return &eventLog{entries: make([]LogEntry, size)}
+}
+
+// record adds the events to the log until the channel is closed
+func (l *eventLog) record(events <-chan sysd.Event) {
+	for e := range events {
+		entry := LogEntry{Time: e.Time, App: e.App, Type: e.Type.String()}
+		if e.Err != nil {
+			entry.Error = e.Err.Error()
+		}
+		l.add(entry)
+	}
+}
+
+// recordLogs adds the logged messages to the log until the channel is closed
+func (l *eventLog) recordLogs(logs <-chan sysd.LogRecord) {
+	for r := range logs {
+		l.add(LogEntry{Time: r.Time, App: r.App, Level: r.Level.String(), Message: r.Message})
+	}
+}
+
+func (l *eventLog) add(entry LogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	entry.Seq = l.seq
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// after returns the entries of the app, or of all apps if empty, recorded after the entry with
+// the sequence number seq, oldest first
+func (l *eventLog) after(app string, seq uint64) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ordered := l.entries[:l.next]
+	if l.full {
+		ordered = append(append([]LogEntry{}, l.entries[l.next:]...), l.entries[:l.next]...)
+	}
+
+	var entries []LogEntry
+	for _, e := range ordered {
+		if (app == "" || e.App == app) && e.Seq > seq {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,259 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &Server{}
+
+// serviceName is the name the control methods are registered under
+const serviceName = "Control"
+
+// DefaultSocketPath is where the control server listens and sysdctl connects by default, in
+// XDG_RUNTIME_DIR if set, or in the temp directory named after the user. several services run
+// by the same user need their own paths
+var DefaultSocketPath = defaultSocketPath()
+
+// ErrSocketInUse is returned by Start if another control server answers on the socket path
+var ErrSocketInUse = errors.New("control socket is in use")
+
+// staleTimeout bounds checking whether another control server answers on the socket path
+const staleTimeout = time.Second
+
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sysd.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("sysd-%d.sock", os.Getuid()))
+}
+
+// Server serves the control API of a systemd service on a unix socket, it is added
+// to the systemd service like any other app
+type Server struct {
+	path string
+	sysd *sysd.Systemd
+	logs *eventLog
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+}
+
+// NewServer returns a control server for the systemd service listening on the socket path,
+// DefaultSocketPath if empty. the socket is only accessible by the owner of the process
+func NewServer(s *sysd.Systemd, path string) *Server {
+	if path == "" {
+		path = DefaultSocketPath
+	}
+	return &Server{path: path, sysd: s, logs: newEventLog(eventLogSize)}
+}
+
+func (srv *Server) Start(ctx context.Context) error {
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName(serviceName, &service{sysd: srv.sysd, logs: srv.logs}); err != nil {
+		return err
+	}
+
+	ln, err := listen(srv.path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(srv.path)
+
+	events := srv.sysd.Subscribe()
+	defer srv.sysd.Unsubscribe(events)
+	go srv.logs.record(events)
+	logs := srv.sysd.SubscribeLogs()
+	defer srv.sysd.UnsubscribeLogs(logs)
+	go srv.logs.recordLogs(logs)
+
+	srv.mu.Lock()
+	srv.listener = ln
+	srv.mu.Unlock()
+
+	var served sync.WaitGroup
+	// connections still open on shutdown, like sysdctl logs -f, are closed with the listener
+	defer served.Wait()
+	defer srv.closeConns()
+
+	accepted := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				accepted <- err
+				return
+			}
+			if !srv.track(conn) {
+				conn.Close()
+				continue
+			}
+			served.Add(1)
+			go func() {
+				defer served.Done()
+				defer srv.untrack(conn)
+				rpcServer.ServeCodec(jsonrpc.NewServerCodec(conn))
+			}()
+		}
+	}()
+
+	select {
+	case err := <-accepted:
+		return err
+	case <-ctx.Done():
+	}
+
+	srv.mu.Lock()
+	srv.listener = nil
+	srv.mu.Unlock()
+	return ln.Close()
+}
+
+// listen listens on the socket path, which is only accessible by the owner of the process
+func listen(path string) (net.Listener, error) {
+	// a socket left over by a process which did not shut down cleanly blocks listening,
+	// a socket still answering belongs to a running service and is not taken over
+	if conn, err := net.DialTimeout("unix", path, staleTimeout); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %s", ErrSocketInUse, path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// the socket is created in a directory only the owner can enter and restricted before it is
+	// moved in place, other users can not connect in between
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".sysd-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "sock")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// the socket is removed from its final path on shutdown
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// track adds the connection to the open ones, it returns false if the server is shutting down
+func (srv *Server) track(conn net.Conn) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.listener == nil {
+		return false
+	}
+	if srv.conns == nil {
+		srv.conns = make(map[net.Conn]struct{})
+	}
+	srv.conns[conn] = struct{}{}
+	return true
+}
+
+func (srv *Server) untrack(conn net.Conn) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	delete(srv.conns, conn)
+}
+
+// closeConns closes the open connections, ending the calls in flight
+func (srv *Server) closeConns() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	for conn := range srv.conns {
+		conn.Close()
+	}
+}
+
+func (srv *Server) Status(ctx context.Context) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.listener == nil {
+		return errors.New("control server is not running")
+	}
+	return nil
+}
+
+func (srv *Server) Name() string {
+	return "control"
+}
+
+// service implements the control methods, see Client for their meaning
+type service struct {
+	sysd *sysd.Systemd
+	logs *eventLog
+}
+
+// callTimeout bounds the commands waiting for an app to stop
+const callTimeout = time.Minute
+
+func (s *service) Status(_ Empty, reply *[]AppStatus) error {
+	// the JSON-RPC client rejects a null result, no apps is an empty list
+	*reply = []AppStatus{}
+	for _, app := range s.sysd.Snapshot() {
+		status := AppStatus{
+			Name:     app.Name,
+			State:    app.State.String(),
+			Uptime:   app.Uptime,
+			Restarts: app.Restarts,
+		}
+		if app.LastError != nil {
+			status.LastError = app.LastError.Error()
+		}
+		*reply = append(*reply, status)
+	}
+	return nil
+}
+
+func (s *service) Restart(args AppArgs, _ *Empty) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	return s.sysd.RestartApp(ctx, args.Name)
+}
+
+func (s *service) Stop(args AppArgs, _ *Empty) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	return s.sysd.StopApp(ctx, args.Name)
+}
+
+func (s *service) Pause(args AppArgs, _ *Empty) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	return s.sysd.Pause(ctx, args.Name)
+}
+
+func (s *service) Resume(args AppArgs, _ *Empty) error {
+	return s.sysd.Resume(args.Name)
+}
+
+func (s *service) Logs(args LogsArgs, reply *[]LogEntry) error {
+	// no new entries is an empty list, not a null result
+	*reply = append([]LogEntry{}, s.logs.after(args.Name, args.After)...)
+	return nil
+}
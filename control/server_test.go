@@ -0,0 +1,251 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// newServer returns a harness running the control server on a socket in a temp directory,
+// and a client connected to it
+func newServer(t *testing.T) (*sysdtest.Harness, string, *Client) {
+	t.Helper()
+
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app")
+	path := filepath.Join(t.TempDir(), "control.sock")
+	if err := h.Systemd.Add(NewServer(h.Systemd, path)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("control", sysd.AppRunning)
+	return h, path, dial(t, path)
+}
+
+// dial connects to the control server once it listens
+func dial(t *testing.T, path string) *Client {
+	t.Helper()
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); ; time.Sleep(5 * time.Millisecond) {
+		c, err := Dial(path)
+		if err == nil {
+			t.Cleanup(func() { c.Close() })
+			return c
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("control server not listening: %v", err)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	h, _, c := newServer(t)
+	h.WaitForState("app", sysd.AppRunning)
+
+	apps, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status returned %v", err)
+	}
+	states := map[string]string{}
+	for _, app := range apps {
+		states[app.Name] = app.State
+	}
+	if states["app"] != sysd.AppRunning.String() || states["control"] != sysd.AppRunning.String() {
+		t.Errorf("Status returned %+v", apps)
+	}
+
+	for _, step := range []struct {
+		call  func(string) error
+		state sysd.AppState
+	}{
+		{c.Stop, sysd.AppStopped},
+		{c.Restart, sysd.AppRunning},
+		{c.Pause, sysd.AppPaused},
+		{c.Resume, sysd.AppRunning},
+	} {
+		if err := step.call("app"); err != nil {
+			t.Fatalf("command returned %v", err)
+		}
+		h.WaitForState("app", step.state)
+	}
+
+	if err := c.Restart("missing"); err == nil || err.Error() != sysd.ErrAppNotExists.Error() {
+		t.Errorf("Restart of a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+}
+
+func TestSocketOnlyForOwner(t *testing.T) {
+	_, path, _ := newServer(t)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("socket mode is %o, want 600", mode)
+	}
+	// the directory the socket was created in is gone
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("socket directory holds %d entries, want only the socket", len(entries))
+	}
+}
+
+func TestSocketInUse(t *testing.T) {
+	h, path, c := newServer(t)
+
+	if err := NewServer(sysd.New(), path).Start(context.Background()); !errors.Is(err, ErrSocketInUse) {
+		t.Errorf("second server on the socket returned %v, want %v", err, ErrSocketInUse)
+	}
+	// the first server still answers
+	if _, err := c.Status(); err != nil {
+		t.Errorf("Status returned %v after another server tried the socket", err)
+	}
+	if err := h.Stop(); err != nil {
+		t.Errorf("Stop returned %v", err)
+	}
+}
+
+func TestStaleSocketReplaced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a process which did not shut down cleanly leaves its socket behind
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	h := sysdtest.NewHarness(t)
+	if err := h.Systemd.Add(NewServer(h.Systemd, path)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	if _, err := dial(t, path).Status(); err != nil {
+		t.Errorf("Status returned %v", err)
+	}
+}
+
+func TestShutdownClosesConnections(t *testing.T) {
+	h, path, c := newServer(t)
+	// the connection is served before the shutdown, not left in the listen backlog
+	if _, err := c.Status(); err != nil {
+		t.Fatalf("Status returned %v", err)
+	}
+
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	if _, err := c.Status(); err == nil {
+		t.Error("connection still served after shutdown")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket left behind after shutdown: %v", err)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	err := h.Systemd.Add(sysd.AppFunc("cache", func(ctx context.Context) error {
+		sysd.LoggerFrom(ctx).Warn("evicting")
+		<-ctx.Done()
+		return nil
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.NewApp("app")
+	path := filepath.Join(t.TempDir(), "control.sock")
+	if err := h.Systemd.Add(NewServer(h.Systemd, path)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	c := dial(t, path)
+	h.WaitForState("cache", sysd.AppRunning)
+	if err := c.Restart("cache"); err != nil {
+		t.Fatalf("Restart returned %v", err)
+	}
+
+	// the server may subscribe after the first start, the restart is logged again
+	var entries []LogEntry
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); ; time.Sleep(5 * time.Millisecond) {
+		entries, err = c.Logs("cache", 0)
+		if err != nil {
+			t.Fatalf("Logs returned %v", err)
+		}
+		if hasMessage(entries, "evicting") && hasType(entries, sysd.EventAppRestarted.String()) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Logs returned %+v, want the message and the restart of the app", entries)
+		}
+	}
+	for i, e := range entries {
+		if e.App != "cache" {
+			t.Errorf("Logs of cache returned an entry of %q", e.App)
+		}
+		if i > 0 && e.Seq <= entries[i-1].Seq {
+			t.Errorf("entries out of order: %d after %d", e.Seq, entries[i-1].Seq)
+		}
+	}
+
+	last := entries[len(entries)-1].Seq
+	rest, err := c.Logs("cache", last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range rest {
+		if e.Seq <= last {
+			t.Errorf("Logs after %d returned entry %d", last, e.Seq)
+		}
+	}
+}
+
+func hasMessage(entries []LogEntry, msg string) bool {
+	for _, e := range entries {
+		if e.Message == msg && e.Level == "WARN" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasType(entries []LogEntry, typ string) bool {
+	for _, e := range entries {
+		if e.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEventLogKeepsEntriesAtTheSameTime(t *testing.T) {
+	l := newEventLog(3)
+	now := time.Now()
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		l.add(LogEntry{Time: now, App: "app", Message: msg})
+	}
+
+	var got []string
+	for _, e := range l.after("app", 2) {
+		got = append(got, e.Message)
+	}
+	// the oldest entry fell out of the ring, the ones at the same time after the cursor are kept
+	if strings.Join(got, ",") != "c,d" {
+		t.Errorf("entries after 2 are %v, want [c d]", got)
+	}
+	if n := len(l.after("", 0)); n != 3 {
+		t.Errorf("log keeps %d entries, want 3", n)
+	}
+}
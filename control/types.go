@@ -0,0 +1,41 @@
+package control
+
+import "time"
+
+// Empty is the argument or reply of methods which have none
+type Empty struct{}
+
+// AppArgs names the app a command applies to
+type AppArgs struct {
+	Name string
+}
+
+// LogsArgs selects the log entries of an app, of all apps if Name is empty, recorded after the
+// entry with the sequence number After, all entries kept if zero
+type LogsArgs struct {
+	Name  string
+	After uint64
+}
+
+// AppStatus is the status of an app as reported by the control server
+type AppStatus struct {
+	Name      string
+	State     string
+	Uptime    time.Duration
+	Restarts  int
+	LastError string
+}
+
+// LogEntry is a message logged by an app, or a lifecycle event of an app, recorded by the control server
+type LogEntry struct {
+	// Seq orders the entries, it is passed as LogsArgs.After to get the entries recorded since
+	Seq  uint64
+	Time time.Time
+	App  string
+	// Level is the level of a logged message, empty for a lifecycle event
+	Level   string
+	Message string
+	// Type is the type of a lifecycle event, empty for a logged message
+	Type  string
+	Error string
+}
@@ -0,0 +1,211 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrDependencyCycle is returned by Start, Add and SetAppDependencies when apps depend
+// on each other in a cycle
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// dependencyCheckInterval is how often the health of dependencies is checked
+// while an app waits for them
+const dependencyCheckInterval = 100 * time.Millisecond
+
+// SetAppDependencies sets the apps a specific app depends on, an app is started once its
+// dependencies are ready and is stopped before them, regardless of priorities
+func (s *Systemd) SetAppDependencies(appName string, deps ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.apps[appName]
+	if !ok {
+		return ErrAppNotExists
+	}
+	if err := s.checkNewDependencies(appName, deps); err != nil {
+		return err
+	}
+
+	app.dependsOn = append([]string(nil), deps...)
+	return nil
+}
+
+// checkDependencies returns an error if apps depend on unknown apps or on each other in a cycle
+func (s *Systemd) checkDependencies() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	visited := make(map[string]bool, len(s.apps))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		for i, n := range path {
+			if n == name {
+				return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path[i:], name), " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+
+		app, ok := s.apps[name]
+		if !ok {
+			return fmt.Errorf("dependency %q of app %q: %w", name, path[len(path)-1], ErrAppNotExists)
+		}
+
+		path = append(path, name)
+		for _, dep := range app.dependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		visited[name] = true
+		return nil
+	}
+
+	names := make([]string, 0, len(s.apps))
+	for name := range s.apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForDependencies blocks until all apps the app depends on are ready, or the context is done
+func (s *Systemd) waitForDependencies(ctx context.Context, app *appItem) error {
+	s.mu.RLock()
+	deps := make([]*appItem, 0, len(app.dependsOn))
+	for _, name := range app.dependsOn {
+		if dep, ok := s.apps[name]; ok {
+			deps = append(deps, dep)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(deps) == 0 {
+		return nil
+	}
+
+	s.setState(app, AppPending, nil)
+
+	ticker := s.clock.NewTicker(dependencyCheckInterval)
+	defer ticker.Stop()
+
+	for _, dep := range deps {
+		s.logger.Info("app %q is waiting for %q", app.Name(), dep.Name())
+		for !s.appReady(ctx, dep) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C():
+			}
+		}
+	}
+	return nil
+}
+
+// checkNewDependencies returns an error if the app would depend on unknown apps, or on itself
+// through the apps it depends on. s.mu must be held
+func (s *Systemd) checkNewDependencies(name string, deps []string) error {
+	for _, dep := range deps {
+		if _, ok := s.apps[dep]; !ok && dep != name {
+			return fmt.Errorf("dependency %q of app %q: %w", dep, name, ErrAppNotExists)
+		}
+	}
+
+	visited := make(map[string]bool, len(s.apps))
+	path := []string{name}
+
+	var reaches func(dep string) bool
+	reaches = func(dep string) bool {
+		path = append(path, dep)
+		if dep == name {
+			return true
+		}
+		if !visited[dep] {
+			visited[dep] = true
+			if app, ok := s.apps[dep]; ok {
+				for _, next := range app.dependsOn {
+					if reaches(next) {
+						return true
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	for _, dep := range deps {
+		if reaches(dep) {
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(path, " -> "))
+		}
+	}
+	return nil
+}
+
+// startOrder returns the apps in the order they should start, dependencies first and
+// otherwise by priority then name. apps are expected to be sorted by priority
+func (s *Systemd) startOrder(apps []*appItem) []*appItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := make(map[string]*appItem, len(apps))
+	for _, app := range apps {
+		index[app.name] = app
+	}
+
+	ordered := make([]*appItem, 0, len(apps))
+	visited := make(map[string]bool, len(apps))
+	visiting := make(map[string]bool)
+
+	var visit func(app *appItem)
+	visit = func(app *appItem) {
+		if visited[app.name] || visiting[app.name] {
+			// visiting means a cycle, keep the priority order for the rest of it
+			return
+		}
+		visiting[app.name] = true
+		for _, name := range app.dependsOn {
+			dep, ok := index[name]
+			if !ok {
+				continue
+			}
+			if dep.priority > app.priority {
+				s.logger.Warn("app %q depends on %q which has a lower priority, starting %q first",
+					app.name, dep.name, dep.name)
+			}
+			visit(dep)
+		}
+		delete(visiting, app.name)
+		visited[app.name] = true
+		ordered = append(ordered, app)
+	}
+
+	for _, app := range apps {
+		visit(app)
+	}
+	return ordered
+}
+
+// shutdownOrder returns the apps in the order they should stop, the reverse of the start order
+func (s *Systemd) shutdownOrder(apps []*appItem) []*appItem {
+	ordered := s.startOrder(apps)
+	for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	}
+	return ordered
+}
@@ -0,0 +1,105 @@
+package sysd
+
+import (
+	"errors"
+	"time"
+)
+
+// eventBufferSize is the number of events a subscriber can fall behind before events are dropped
+const eventBufferSize = 64
+
+// EventType is the type of a lifecycle event
+type EventType int
+
+const (
+	// EventAppStarted is emitted every time an app is started
+	EventAppStarted EventType = iota
+	// EventAppFailed is emitted when an app returns an error, panics or fails its status check
+	EventAppFailed
+	// EventAppRestarted is emitted when a failed app is started again
+	EventAppRestarted
+	// EventAppStopped is emitted when an app returns from Start
+	EventAppStopped
+	// EventShutdownBegun is emitted when the systemd service starts stopping all apps
+	EventShutdownBegun
+	// EventAppQuarantined is emitted when an app restarted too often and is not restarted anymore
+	EventAppQuarantined
+	// EventShutdownTimeout is emitted when apps did not stop within the graceful shutdown timeout
+	EventShutdownTimeout
+)
+
+var eventTypeNames = map[EventType]string{
+	EventAppStarted:      "app_started",
+	EventAppFailed:       "app_failed",
+	EventAppRestarted:    "app_restarted",
+	EventAppStopped:      "app_stopped",
+	EventShutdownBegun:   "shutdown_begun",
+	EventAppQuarantined:  "app_quarantined",
+	EventShutdownTimeout: "shutdown_timeout",
+}
+
+// String returns the string representation of the EventType
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Event is a lifecycle event of the systemd service or one of its apps
+type Event struct {
+	Type EventType
+	// App is the name of the app the event is about, empty for events about the systemd service
+	App  string
+	Time time.Time
+	Err  error
+	// Stack is where the app panicked, for failures caused by a panic
+	Stack []byte
+}
+
+// Subscribe returns a channel receiving lifecycle events. events are dropped for subscribers
+// which fall behind, the channel is closed by Unsubscribe
+func (s *Systemd) Subscribe() <-chan Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := make(chan Event, eventBufferSize)
+	s.subscribers = append(s.subscribers, c)
+	return c
+}
+
+// Unsubscribe stops sending events to the channel returned by Subscribe and closes it
+func (s *Systemd) Unsubscribe(c <-chan Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, sub := range s.subscribers {
+		if sub == c {
+			s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+			close(sub)
+			return
+		}
+	}
+}
+
+// emit sends the event to all subscribers without blocking
+func (s *Systemd) emit(typ EventType, app string, err error) {
+	e := Event{Type: typ, App: app, Time: s.clock.Now(), Err: err}
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		e.Stack = panicErr.Stack
+	}
+	s.audit.record(e)
+	s.alerts.record(s, e)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, sub := range s.subscribers {
+		select {
+		case sub <- e:
+		default:
+			s.logger.Warn("Dropping %s event of app %q, subscriber is falling behind", typ, app)
+		}
+	}
+}
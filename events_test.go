@@ -0,0 +1,61 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestSubscribeReceivesLifecycleEvents(t *testing.T) {
+	boom := errors.New("boom")
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureRestart.RetryTimeout(time.Second))).FailStarts(1, boom)
+	events := h.Systemd.Subscribe()
+	h.Start()
+	advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "app")
+	h.WaitForState("app", sysd.AppRunning)
+	if err := h.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	h.Systemd.Unsubscribe(events)
+
+	seen := make(map[sysd.EventType]sysd.Event)
+	for e := range events {
+		if e.Time.IsZero() {
+			t.Errorf("%s event has no time", e.Type)
+		}
+		seen[e.Type] = e
+	}
+	for _, typ := range []sysd.EventType{sysd.EventAppStarted, sysd.EventAppStopped, sysd.EventShutdownBegun} {
+		if _, ok := seen[typ]; !ok {
+			t.Errorf("no %s event", typ)
+		}
+	}
+	if e := seen[sysd.EventAppStarted]; e.App != "app" {
+		t.Errorf("started event is about %q, want app", e.App)
+	}
+	if e := seen[sysd.EventShutdownBegun]; e.App != "" {
+		t.Errorf("shutdown begun event is about app %q, want none", e.App)
+	}
+}
+
+func TestSubscribeFailedEventCarriesError(t *testing.T) {
+	boom := errors.New("boom")
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureIgnore)).FailStarts(1, boom)
+	events := h.Systemd.Subscribe()
+	h.Start()
+
+	e := h.WaitForEvent(sysd.EventAppFailed, "app")
+	if !errors.Is(e.Err, boom) {
+		t.Errorf("failed event carries %v, want %v", e.Err, boom)
+	}
+	select {
+	case <-events:
+	default:
+		t.Error("subscriber received no event")
+	}
+}
@@ -0,0 +1,47 @@
+package sysd
+
+import (
+	"context"
+	"time"
+)
+
+// Finalizer cleans up after all apps have stopped, e.g. flushing telemetry, removing a PID file
+// or deregistering from service discovery
+type Finalizer func(ctx context.Context) error
+
+type finalizer struct {
+	name string
+	fn   Finalizer
+}
+
+// AddFinalizer registers a finalizer which runs on every shutdown once all apps have stopped.
+// finalizers run one at a time in reverse registration order, like deferred calls, with a context
+// done when the graceful shutdown timeout expires. their errors are logged
+func (s *Systemd) AddFinalizer(name string, fn Finalizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.finalizers = append(s.finalizers, finalizer{name: name, fn: fn})
+}
+
+// runFinalizers runs the registered finalizers with a context bound to the deadline
+func (s *Systemd) runFinalizers(deadline time.Time) {
+	s.mu.RLock()
+	finalizers := append([]finalizer{}, s.finalizers...)
+	s.mu.RUnlock()
+
+	if len(finalizers) == 0 {
+		return
+	}
+
+	ctx, cancel := withClockTimeout(context.Background(), s.clock, deadline.Sub(s.clock.Now()))
+	defer cancel()
+
+	for i := len(finalizers) - 1; i >= 0; i-- {
+		f := finalizers[i]
+		s.logger.Info("Running finalizer %q", f.name)
+		if err := recovered(func() error { return f.fn(ctx) }); err != nil {
+			s.logger.Error("finalizer %q failed: %v", f.name, err)
+		}
+	}
+}
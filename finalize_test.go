@@ -0,0 +1,46 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestFinalizersRunAfterAppsInReverseOrder(t *testing.T) {
+	rec := &recorder{}
+	finalizer := func(name string, err error) sysd.Finalizer {
+		return func(context.Context) error {
+			rec.record(name)
+			return err
+		}
+	}
+
+	h := sysdtest.NewHarness(t, sysd.WithFinalizer("pidfile", finalizer("pidfile", nil)))
+	app := h.NewApp("app")
+	h.Systemd.AddFinalizer("telemetry", finalizer("telemetry", errors.New("flush failed")))
+	h.Systemd.AddFinalizer("discovery", func(context.Context) error {
+		if app.Running() {
+			rec.record("app still running")
+		}
+		rec.record("discovery")
+		panic("deregister")
+	})
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	if calls := rec.take(); len(calls) != 0 {
+		t.Fatalf("finalizers ran while apps are running: %v", calls)
+	}
+
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	// failing and panicking finalizers do not keep the others from running
+	want := []string{"discovery", "telemetry", "pidfile"}
+	if calls := rec.take(); fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Fatalf("finalizers ran as %v, want %v", calls, want)
+	}
+}
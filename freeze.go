@@ -0,0 +1,65 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Freezer is an optional interface for apps that can pause their work
+// without stopping, e.g. stop accepting new work while holding connections
+type Freezer interface {
+	// Freeze pauses the app
+	Freeze(ctx context.Context) error
+	// Thaw resumes the app after a freeze
+	Thaw(ctx context.Context) error
+}
+
+// Freeze pauses all apps implementing Freezer, in reverse priority order,
+// and pauses status checks until Thaw is called
+func (s *Systemd) Freeze(ctx context.Context) error {
+	s.frozen.Store(true)
+
+	apps := s.appList()
+
+	var errs []error
+	for i := len(apps) - 1; i >= 0; i-- {
+		freezer, ok := apps[i].App.(Freezer)
+		if !ok {
+			continue
+		}
+		s.logger.Info("Freezing app %q", apps[i].Name())
+		if err := freezer.Freeze(ctx); err != nil {
+			s.logger.Error("app %q freeze failed: %v", apps[i].Name(), err)
+			errs = append(errs, fmt.Errorf("freeze app %q: %w", apps[i].Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Thaw resumes all apps implementing Freezer, in priority order,
+// and resumes status checks
+func (s *Systemd) Thaw(ctx context.Context) error {
+	apps := s.appList()
+
+	var errs []error
+	for _, app := range apps {
+		freezer, ok := app.App.(Freezer)
+		if !ok {
+			continue
+		}
+		s.logger.Info("Thawing app %q", app.Name())
+		if err := freezer.Thaw(ctx); err != nil {
+			s.logger.Error("app %q thaw failed: %v", app.Name(), err)
+			errs = append(errs, fmt.Errorf("thaw app %q: %w", app.Name(), err))
+		}
+	}
+
+	s.frozen.Store(false)
+	return errors.Join(errs...)
+}
+
+// IsFrozen returns true if the systemd service is frozen
+func (s *Systemd) IsFrozen() bool {
+	return s.frozen.Load()
+}
@@ -0,0 +1,106 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// recorder records calls in order across apps
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) record(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls := r.calls
+	r.calls = nil
+	return calls
+}
+
+// freezingApp is a fake app implementing sysd.Freezer
+type freezingApp struct {
+	*sysdtest.FakeApp
+	rec *recorder
+	err error
+}
+
+func (a *freezingApp) Freeze(context.Context) error {
+	a.rec.record("freeze " + a.Name())
+	return a.err
+}
+
+func (a *freezingApp) Thaw(context.Context) error {
+	a.rec.record("thaw " + a.Name())
+	return nil
+}
+
+func TestFreezeAndThaw(t *testing.T) {
+	rec := &recorder{}
+	failed := errors.New("busy")
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	first := &freezingApp{FakeApp: sysdtest.NewFakeApp("first"), rec: rec}
+	second := &freezingApp{FakeApp: sysdtest.NewFakeApp("second"), rec: rec, err: failed}
+	if err := h.Systemd.Add(first, sysd.WithPriority(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Systemd.Add(second, sysd.WithPriority(2)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("first", sysd.AppRunning)
+	h.WaitForState("second", sysd.AppRunning)
+
+	err := h.Systemd.Freeze(context.Background())
+	if !errors.Is(err, failed) {
+		t.Errorf("Freeze returned %v, want the error of the app %v", err, failed)
+	}
+	if !h.Systemd.IsFrozen() {
+		t.Fatal("not frozen after Freeze")
+	}
+	if calls := rec.take(); !slices.Equal(calls, []string{"freeze second", "freeze first"}) {
+		t.Errorf("freeze calls are %q, want reverse priority order", calls)
+	}
+
+	// status checks are paused while frozen, a check in flight may still finish
+	time.Sleep(20 * time.Millisecond)
+	checks := first.StatusChecks()
+	for i := 0; i < 5; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := first.StatusChecks(); n != checks {
+		t.Errorf("app status checked %d times while frozen", n-checks)
+	}
+
+	if err := h.Systemd.Thaw(context.Background()); err != nil {
+		t.Errorf("Thaw returned %v", err)
+	}
+	if h.Systemd.IsFrozen() {
+		t.Error("still frozen after Thaw")
+	}
+	if calls := rec.take(); !slices.Equal(calls, []string{"thaw first", "thaw second"}) {
+		t.Errorf("thaw calls are %q, want priority order", calls)
+	}
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); first.StatusChecks() == checks && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if first.StatusChecks() == checks {
+		t.Error("status checks did not resume after Thaw")
+	}
+}
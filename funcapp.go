@@ -0,0 +1,34 @@
+package sysd
+
+import "context"
+
+type funcApp struct {
+	name   string
+	start  func(ctx context.Context) error
+	status func(ctx context.Context) error
+}
+
+// AppFunc returns an App running the given functions, so small background tasks can be
+// supervised without writing a type. a nil start blocks until the app is stopped and
+// a nil status always reports the app as healthy
+func AppFunc(name string, start func(ctx context.Context) error, status func(ctx context.Context) error) App {
+	return &funcApp{name: name, start: start, status: status}
+}
+
+func (f *funcApp) Start(ctx context.Context) error {
+	if f.start == nil {
+		return ShutdownGracefully(ctx, nil)
+	}
+	return f.start(ctx)
+}
+
+func (f *funcApp) Status(ctx context.Context) error {
+	if f.status == nil {
+		return nil
+	}
+	return f.status(ctx)
+}
+
+func (f *funcApp) Name() string {
+	return f.name
+}
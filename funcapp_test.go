@@ -0,0 +1,46 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+)
+
+func TestAppFuncDefaults(t *testing.T) {
+	app := sysd.AppFunc("noop", nil, nil)
+	if app.Name() != "noop" {
+		t.Errorf("name is %q, want noop", app.Name())
+	}
+	if err := app.Status(context.Background()); err != nil {
+		t.Errorf("nil status reported %v, want healthy", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.Start(ctx) }()
+	select {
+	case err := <-done:
+		t.Fatalf("nil start returned %v before the app is stopped", err)
+	default:
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("nil start returned %v once stopped, want nil", err)
+	}
+}
+
+func TestAppFuncCallsFunctions(t *testing.T) {
+	boom := errors.New("boom")
+	app := sysd.AppFunc("pusher",
+		func(context.Context) error { return boom },
+		func(context.Context) error { return sysd.Degraded(boom) },
+	)
+	if err := app.Start(context.Background()); !errors.Is(err, boom) {
+		t.Errorf("Start returned %v, want %v", err, boom)
+	}
+	if err := app.Status(context.Background()); sysd.HealthOf(err) != sysd.HealthDegraded {
+		t.Errorf("Status returned %v, want the degraded status of the function", err)
+	}
+}
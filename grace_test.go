@@ -0,0 +1,57 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStartupGraceDelaysStatusChecks(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	app := h.NewApp("slow", sysd.WithStartupGrace(30*time.Second), sysd.WithOnFailure(sysd.OnFailureIgnore))
+	initializing := errors.New("initializing")
+	app.SetStatus(initializing)
+	if err := h.Systemd.SetAppStartupGrace("missing", time.Second); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Errorf("SetAppStartupGrace of a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("slow", sysd.AppRunning)
+
+	for i := 0; i < 25; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := app.StatusChecks(); n != 0 {
+		t.Errorf("app status checked %d times within its startup grace", n)
+	}
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "slow")
+	if !errors.Is(e.Err, initializing) {
+		t.Errorf("app failed with %v, want its status %v after its startup grace", e.Err, initializing)
+	}
+}
+
+func TestStartupGraceEndsOnceReady(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	app := &readyApp{FakeApp: sysdtest.NewFakeApp("app"), release: make(chan struct{})}
+	if err := h.Systemd.Add(app, sysd.WithStartupGrace(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app did not start")
+	}
+
+	close(app.release)
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); app.StatusChecks() == 0 && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if app.StatusChecks() == 0 {
+		t.Error("app not status checked once it marked itself ready within its startup grace")
+	}
+}
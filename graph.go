@@ -0,0 +1,69 @@
+package sysd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphNode is an app in the dependency graph
+type GraphNode struct {
+	Name      string   `json:"name"`
+	Priority  int      `json:"priority"`
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// Graph is the dependency and priority graph of the apps, nodes are in start order.
+// it marshals to JSON as is, DOT renders it for graphviz
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+}
+
+// Graph returns the dependency and priority graph of the apps, e.g. to review the boot order
+func (s *Systemd) Graph() Graph {
+	apps := s.startOrder(s.appList())
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g := Graph{Nodes: make([]GraphNode, 0, len(apps))}
+	for _, app := range apps {
+		g.Nodes = append(g.Nodes, GraphNode{
+			Name:      app.name,
+			Priority:  app.priority,
+			DependsOn: append([]string(nil), app.dependsOn...),
+		})
+	}
+	return g
+}
+
+// DOT renders the graph in the graphviz DOT language, apps of the same priority are clustered
+// and edges point from an app to the apps it depends on
+func (g Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph sysd {\n")
+
+	var priorities []int
+	clusters := make(map[int][]string)
+	for _, node := range g.Nodes {
+		if _, ok := clusters[node.Priority]; !ok {
+			priorities = append(priorities, node.Priority)
+		}
+		clusters[node.Priority] = append(clusters[node.Priority], node.Name)
+	}
+	for _, priority := range priorities {
+		fmt.Fprintf(&b, "\tsubgraph \"cluster_priority_%d\" {\n", priority)
+		fmt.Fprintf(&b, "\t\tlabel=\"priority %d\";\n", priority)
+		for _, name := range clusters[priority] {
+			fmt.Fprintf(&b, "\t\t%q;\n", name)
+		}
+		b.WriteString("\t}\n")
+	}
+
+	for _, node := range g.Nodes {
+		for _, dep := range node.DependsOn {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", node.Name, dep)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package sysd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func newGraphSystemd(t *testing.T) *sysd.Systemd {
+	t.Helper()
+
+	s := sysd.New()
+	add := func(name string, opts ...sysd.AddOption) {
+		if err := s.Add(sysdtest.NewFakeApp(name), opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("api", sysd.WithPriority(1))
+	add("db")
+	add("cache")
+	if err := s.SetAppDependencies("api", "db", "cache"); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestGraphInStartOrder(t *testing.T) {
+	g := newGraphSystemd(t).Graph()
+
+	var order []string
+	for _, node := range g.Nodes {
+		order = append(order, node.Name)
+	}
+	if fmt.Sprint(order) != "[cache db api]" {
+		t.Fatalf("graph nodes are %v, want [cache db api]", order)
+	}
+	api := g.Nodes[2]
+	if api.Priority != 1 || fmt.Sprint(api.DependsOn) != "[db cache]" {
+		t.Errorf("api node is %+v", api)
+	}
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `{"name":"api","priority":1,"depends_on":["db","cache"]}`) {
+		t.Errorf("graph JSON is %s", b)
+	}
+}
+
+func TestGraphDOT(t *testing.T) {
+	dot := newGraphSystemd(t).Graph().DOT()
+	for _, want := range []string{
+		"digraph sysd {",
+		`subgraph "cluster_priority_0" {`,
+		`subgraph "cluster_priority_1" {`,
+		`"api" -> "db";`,
+		`"api" -> "cache";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output does not contain %q:\n%s", want, dot)
+		}
+	}
+}
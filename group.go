@@ -0,0 +1,54 @@
+package sysd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+var _ App = &Group{}
+
+// Group is a systemd service which runs as an app of another systemd service, so related
+// apps can be managed as a unit with their own failure policy, shutdown timeout and priority
+// in the parent
+type Group struct {
+	*Systemd
+	name string
+}
+
+// NewGroup returns a new Group with the given name configured with the given options.
+// the group does not notify the service manager nor handle signals, the root service does
+func NewGroup(name string, opts ...Option) *Group {
+	s := New(opts...)
+	s.nested = true
+	return &Group{
+		Systemd: s,
+		name:    name,
+	}
+}
+
+// Name returns the name of the group
+func (g *Group) Name() string {
+	return g.name
+}
+
+// Status returns an error if any app of the group is failed or quarantined,
+// a Degraded error if any app is degraded
+func (g *Group) Status(ctx context.Context) error {
+	var unhealthy, degraded []string
+	for _, app := range g.Snapshot() {
+		switch app.State {
+		case AppFailed, AppQuarantined:
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is %s", app.Name, app.State))
+		case AppDegraded:
+			degraded = append(degraded, app.Name)
+		}
+	}
+	if len(unhealthy) > 0 {
+		return fmt.Errorf("group %q: %s", g.name, strings.Join(unhealthy, ", "))
+	}
+	if len(degraded) > 0 {
+		return Degraded(fmt.Errorf("group %q: %s degraded", g.name, strings.Join(degraded, ", ")))
+	}
+	return nil
+}
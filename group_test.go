@@ -0,0 +1,210 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// listenNotify sets NOTIFY_SOCKET to a socket of the test and returns it
+func listenNotify(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	addr := &net.UnixAddr{Name: filepath.Join(t.TempDir(), "notify.sock"), Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Fatalf("listen notify socket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", addr.Name)
+	return conn
+}
+
+// readNotify returns the states sent to the socket until nothing is sent for the quiet period
+func readNotify(t *testing.T, conn *net.UnixConn, quiet time.Duration) []string {
+	t.Helper()
+
+	var states []string
+	buf := make([]byte, 256)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(quiet))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return states
+		}
+		states = append(states, string(buf[:n]))
+	}
+}
+
+func count(states []string, state string) int {
+	n := 0
+	for _, s := range states {
+		if s == state {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGroupDoesNotNotify(t *testing.T) {
+	conn := listenNotify(t)
+
+	h := sysdtest.NewHarness(t)
+	group := sysd.NewGroup("group", sysd.WithClock(h.Clock))
+	if err := group.Add(sysdtest.NewFakeApp("inner")); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Systemd.Add(group); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("group", sysd.AppRunning)
+	sysdtest.WaitForState(t, group.Systemd, "inner", sysd.AppRunning)
+
+	// readiness is polled on the clock of the service
+	var states []string
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); count(states, "READY=1") == 0 && time.Now().Before(deadline); {
+		h.Clock.Advance(100 * time.Millisecond)
+		states = append(states, readNotify(t, conn, 10*time.Millisecond)...)
+	}
+	states = append(states, readNotify(t, conn, 500*time.Millisecond)...)
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	states = append(states, readNotify(t, conn, 200*time.Millisecond)...)
+
+	if n := count(states, "READY=1"); n != 1 {
+		t.Errorf("READY=1 sent %d times, want once: %q", n, states)
+	}
+	if n := count(states, "STOPPING=1"); n != 1 {
+		t.Errorf("STOPPING=1 sent %d times, want once: %q", n, states)
+	}
+}
+
+// reloadingApp is a fake app counting its reloads
+type reloadingApp struct {
+	*sysdtest.FakeApp
+	reloads atomic.Int32
+}
+
+func (a *reloadingApp) Reload(context.Context) error {
+	a.reloads.Add(1)
+	return nil
+}
+
+func TestGroupDoesNotHandleSignals(t *testing.T) {
+	// the test process must survive the signal whoever handles it
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGHUP)
+	defer signal.Stop(ignored)
+
+	h := sysdtest.NewHarness(t)
+	group := sysd.NewGroup("group", sysd.WithClock(h.Clock))
+	inner := &reloadingApp{FakeApp: sysdtest.NewFakeApp("inner")}
+	if err := group.Add(inner); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Systemd.Add(group); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("group", sysd.AppRunning)
+	sysdtest.WaitForState(t, group.Systemd, "inner", sysd.AppRunning)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(sysdtest.WaitTimeout)
+	for inner.reloads.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	// give a second handler the time to reload again
+	time.Sleep(200 * time.Millisecond)
+	if n := inner.reloads.Load(); n != 1 {
+		t.Errorf("inner app reloaded %d times, want once", n)
+	}
+}
+
+func TestGroupRunsAsApp(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	group := sysd.NewGroup("storage", sysd.WithClock(h.Clock))
+	db := sysdtest.NewFakeApp("db")
+	cache := sysdtest.NewFakeApp("cache")
+	if err := group.Add(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Add(cache, sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Systemd.Add(group, sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("storage", sysd.AppRunning)
+	sysdtest.WaitForState(t, group.Systemd, "db", sysd.AppRunning)
+	sysdtest.WaitForState(t, group.Systemd, "cache", sysd.AppRunning)
+	if err := group.Status(context.Background()); err != nil {
+		t.Errorf("group status is %v with all apps running", err)
+	}
+
+	// the group reports the health of its apps to the parent
+	if err := group.StopApp(context.Background(), "cache"); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Status(context.Background()); err != nil {
+		t.Errorf("group status is %v with an app stopped on purpose", err)
+	}
+
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	if db.Running() {
+		t.Error("app of the group still running after the parent stopped")
+	}
+	if !errors.Is(db.Cause(), sysd.ErrShutdown) {
+		t.Errorf("app of the group cancelled with %v, want %v", db.Cause(), sysd.ErrShutdown)
+	}
+}
+
+func TestGroupStatusReportsApps(t *testing.T) {
+	group := sysd.NewGroup("transport")
+	if err := group.Add(sysdtest.NewFakeApp("http").SetStatus(sysd.Degraded(errors.New("slow")))); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Add(sysdtest.NewFakeApp("grpc").FailStarts(1, errors.New("boom")), sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Status(context.Background()); err != nil {
+		t.Errorf("group status is %v before it is started", err)
+	}
+
+	group.SetStatusCheckInterval(10 * time.Millisecond)
+	h, err := group.StartAsync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = h.Shutdown(context.Background()) })
+
+	sysdtest.WaitForState(t, group.Systemd, "grpc", sysd.AppFailed)
+	if err := group.Status(context.Background()); err == nil || sysd.HealthOf(err) != sysd.HealthUnhealthy {
+		t.Errorf("group status is %v with a failed app, want unhealthy", err)
+	}
+	if err := group.RestartApp(context.Background(), "grpc"); err != nil {
+		t.Fatal(err)
+	}
+	sysdtest.WaitForState(t, group.Systemd, "http", sysd.AppDegraded)
+	sysdtest.WaitForState(t, group.Systemd, "grpc", sysd.AppRunning)
+	if err := group.Status(context.Background()); sysd.HealthOf(err) != sysd.HealthDegraded {
+		t.Errorf("group status is %v with a degraded app, want degraded", err)
+	}
+}
@@ -0,0 +1,127 @@
+package sysd
+
+import "context"
+
+// RunHandle controls a systemd service started with StartAsync
+type RunHandle struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+	err    error
+}
+
+// StartAsync starts the systemd service in the background and returns right away
+// with a handle to wait for or shut it down
+func (s *Systemd) StartAsync(ctx context.Context) (*RunHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.run != nil || (s.handle != nil && s.handle.Running()) {
+		return nil, ErrAlreadyRunning
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	h := &RunHandle{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.handle = h
+	go func() {
+		defer close(h.done)
+		defer cancel(nil)
+		h.err = s.Start(ctx)
+	}()
+	return h, nil
+}
+
+// IsRunning returns true if the systemd service is started and not stopped yet
+func (s *Systemd) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.run != nil
+}
+
+// Wait blocks until the systemd service stops and returns the error Start returned
+func (h *RunHandle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Done returns a channel that is closed once the systemd service stops
+func (h *RunHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Running returns true until the systemd service stops
+func (h *RunHandle) Running() bool {
+	select {
+	case <-h.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Shutdown gracefully stops the systemd service and waits for it to stop or the context
+// to be done, it returns the error Start returned
+func (h *RunHandle) Shutdown(ctx context.Context) error {
+	h.cancel(ErrShutdownRequested)
+	select {
+	case <-h.done:
+		return h.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run starts the systemd service in the background and returns right away, use Wait to wait
+// for it to stop and Shutdown to stop it. errors found before starting any app are returned
+func (s *Systemd) Run(ctx context.Context) error {
+	if err := s.checkDependencies(); err != nil {
+		return err
+	}
+	_, err := s.StartAsync(ctx)
+	return err
+}
+
+// Wait blocks until the systemd service started with Run or StartAsync stops and returns the error Start returned
+func (s *Systemd) Wait() error {
+	s.mu.RLock()
+	h := s.handle
+	s.mu.RUnlock()
+
+	if h == nil {
+		return ErrNotRunning
+	}
+	return h.Wait()
+}
+
+// Shutdown gracefully stops the running systemd service, started with Start, StartAsync or Run,
+// and waits for it to stop or the context to be done. it returns the error Start returned.
+// apps see ErrShutdownRequested as the shutdown cause
+func (s *Systemd) Shutdown(ctx context.Context) error {
+	s.mu.RLock()
+	run, h := s.run, s.handle
+	s.mu.RUnlock()
+
+	var done <-chan struct{}
+	var result func() error
+	switch {
+	case run != nil:
+		requestShutdown(run.ctx, ErrShutdownRequested)
+		done, result = run.done, func() error { return run.err }
+	case h != nil && h.Running():
+		// started in the background but Start has not taken over yet
+		h.cancel(ErrShutdownRequested)
+		done, result = h.done, func() error { return h.err }
+	default:
+		return ErrNotRunning
+	}
+
+	s.logger.Info("Shutdown requested")
+	select {
+	case <-done:
+		return result()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
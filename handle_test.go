@@ -0,0 +1,108 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStartAsync(t *testing.T) {
+	s := sysd.New()
+	app := sysdtest.NewFakeApp("app")
+	if err := s.Add(app); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := s.StartAsync(context.Background())
+	if err != nil {
+		t.Fatalf("StartAsync returned %v", err)
+	}
+	sysdtest.WaitForState(t, s, "app", sysd.AppRunning)
+	if !h.Running() {
+		t.Error("handle is not running while the app is")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sysdtest.WaitTimeout)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown returned %v", err)
+	}
+	select {
+	case <-h.Done():
+	default:
+		t.Error("handle not done after Shutdown")
+	}
+	if h.Running() {
+		t.Error("handle still running after Shutdown")
+	}
+	if err := h.Wait(); err != nil {
+		t.Errorf("Wait returned %v", err)
+	}
+	if app.Running() {
+		t.Error("app still running after Shutdown")
+	}
+}
+
+func TestRunUsesStartAsync(t *testing.T) {
+	s := sysd.New()
+	app := sysdtest.NewFakeApp("app")
+	if err := s.Add(app); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+	if _, err := s.StartAsync(context.Background()); !errors.Is(err, sysd.ErrAlreadyRunning) {
+		t.Errorf("StartAsync while running returned %v, want %v", err, sysd.ErrAlreadyRunning)
+	}
+	if err := s.Run(context.Background()); !errors.Is(err, sysd.ErrAlreadyRunning) {
+		t.Errorf("Run while running returned %v, want %v", err, sysd.ErrAlreadyRunning)
+	}
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app did not start")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sysdtest.WaitTimeout)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown returned %v", err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("Wait returned %v", err)
+	}
+	if !errors.Is(app.Cause(), sysd.ErrShutdownRequested) {
+		t.Errorf("app cancelled with %v, want %v", app.Cause(), sysd.ErrShutdownRequested)
+	}
+}
+
+func TestShutdownReturnsStartError(t *testing.T) {
+	boom := errors.New("boom")
+	h := sysdtest.NewHarness(t)
+	h.NewApp("slow").SetStopDelay(10 * time.Second)
+	h.NewApp("bad", sysd.WithOnFailure(sysd.OnFailureShutdownAll)).FailStarts(1, boom)
+	h.Start()
+	h.WaitForEvent(sysd.EventShutdownBegun, "")
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- h.Systemd.Shutdown(context.Background()) }()
+
+	deadline := time.After(sysdtest.WaitTimeout)
+	for {
+		select {
+		case err := <-shutdown:
+			if !errors.Is(err, boom) {
+				t.Errorf("Shutdown returned %v, want the error of Start %v", err, boom)
+			}
+			return
+		case <-time.After(5 * time.Millisecond):
+			h.Clock.Advance(time.Second)
+		case <-deadline:
+			t.Fatal("Shutdown did not return")
+		}
+	}
+}
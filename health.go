@@ -0,0 +1,75 @@
+package sysd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDegraded marks a status check error reporting the app as degraded rather than unhealthy,
+// a degraded app keeps running and its on failure action is not taken
+var ErrDegraded = errors.New("degraded")
+
+// Health is the health level of an app or the whole systemd service
+type Health int
+
+const (
+	// HealthHealthy means everything works as expected
+	HealthHealthy Health = iota
+	// HealthDegraded means the app works with reduced capacity, e.g. replica lag or a half open circuit
+	HealthDegraded
+	// HealthUnhealthy means the app does not work
+	HealthUnhealthy
+)
+
+var healthNames = map[Health]string{
+	HealthHealthy:   "healthy",
+	HealthDegraded:  "degraded",
+	HealthUnhealthy: "unhealthy",
+}
+
+// String returns the string representation of the Health
+func (h Health) String() string {
+	if name, ok := healthNames[h]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Degraded wraps a status check error to report the app as degraded instead of unhealthy
+func Degraded(err error) error {
+	return fmt.Errorf("%w: %w", ErrDegraded, err)
+}
+
+// HealthOf returns the health level a status check error reports
+func HealthOf(err error) Health {
+	switch {
+	case err == nil:
+		return HealthHealthy
+	case errors.Is(err, ErrDegraded):
+		return HealthDegraded
+	default:
+		return HealthUnhealthy
+	}
+}
+
+// Health returns the worst health level of all apps, failed and quarantined apps are unhealthy
+func (s *Systemd) Health() Health {
+	health := HealthHealthy
+	for _, app := range s.Snapshot() {
+		switch app.State {
+		case AppFailed, AppQuarantined:
+			return HealthUnhealthy
+		case AppDegraded:
+			health = HealthDegraded
+		}
+	}
+	return health
+}
+
+// statusDegraded moves a running app reporting degraded health to the degraded state
+func (s *Systemd) statusDegraded(app *appItem, err error) {
+	if s.appState(app) != AppDegraded {
+		s.logger.Warn("app %q is degraded: %v", app.Name(), err)
+	}
+	s.setState(app, AppDegraded, err)
+}
@@ -0,0 +1,62 @@
+package sysd_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestHealthOf(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		err  error
+		want sysd.Health
+	}{
+		{err: nil, want: sysd.HealthHealthy},
+		{err: sysd.Degraded(boom), want: sysd.HealthDegraded},
+		{err: fmt.Errorf("replica: %w", sysd.Degraded(boom)), want: sysd.HealthDegraded},
+		{err: boom, want: sysd.HealthUnhealthy},
+	}
+	for _, tt := range tests {
+		if got := sysd.HealthOf(tt.err); got != tt.want {
+			t.Errorf("HealthOf(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHealthIsWorstOfApps(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	replica := h.NewApp("replica")
+	breaker := h.NewApp("breaker", sysd.WithOnFailure(sysd.OnFailureIgnore))
+	h.Start()
+	h.WaitForState("breaker", sysd.AppRunning)
+	if health := h.Systemd.Health(); health != sysd.HealthHealthy {
+		t.Errorf("health is %s with all apps running, want %s", health, sysd.HealthHealthy)
+	}
+
+	// a degraded app keeps running instead of being restarted
+	replica.SetStatus(sysd.Degraded(errors.New("replica lag")))
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); h.Systemd.Health() != sysd.HealthDegraded && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if health := h.Systemd.Health(); health != sysd.HealthDegraded {
+		t.Errorf("health is %s with a degraded app, want %s", health, sysd.HealthDegraded)
+	}
+	if n := replica.Starts(); n != 1 || !replica.Running() {
+		t.Errorf("degraded app started %d times, want it kept running", n)
+	}
+
+	breaker.SetStatus(errors.New("open circuit"))
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); h.Systemd.Health() != sysd.HealthUnhealthy && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if health := h.Systemd.Health(); health != sysd.HealthUnhealthy {
+		t.Errorf("health is %s with a failed app, want %s", health, sysd.HealthUnhealthy)
+	}
+}
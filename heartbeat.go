@@ -0,0 +1,68 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrHeartbeatMissed is returned by the status check of an app which did not send
+// a heartbeat within its heartbeat timeout
+var ErrHeartbeatMissed = errors.New("heartbeat missed")
+
+type heartbeatKey struct{}
+
+// heartbeat is the context value of apps, recording their heartbeats with the clock of their systemd service
+type heartbeat struct {
+	app   *appItem
+	clock Clock
+}
+
+// Heartbeat reports the app owning the context as alive, apps with a heartbeat timeout must
+// call it regularly from their main loop. it should be called with the context passed to the
+// app Start and returns false if the context does not belong to an app started by a systemd service
+func Heartbeat(ctx context.Context) bool {
+	hb, ok := ctx.Value(heartbeatKey{}).(heartbeat)
+	if !ok {
+		return false
+	}
+	hb.app.lastHeartbeat.Store(hb.clock.Now().UnixNano())
+	return true
+}
+
+// SetAppHeartbeatTimeout fails the status check of a specific app if it does not call Heartbeat
+// within the timeout, zero disables it
+func (s *Systemd) SetAppHeartbeatTimeout(appName string, timeout time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app, ok := s.apps[appName]; ok {
+		app.heartbeatTimeout = timeout
+		return nil
+	}
+
+	return ErrAppNotExists
+}
+
+// checkHeartbeat returns ErrHeartbeatMissed if the app did not send a heartbeat in time,
+// the (re)start of the app counts as a heartbeat
+func (s *Systemd) checkHeartbeat(app *appItem) error {
+	s.mu.RLock()
+	timeout := app.heartbeatTimeout
+	s.mu.RUnlock()
+
+	if timeout == 0 {
+		return nil
+	}
+
+	last := max(app.lastHeartbeat.Load(), app.startedAt.Load())
+	if last == 0 {
+		return nil
+	}
+
+	if since := s.clock.Now().Sub(time.Unix(0, last)); since > timeout {
+		return fmt.Errorf("%w for %s", ErrHeartbeatMissed, since.Round(time.Millisecond))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// beatingApp is a fake app sending a heartbeat on every tick until it wedges
+type beatingApp struct {
+	*sysdtest.FakeApp
+	tick   chan struct{}
+	wedged atomic.Bool
+}
+
+func (a *beatingApp) Start(ctx context.Context) error {
+	go func() {
+		for {
+			select {
+			case <-a.tick:
+				if !a.wedged.Load() {
+					sysd.Heartbeat(ctx)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return a.FakeApp.Start(ctx)
+}
+
+func TestMissedHeartbeatRestartsApp(t *testing.T) {
+	if sysd.Heartbeat(context.Background()) {
+		t.Error("Heartbeat without an app context returned true")
+	}
+
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	app := &beatingApp{FakeApp: sysdtest.NewFakeApp("loop"), tick: make(chan struct{})}
+	err := h.Systemd.Add(app, sysd.WithHeartbeatTimeout(5*time.Second),
+		sysd.WithOnFailure(sysd.OnFailureRestart.RetryTimeout(time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("loop", sysd.AppRunning)
+
+	// an app sending heartbeats keeps running although the heartbeat timeout passes many times
+	for i := 0; i < 30; i++ {
+		app.tick <- struct{}{}
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := app.Starts(); n != 1 {
+		t.Fatalf("app sending heartbeats started %d times, want once", n)
+	}
+
+	// the main loop wedges while Status still passes
+	app.wedged.Store(true)
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "loop")
+	if !errors.Is(e.Err, sysd.ErrStatusCheckFailed) || !strings.Contains(e.Err.Error(), sysd.ErrHeartbeatMissed.Error()) {
+		t.Errorf("app restarted for %v, want a missed heartbeat", e.Err)
+	}
+}
@@ -0,0 +1,55 @@
+package sysd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAppIdle is returned by the status check of an app idle for longer than its idle timeout
+var ErrAppIdle = errors.New("app is idle")
+
+// ActivityReporter is an optional interface for apps that should be doing work, like
+// workers and consumers, to report when they last did some. it lets the status check
+// catch apps which are stalled while their Status still passes
+type ActivityReporter interface {
+	// LastActivity returns the time the app last did some work
+	LastActivity() time.Time
+}
+
+// SetAppIdleTimeout sets how long an app implementing ActivityReporter may be idle
+// before its status check fails, zero disables the check
+func (s *Systemd) SetAppIdleTimeout(appName string, timeout time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app, ok := s.apps[appName]; ok {
+		app.idleTimeout = timeout
+		return nil
+	}
+
+	return ErrAppNotExists
+}
+
+// checkIdle returns ErrAppIdle if the app has been idle for longer than its idle timeout
+func (s *Systemd) checkIdle(app *appItem) error {
+	s.mu.RLock()
+	timeout := app.idleTimeout
+	s.mu.RUnlock()
+
+	reporter, ok := app.App.(ActivityReporter)
+	if !ok || timeout == 0 {
+		return nil
+	}
+
+	// don't count the time before the app was (re)started
+	last := reporter.LastActivity()
+	if startedAt := app.startedAt.Load(); startedAt != 0 && last.Before(time.Unix(0, startedAt)) {
+		last = time.Unix(0, startedAt)
+	}
+
+	if idle := s.clock.Now().Sub(last); idle > timeout {
+		return fmt.Errorf("%w for %s", ErrAppIdle, idle.Round(time.Second))
+	}
+	return nil
+}
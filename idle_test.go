@@ -0,0 +1,73 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// stallingApp is a fake app reporting activity until it is stalled
+type stallingApp struct {
+	*sysdtest.FakeApp
+	clock *sysdtest.Clock
+
+	mu      sync.Mutex
+	stalled bool
+	last    time.Time
+}
+
+func (a *stallingApp) Status(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.stalled {
+		a.last = a.clock.Now()
+	}
+	a.mu.Unlock()
+	return a.FakeApp.Status(ctx)
+}
+
+func (a *stallingApp) LastActivity() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.last
+}
+
+func (a *stallingApp) stall() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stalled = true
+	return a.last
+}
+
+func TestIdleAppFailsStatus(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	app := &stallingApp{FakeApp: sysdtest.NewFakeApp("worker"), clock: h.Clock}
+	if err := h.Systemd.Add(app, sysd.WithIdleTimeout(time.Minute), sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("worker", sysd.AppRunning)
+
+	// an active app passes its status checks for longer than the idle timeout
+	for i := 0; i < 120; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	if !app.Running() {
+		t.Fatal("active app stopped")
+	}
+
+	last := app.stall()
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "worker")
+	if !errors.Is(e.Err, sysd.ErrAppIdle) {
+		t.Errorf("app failed with %v, want %v", e.Err, sysd.ErrAppIdle)
+	}
+	if idle := e.Time.Sub(last); idle <= time.Minute {
+		t.Errorf("app flagged after %s idle, want more than the idle timeout", idle)
+	}
+}
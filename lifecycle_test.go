@@ -0,0 +1,173 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// overlapApp records how many of its Start calls run at the same time
+type overlapApp struct {
+	name      string
+	active    atomic.Int32
+	overlap   atomic.Bool
+	unhealthy atomic.Bool
+}
+
+func (a *overlapApp) Name() string { return a.name }
+
+func (a *overlapApp) Start(ctx context.Context) error {
+	if a.active.Add(1) > 1 {
+		a.overlap.Store(true)
+	}
+	defer a.active.Add(-1)
+	<-ctx.Done()
+	return nil
+}
+
+func (a *overlapApp) Status(context.Context) error {
+	if a.unhealthy.Load() {
+		return errors.New("unhealthy")
+	}
+	return nil
+}
+
+func TestConcurrentLifecycleCommandsDoNotOverlap(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := &overlapApp{name: "app"}
+	if err := h.Systemd.Add(app); err != nil {
+		t.Fatal(err)
+	}
+	h.NewApp("other")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				switch (i + j) % 3 {
+				case 0:
+					_ = h.Systemd.RestartApp(ctx, "app")
+				case 1:
+					_ = h.Systemd.Pause(ctx, "app")
+				case 2:
+					_ = h.Systemd.Resume("app")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_ = h.Systemd.Resume("app")
+	if err := h.Systemd.RestartApp(ctx, "app"); err != nil {
+		t.Fatalf("RestartApp returned %v", err)
+	}
+	h.WaitForState("app", sysd.AppRunning)
+	if app.overlap.Load() {
+		t.Error("the app was started while still running")
+	}
+	if h.Returned() {
+		t.Fatal("Start returned while apps are running")
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+}
+
+func TestStatusRestartsInterleaveWithCommands(t *testing.T) {
+	// the clock races ahead, stopping apps must not time out while they are still returning
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second), sysd.WithStatusCheckTimeout(time.Hour),
+		sysd.WithGracefulShutdownTimeout(24*time.Hour))
+	h.Systemd.SetRestartStrategy(sysd.OneForAll)
+	flaky, app := &overlapApp{name: "flaky"}, &overlapApp{name: "app"}
+	flaky.unhealthy.Store(true)
+	for _, a := range []*overlapApp{flaky, app} {
+		if err := h.Systemd.Add(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	// the failing app restarts both apps on every check while the other one is driven by hand
+	ctx := context.Background()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				switch (i + j) % 4 {
+				case 0:
+					_ = h.Systemd.RestartApp(ctx, "app")
+				case 1:
+					_ = h.Systemd.Pause(ctx, "app")
+				case 2:
+					_ = h.Systemd.Resume("app")
+				case 3:
+					_ = h.Systemd.StopApp(ctx, "app")
+				}
+			}
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for waiting := true; waiting; {
+		select {
+		case <-done:
+			waiting = false
+		case <-time.After(time.Millisecond):
+			h.Clock.Advance(time.Second)
+		}
+	}
+
+	flaky.unhealthy.Store(false)
+	_ = h.Systemd.Resume("app")
+	if err := h.Systemd.RestartApp(ctx, "app"); err != nil {
+		t.Fatalf("RestartApp returned %v", err)
+	}
+	h.WaitForState("app", sysd.AppRunning)
+	h.WaitForState("flaky", sysd.AppRunning)
+	for _, a := range []*overlapApp{flaky, app} {
+		if a.overlap.Load() {
+			t.Errorf("app %q was started while still running", a.name)
+		}
+	}
+	if h.Returned() {
+		t.Fatal("Start returned while apps are running")
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+}
+
+func TestIgnoredStatusFailureKeepsAppRegistered(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureIgnore)).SetStatus(errors.New("unhealthy"))
+	h.NewApp("other")
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "app")
+	h.WaitForState("app", sysd.AppFailed)
+	if status := appStatus(t, h.Systemd, "app"); status.State != sysd.AppFailed {
+		t.Errorf("app is %s, want %s", status.State, sysd.AppFailed)
+	}
+	if err := h.Systemd.RestartApp(context.Background(), "app"); err != nil {
+		t.Fatalf("RestartApp of the failed app returned %v", err)
+	}
+}
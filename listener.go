@@ -0,0 +1,145 @@
+package sysd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ListenerOwner is an optional interface for apps owning listeners which are handed over to the
+// new process on Upgrade, the new process gets them back with InheritedListener
+type ListenerOwner interface {
+	// Listeners returns the listeners of the app by name, names must be unique across apps
+	// and must not contain commas
+	Listeners() map[string]net.Listener
+}
+
+// ListenerProvider hands pre-opened listeners to apps by name
+type ListenerProvider interface {
+	// Listener returns the listener with the given name, each listener can be taken once
+	Listener(name string) (net.Listener, bool)
+}
+
+// ListenerProviderFunc is a function implementing ListenerProvider
+type ListenerProviderFunc func(name string) (net.Listener, bool)
+
+// Listener implements ListenerProvider
+func (f ListenerProviderFunc) Listener(name string) (net.Listener, bool) {
+	return f(name)
+}
+
+// InheritedListeners is the default ListenerProvider, handing out listeners of the previous process
+// on Upgrade and listeners passed by systemd socket activation, see InheritedListener
+var InheritedListeners ListenerProvider = ListenerProviderFunc(InheritedListener)
+
+type listenerProviderKey struct{}
+
+// SetListenerProvider sets the provider apps get their listeners from with Listen,
+// nil restores InheritedListeners
+func (s *Systemd) SetListenerProvider(p ListenerProvider) {
+	s.listenerProvider = p
+}
+
+// listenerContext returns a context carrying the listener provider of the systemd service
+func (s *Systemd) listenerContext(ctx context.Context) context.Context {
+	p := s.listenerProvider
+	if p == nil {
+		p = InheritedListeners
+	}
+	return context.WithValue(ctx, listenerProviderKey{}, p)
+}
+
+// Listen returns the listener with the given name from the listener provider of the systemd service
+// starting the app, or listens on the network address if there is none. it should be called with the
+// context passed to the app Start, so apps bind instantly with socket activation and keep their
+// sockets open on Upgrade
+func Listen(ctx context.Context, name, network, address string) (net.Listener, error) {
+	p, ok := ctx.Value(listenerProviderKey{}).(ListenerProvider)
+	if !ok {
+		p = InheritedListeners
+	}
+	if l, ok := p.Listener(name); ok {
+		return l, nil
+	}
+	return net.Listen(network, address)
+}
+
+var (
+	inheritedOnce sync.Once
+	inheritedMu   sync.Mutex
+	inherited     map[string]net.Listener
+)
+
+// InheritedListener returns the listener with the given name handed over by the previous process
+// on Upgrade, or passed by systemd socket activation (named by FileDescriptorName, or fd:N without one).
+// each listener can be taken once, apps should fall back to net.Listen if there is none
+func InheritedListener(name string) (net.Listener, bool) {
+	inheritedOnce.Do(loadInheritedListeners)
+
+	inheritedMu.Lock()
+	defer inheritedMu.Unlock()
+
+	l, ok := inherited[name]
+	delete(inherited, name)
+	return l, ok
+}
+
+// loadInheritedListeners reads the listeners passed by Upgrade or socket activation from the environment
+func loadInheritedListeners() {
+	inherited = make(map[string]net.Listener)
+
+	// the variables must not leak into processes started by the apps
+	names := os.Getenv(upgradeFdsEnv)
+	_ = os.Unsetenv(upgradeFdsEnv)
+	if names != "" {
+		addInheritedListeners(strings.Split(names, ","))
+		return
+	}
+
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	fdNames := os.Getenv("LISTEN_FDNAMES")
+	for _, env := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(env)
+	}
+	// the fds are meant for this process only, not for one started by it
+	if pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n <= 0 {
+		return
+	}
+
+	activated := make([]string, n)
+	given := strings.Split(fdNames, ":")
+	for i := range activated {
+		if i < len(given) && given[i] != "" && given[i] != "unknown" {
+			activated[i] = given[i]
+		} else {
+			activated[i] = "fd:" + strconv.Itoa(3+i)
+		}
+	}
+	addInheritedListeners(activated)
+}
+
+// addInheritedListeners adds the listeners on the fds starting at 3 by name, fds which are
+// not listening sockets are skipped
+func addInheritedListeners(names []string) {
+	for i, name := range names {
+		f := os.NewFile(uintptr(3+i), name)
+		l, err := net.FileListener(f)
+		// the listener holds its own copy of the fd
+		_ = f.Close()
+		if err != nil {
+			continue
+		}
+		if _, ok := inherited[name]; ok {
+			_ = l.Close()
+			continue
+		}
+		inherited[name] = l
+	}
+}
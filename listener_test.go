@@ -0,0 +1,89 @@
+package sysd_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestListenUsesListenerProvider(t *testing.T) {
+	provided, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provided.Close()
+
+	h := sysdtest.NewHarness(t)
+	h.Systemd.SetListenerProvider(sysd.ListenerProviderFunc(func(name string) (net.Listener, bool) {
+		return provided, name == "http"
+	}))
+	got := make(chan net.Addr, 2)
+	err = h.Systemd.Add(sysd.AppFunc("http", func(ctx context.Context) error {
+		for _, name := range []string{"http", "other"} {
+			l, err := sysd.Listen(ctx, name, "tcp", "127.0.0.1:0")
+			if err != nil {
+				return err
+			}
+			got <- l.Addr()
+			if l != provided {
+				_ = l.Close()
+			}
+		}
+		<-ctx.Done()
+		return nil
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+
+	if addr := <-got; addr.String() != provided.Addr().String() {
+		t.Errorf("provided listener is on %s, want %s", addr, provided.Addr())
+	}
+	if addr := <-got; addr.String() == provided.Addr().String() {
+		t.Error("listener missing from the provider is the provided one, want a new one")
+	}
+}
+
+// TestSocketActivation checks in a child process that listeners passed by systemd socket activation
+// are handed out by name, the child gets LISTEN_PID set to its own pid by the shell it is exec'ed from
+func TestSocketActivation(t *testing.T) {
+	if addr := os.Getenv("SYSD_TEST_ACTIVATED_ADDR"); addr != "" {
+		l, ok := sysd.InheritedListener("http")
+		switch {
+		case !ok:
+			os.Exit(2)
+		case l.Addr().String() != addr:
+			os.Exit(3)
+		case os.Getenv("LISTEN_FDS") != "":
+			os.Exit(4)
+		}
+		if _, ok := sysd.InheritedListener("http"); ok {
+			os.Exit(5)
+		}
+		os.Exit(0)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cmd := exec.Command("/bin/sh", "-c", `LISTEN_PID=$$ exec "$0" -test.run='^TestSocketActivation$'`, os.Args[0])
+	cmd.Env = append(os.Environ(), "LISTEN_FDS=1", "LISTEN_FDNAMES=http", "SYSD_TEST_ACTIVATED_ADDR="+l.Addr().String())
+	cmd.ExtraFiles = []*os.File{f}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("child failed with %v: %s", err, out)
+	}
+}
@@ -0,0 +1,29 @@
+package sysd
+
+import "time"
+
+// aliveTicks is the number of status watcher ticks which may be missed before the
+// systemd service is not alive anymore
+const aliveTicks = 3
+
+// Alive returns true while the systemd service is running and its status watcher keeps ticking.
+// a watcher which missed a few ticks, e.g. because it is deadlocked or starved, is not alive
+func (s *Systemd) Alive() bool {
+	last := s.watcherTick.Load()
+	if last == 0 {
+		return false
+	}
+	interval := time.Duration(s.watcherInterval.Load())
+	return s.clock.Now().Sub(time.Unix(0, last)) <= aliveTicks*interval
+}
+
+// markAlive records a tick of the status watcher running at the given interval,
+// a zero interval marks the watcher stopped
+func (s *Systemd) markAlive(now time.Time, interval time.Duration) {
+	if interval == 0 {
+		s.watcherTick.Store(0)
+		return
+	}
+	s.watcherInterval.Store(int64(interval))
+	s.watcherTick.Store(now.UnixNano())
+}
@@ -1,29 +1,218 @@
 package sysd
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 )
 
-// Logger is the interface that wraps the basic logging methods
+// logBufferSize is the number of messages a log subscriber can fall behind before messages are dropped
+const logBufferSize = 256
+
+// Logger is the interface that wraps the basic logging methods. it is kept for loggers
+// which are not slog based, see SetSlog for structured logging
 type Logger interface {
 	Println(v ...any)
 }
 
+// logger writes to a slog logger if set, or to a Logger otherwise
 type logger struct {
-	l Logger
+	out *logOutput
+	// attrs are the structured fields added to every message, ignored by a Logger
+	attrs []any
+	// app is the app logging, its name prefixes the messages written to a Logger
+	app string
+}
+
+// logOutput is where a logger and the loggers derived from it write to, it can be changed
+// while apps are logging
+type logOutput struct {
+	mu          sync.RWMutex
+	l           Logger
+	slog        *slog.Logger
+	level       slog.Level
+	subscribers []chan LogRecord
+}
+
+func newLogger(l Logger) *logger {
+	return &logger{out: &logOutput{l: l}}
+}
+
+// with returns a logger adding the key value pairs as structured fields to its messages
+func (l *logger) with(args ...any) *logger {
+	return &logger{out: l.out, attrs: append(l.attrs[:len(l.attrs):len(l.attrs)], args...), app: l.app}
+}
+
+// forApp returns a logger for the messages of the app
+func (l *logger) forApp(name string) *logger {
+	app := l.with("app", name)
+	app.app = name
+	return app
+}
+
+// setLogger, setSlog and setLevel change the output of the logger and the loggers derived from it
+func (l *logger) setLogger(out Logger) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.l = out
+}
+
+func (l *logger) setSlog(out *slog.Logger) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.slog = out
+}
+
+func (l *logger) setLevel(level slog.Level) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.level = level
+}
+
+func (l *logger) log(level slog.Level, format string, args ...any) {
+	l.out.mu.RLock()
+	out, sl, minLevel := l.out.l, l.out.slog, l.out.level
+	if level < minLevel {
+		l.out.mu.RUnlock()
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	l.publish(level, msg)
+	l.out.mu.RUnlock()
+
+	if sl != nil {
+		sl.Log(context.Background(), level, msg, l.attrs...)
+		return
+	}
+	if l.app != "" {
+		msg = "[" + l.app + "] " + msg
+	}
+	out.Println(level.String(), msg)
+}
+
+// publish sends the message to the log subscribers without blocking, l.out.mu must be held
+func (l *logger) publish(level slog.Level, msg string) {
+	r := LogRecord{Time: time.Now(), Level: level, App: l.app, Message: msg}
+	for _, sub := range l.out.subscribers {
+		select {
+		case sub <- r:
+		default:
+		}
+	}
 }
 
 // Info logs an info message
 func (l *logger) Info(format string, args ...any) {
-	l.l.Println("INFO", fmt.Sprintf(format, args...))
+	l.log(slog.LevelInfo, format, args...)
 }
 
 // Error logs an error message
 func (l *logger) Error(format string, args ...any) {
-	l.l.Println("ERROR", fmt.Sprintf(format, args...))
+	l.log(slog.LevelError, format, args...)
 }
 
 // Warn logs a warning message
 func (l *logger) Warn(format string, args ...any) {
-	l.l.Println("WARN", fmt.Sprintf(format, args...))
+	l.log(slog.LevelWarn, format, args...)
+}
+
+// SetSlog makes the systemd service log to the slog logger, with the app, state, attempt and
+// error of a message as structured fields. nil goes back to the Logger set with SetLogger
+func (s *Systemd) SetSlog(l *slog.Logger) {
+	s.logger.setSlog(l)
+}
+
+// SetLogLevel sets the minimum level of the messages logged, info by default
+func (s *Systemd) SetLogLevel(level slog.Level) {
+	s.logger.setLevel(level)
+}
+
+// LogRecord is a message logged by the systemd service, or by an app with LoggerFrom
+type LogRecord struct {
+	Time  time.Time
+	Level slog.Level
+	// App is the name of the app which logged the message, empty for messages of the systemd service
+	App     string
+	Message string
+}
+
+// SubscribeLogs returns a channel receiving the logged messages at or above the log level.
+// messages are dropped for subscribers falling behind, see UnsubscribeLogs
+func (s *Systemd) SubscribeLogs() <-chan LogRecord {
+	s.logger.out.mu.Lock()
+	defer s.logger.out.mu.Unlock()
+
+	c := make(chan LogRecord, logBufferSize)
+	s.logger.out.subscribers = append(s.logger.out.subscribers, c)
+	return c
+}
+
+// UnsubscribeLogs stops sending messages to the channel returned by SubscribeLogs and closes it
+func (s *Systemd) UnsubscribeLogs(c <-chan LogRecord) {
+	s.logger.out.mu.Lock()
+	defer s.logger.out.mu.Unlock()
+
+	for i, sub := range s.logger.out.subscribers {
+		if sub == c {
+			s.logger.out.subscribers = append(s.logger.out.subscribers[:i], s.logger.out.subscribers[i+1:]...)
+			close(sub)
+			return
+		}
+	}
+}
+
+// AppLogger logs the messages of an app prefixed with its name, or with the app as a structured
+// field when logging to slog, see LoggerFrom. it implements Logger, so it can be handed to
+// libraries taking one
+type AppLogger struct {
+	l *logger
+}
+
+type loggerKey struct{}
+
+// appContext returns a context carrying the name of the app and the logger of the systemd service
+func (s *Systemd) appContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(appNameContext(ctx, name), loggerKey{}, s.logger)
+}
+
+// LoggerFrom returns a logger writing to the logger of the systemd service with the name of the app
+// as prefix. it should be called with the context passed to the app Start or Status, with other
+// contexts it logs to the standard logger
+func LoggerFrom(ctx context.Context) *AppLogger {
+	l, ok := ctx.Value(loggerKey{}).(*logger)
+	if !ok {
+		l = newLogger(log.Default())
+	}
+	if name := AppName(ctx); name != "" {
+		l = l.forApp(name)
+	}
+	return &AppLogger{l: l}
+}
+
+func (l *AppLogger) log(level slog.Level, format string, args ...any) {
+	l.l.log(level, format, args...)
+}
+
+// Info logs an info message
+func (l *AppLogger) Info(format string, args ...any) {
+	l.log(slog.LevelInfo, format, args...)
+}
+
+// Error logs an error message
+func (l *AppLogger) Error(format string, args ...any) {
+	l.log(slog.LevelError, format, args...)
+}
+
+// Warn logs a warning message
+func (l *AppLogger) Warn(format string, args ...any) {
+	l.log(slog.LevelWarn, format, args...)
+}
+
+// Println logs the values at info level
+func (l *AppLogger) Println(v ...any) {
+	l.log(slog.LevelInfo, "%s", strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
 }
@@ -0,0 +1,153 @@
+package sysd_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLoggerChangedWhileAppsLog(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	logged := make(chan struct{})
+	err := h.Systemd.Add(sysd.AppFunc("app", func(ctx context.Context) error {
+		logger := sysd.LoggerFrom(ctx)
+		for i := 0; ; i++ {
+			logger.Info("message %d", i)
+			if i == 100 {
+				close(logged)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	<-logged
+
+	var out syncBuffer
+	h.Systemd.SetLogLevel(slog.LevelWarn)
+	h.Systemd.SetSlog(slog.New(slog.NewTextHandler(&out, nil)))
+	h.Systemd.SetLogLevel(slog.LevelInfo)
+	// the running app logs to the new output
+	waitLogged(t, &out, "app=app")
+
+	var plain syncBuffer
+	h.Systemd.SetLogger(log.New(&plain, "", 0))
+	h.Systemd.SetSlog(nil)
+	waitLogged(t, &plain, "[app] message")
+}
+
+// waitLogged waits until the output contains s, failing the test otherwise
+func waitLogged(t *testing.T, out *syncBuffer, s string) {
+	t.Helper()
+
+	deadline := time.Now().Add(sysdtest.WaitTimeout)
+	for !strings.Contains(out.String(), s) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%q not logged", s)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLoggerFromPrefixesAppName(t *testing.T) {
+	var out syncBuffer
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	h.Systemd.SetLogger(log.New(&out, "", 0))
+	err := h.Systemd.Add(sysd.AppFunc("cache", func(ctx context.Context) error {
+		sysd.LoggerFrom(ctx).Info("warming up")
+		<-ctx.Done()
+		return nil
+	}, func(ctx context.Context) error {
+		sysd.LoggerFrom(ctx).Warn("evicting")
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	waitLogged(t, &out, "INFO [cache] warming up")
+
+	// status checks get the app logger too
+	h.Clock.Advance(time.Second)
+	waitLogged(t, &out, "WARN [cache] evicting")
+}
+
+func TestLoggerFromOutsideApp(t *testing.T) {
+	var out syncBuffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&out)
+
+	sysd.LoggerFrom(context.Background()).Info("no app")
+	if logged := out.String(); !strings.Contains(logged, "no app") || strings.Contains(logged, "[") {
+		t.Errorf("logged %q, want the message without an app prefix", logged)
+	}
+}
+
+func TestSubscribeLogs(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	logs := h.Systemd.SubscribeLogs()
+	err := h.Systemd.Add(sysd.AppFunc("cache", func(ctx context.Context) error {
+		sysd.LoggerFrom(ctx).Info("warming up")
+		sysd.LoggerFrom(ctx).Warn("evicting")
+		<-ctx.Done()
+		return nil
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Systemd.SetLogLevel(slog.LevelWarn)
+	h.Start()
+
+	// the info message is below the level, only the warning is sent
+	deadline := time.After(sysdtest.WaitTimeout)
+	for {
+		select {
+		case r := <-logs:
+			if r.App != "cache" {
+				continue
+			}
+			if r.Message != "evicting" || r.Level != slog.LevelWarn {
+				t.Fatalf("received %+v, want the warning of the app without a prefix", r)
+			}
+			h.Systemd.UnsubscribeLogs(logs)
+			// the channel is closed once the messages sent before are drained
+			for range logs {
+			}
+			return
+		case <-deadline:
+			t.Fatal("message of the app not received")
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package sysd
+
+import "time"
+
+// maintenance is a maintenance window, with no end if until is zero
+type maintenance struct {
+	active bool
+	until  time.Time
+}
+
+// on returns true if the maintenance window is open at the given time
+func (m maintenance) on(now time.Time) bool {
+	return m.active && (m.until.IsZero() || now.Before(m.until))
+}
+
+func newMaintenance(now time.Time, d time.Duration) maintenance {
+	m := maintenance{active: true}
+	if d > 0 {
+		m.until = now.Add(d)
+	}
+	return m
+}
+
+// EnterMaintenance puts all apps in maintenance: failed status checks are logged but no restarts
+// or shutdowns are triggered. the maintenance ends after d, or with ExitMaintenance if d is zero
+func (s *Systemd) EnterMaintenance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maintenance = newMaintenance(s.clock.Now(), d)
+	s.logger.Warn("Entering maintenance mode")
+}
+
+// ExitMaintenance ends the maintenance started with EnterMaintenance
+func (s *Systemd) ExitMaintenance() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maintenance = maintenance{}
+	s.logger.Info("Exiting maintenance mode")
+}
+
+// InMaintenance returns true while all apps are in maintenance
+func (s *Systemd) InMaintenance() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maintenance.on(s.clock.Now())
+}
+
+// EnterAppMaintenance puts a specific app in maintenance, see EnterMaintenance
+func (s *Systemd) EnterAppMaintenance(appName string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app, ok := s.apps[appName]; ok {
+		app.maintenance = newMaintenance(s.clock.Now(), d)
+		s.logger.Warn("Entering maintenance mode for app %q", appName)
+		return nil
+	}
+
+	return ErrAppNotExists
+}
+
+// ExitAppMaintenance ends the maintenance of a specific app
+func (s *Systemd) ExitAppMaintenance(appName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app, ok := s.apps[appName]; ok {
+		app.maintenance = maintenance{}
+		s.logger.Info("Exiting maintenance mode for app %q", appName)
+		return nil
+	}
+
+	return ErrAppNotExists
+}
+
+// inMaintenance returns true if the app, or all apps, are in maintenance
+func (s *Systemd) inMaintenance(app *appItem) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.clock.Now()
+	return s.maintenance.on(now) || app.maintenance.on(now)
+}
@@ -0,0 +1,58 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestAppMaintenanceSuppressesRestarts(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	app := h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureRestart.RetryTimeout(time.Second)))
+	if err := h.Systemd.EnterAppMaintenance("missing", 0); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Errorf("EnterAppMaintenance of a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	if err := h.Systemd.EnterAppMaintenance("app", 0); err != nil {
+		t.Fatal(err)
+	}
+	app.SetStatus(errors.New("dependency down"))
+	for i := 0; i < 30; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	if app.StatusChecks() == 0 {
+		t.Fatal("app not status checked in maintenance")
+	}
+	if n := app.Starts(); n != 1 {
+		t.Fatalf("app in maintenance started %d times, want once", n)
+	}
+
+	if err := h.Systemd.ExitAppMaintenance("app"); err != nil {
+		t.Fatal(err)
+	}
+	advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "app")
+}
+
+func TestMaintenanceWindowExpires(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	app := h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureRestart.RetryTimeout(time.Second)))
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	begin := h.Clock.Now()
+	h.Systemd.EnterMaintenance(time.Minute)
+	app.SetStatus(errors.New("dependency down"))
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "app")
+	if since := e.Time.Sub(begin); since < time.Minute {
+		t.Errorf("app restarted %s into a maintenance window of 1m", since)
+	}
+}
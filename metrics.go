@@ -0,0 +1,69 @@
+package sysd
+
+import "time"
+
+// AppMetrics is the metrics collected for an app by the systemd service
+type AppMetrics struct {
+	Name     string
+	State    AppState
+	Restarts int
+	// StatusChecks and StatusCheckFailures count the finished status checks, StatusCheckDuration
+	// is the total time spent in them
+	StatusChecks        int
+	StatusCheckFailures int
+	StatusCheckDuration time.Duration
+}
+
+// Metrics is a point in time view of the metrics collected by the systemd service
+type Metrics struct {
+	Apps []AppMetrics
+	// Shutdowns counts the finished shutdowns, ShutdownDuration is the total time spent in them
+	Shutdowns        int
+	ShutdownDuration time.Duration
+}
+
+// Metrics returns the metrics of the systemd service and its apps, apps are sorted by priority then name
+func (s *Systemd) Metrics() Metrics {
+	apps := s.appList()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := Metrics{
+		Apps:             make([]AppMetrics, 0, len(apps)),
+		Shutdowns:        s.shutdowns,
+		ShutdownDuration: s.shutdownDuration,
+	}
+	for _, app := range apps {
+		m.Apps = append(m.Apps, AppMetrics{
+			Name:                app.name,
+			State:               app.state,
+			Restarts:            app.restarts,
+			StatusChecks:        app.statusChecks,
+			StatusCheckFailures: app.statusCheckFailures,
+			StatusCheckDuration: app.statusCheckDuration,
+		})
+	}
+	return m
+}
+
+// recordStatusCheck records a finished status check of the app
+func (s *Systemd) recordStatusCheck(app *appItem, took time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app.statusChecks++
+	app.statusCheckDuration += took
+	if HealthOf(err) == HealthUnhealthy {
+		app.statusCheckFailures++
+	}
+}
+
+// recordShutdown records a finished shutdown of the systemd service
+func (s *Systemd) recordShutdown(took time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdowns++
+	s.shutdownDuration += took
+}
@@ -0,0 +1,159 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStatusTimeout is returned when a status check does not finish in time
+var ErrStatusTimeout = errors.New("status check timed out")
+
+// StatusFunc is a function that checks the status of an app
+type StatusFunc func(ctx context.Context) error
+
+// StatusMiddleware wraps a StatusFunc to add behavior around every status check
+type StatusMiddleware func(next StatusFunc) StatusFunc
+
+// chainStatus wraps the status func with middlewares, the first middleware is the outermost
+func chainStatus(status StatusFunc, middlewares []StatusMiddleware) StatusFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		status = middlewares[i](status)
+	}
+	return status
+}
+
+// StatusTimeout returns a middleware that fails the status check if it does not
+// return within the given timeout. a panic of the status check is returned as a PanicError
+func StatusTimeout(timeout time.Duration) StatusMiddleware {
+	return statusTimeout(RealClock, timeout)
+}
+
+// statusTimeout is StatusTimeout measuring the timeout with the given clock
+func statusTimeout(clock Clock, timeout time.Duration) StatusMiddleware {
+	return func(next StatusFunc) StatusFunc {
+		return func(parent context.Context) error {
+			ctx, cancel := withClockTimeout(parent, clock, timeout)
+			defer cancel()
+
+			// the check runs in its own goroutine, a panic must not crash the process
+			errs := make(chan error, 1)
+			go func() {
+				errs <- recovered(func() error { return next(ctx) })
+			}()
+
+			select {
+			case err := <-errs:
+				return err
+			case <-ctx.Done():
+				if err := parent.Err(); err != nil {
+					return err
+				}
+				return fmt.Errorf("%w after %s", ErrStatusTimeout, timeout)
+			}
+		}
+	}
+}
+
+// StatusLogging returns a middleware that logs the result and duration of every status check
+func StatusLogging(l Logger) StatusMiddleware {
+	lg := newLogger(l)
+	return func(next StatusFunc) StatusFunc {
+		return func(ctx context.Context) error {
+			start := time.Now()
+			err := next(ctx)
+			if err != nil {
+				lg.Error("app %q status check failed after %s: %v", AppName(ctx), time.Since(start), err)
+				return err
+			}
+			lg.Info("app %q status check passed in %s", AppName(ctx), time.Since(start))
+			return nil
+		}
+	}
+}
+
+// StartFunc is a function that runs an app, like App.Start
+type StartFunc func(ctx context.Context) error
+
+// StartMiddleware wraps a StartFunc to add behavior around every start of an app
+type StartMiddleware func(next StartFunc) StartFunc
+
+// AppMiddleware wraps the Start and Status calls of every app, either of them may be nil
+type AppMiddleware struct {
+	Start  StartMiddleware
+	Status StatusMiddleware
+}
+
+// chainStart wraps the start func with middlewares, the first middleware is the outermost
+func chainStart(start StartFunc, middlewares []StartMiddleware) StartFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		start = middlewares[i](start)
+	}
+	return start
+}
+
+// Use registers middlewares applied around every app Start and Status call, middlewares
+// are applied in the order they are registered, the first one being the outermost.
+// the app name is available in the context with AppName
+func (s *Systemd) Use(middlewares ...AppMiddleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, mw := range middlewares {
+		if mw.Start != nil {
+			s.startMiddlewares = append(s.startMiddlewares, mw.Start)
+		}
+		if mw.Status != nil {
+			s.statusMiddlewares = append(s.statusMiddlewares, mw.Status)
+		}
+	}
+}
+
+// LoggingMiddleware returns a middleware that logs every start of an app, how long it ran and why it
+// returned, and the result of every status check, see StatusLogging
+func LoggingMiddleware(l Logger) AppMiddleware {
+	lg := newLogger(l)
+	return AppMiddleware{
+		Start: func(next StartFunc) StartFunc {
+			return func(ctx context.Context) error {
+				start := time.Now()
+				lg.Info("app %q starting", AppName(ctx))
+				err := next(ctx)
+				if err != nil {
+					lg.Error("app %q returned after %s: %v", AppName(ctx), time.Since(start), err)
+					return err
+				}
+				lg.Info("app %q returned after %s", AppName(ctx), time.Since(start))
+				return nil
+			}
+		},
+		Status: StatusLogging(l),
+	}
+}
+
+// MetricsRecorder records how long app operations took and how they ended,
+// op is OperationStart for a run of the app and OperationStatusCheck for a status check
+type MetricsRecorder interface {
+	Observe(app string, op Operation, took time.Duration, err error)
+}
+
+// MetricsMiddleware returns a middleware that records every run and status check of the apps
+func MetricsMiddleware(r MetricsRecorder) AppMiddleware {
+	observe := func(op Operation, next func(ctx context.Context) error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			start := time.Now()
+			err := next(ctx)
+			r.Observe(AppName(ctx), op, time.Since(start), err)
+			return err
+		}
+	}
+	return AppMiddleware{
+		Start: func(next StartFunc) StartFunc {
+			return observe(OperationStart, next)
+		},
+		Status: func(next StatusFunc) StatusFunc {
+			return observe(OperationStatusCheck, next)
+		},
+	}
+}
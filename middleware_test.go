@@ -0,0 +1,252 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStatusTimeout(t *testing.T) {
+	errUnhealthy := errors.New("unhealthy")
+	tests := []struct {
+		name   string
+		status sysd.StatusFunc
+		cancel bool
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name:   "passes the result through",
+			status: func(context.Context) error { return errUnhealthy },
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, errUnhealthy) {
+					t.Fatalf("got %v, want %v", err, errUnhealthy)
+				}
+			},
+		},
+		{
+			name: "times out",
+			status: func(ctx context.Context) error {
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			},
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, sysd.ErrStatusTimeout) {
+					t.Fatalf("got %v, want ErrStatusTimeout", err)
+				}
+			},
+		},
+		{
+			name: "parent cancelled",
+			status: func(ctx context.Context) error {
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			},
+			cancel: true,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, context.Canceled) {
+					t.Fatalf("got %v, want context.Canceled", err)
+				}
+			},
+		},
+		{
+			name:   "recovers a panic",
+			status: func(context.Context) error { panic("boom") },
+			check: func(t *testing.T, err error) {
+				var panicErr *sysd.PanicError
+				if !errors.As(err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+					t.Fatalf("got %v, want a PanicError with a stack", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+			timeout := 20 * time.Millisecond
+			if tt.cancel {
+				timeout = time.Minute
+			}
+			tt.check(t, sysd.StatusTimeout(timeout)(tt.status)(ctx))
+		})
+	}
+}
+
+// panickingApp panics in its status check
+type panickingApp struct {
+	*sysdtest.FakeApp
+}
+
+func (a *panickingApp) Status(context.Context) error {
+	panic("boom")
+}
+
+func TestStatusPanicWithTimeoutIsRecovered(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithPanicPolicy(sysd.IgnorePanic))
+	h.Systemd.SetStatusCheckTimeout(time.Second)
+	app := &panickingApp{FakeApp: sysdtest.NewFakeApp("app")}
+	if err := h.Systemd.Add(app); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	h.Clock.Advance(sysd.StatusCheckInterval)
+	e := h.WaitForEvent(sysd.EventAppFailed, "app")
+	var panicErr *sysd.PanicError
+	if !errors.As(e.Err, &panicErr) || len(e.Stack) == 0 {
+		t.Fatalf("failure is %v, want a PanicError with a stack", e.Err)
+	}
+	h.WaitForState("app", sysd.AppFailed)
+}
+
+func TestStatusMiddlewareOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) sysd.StatusMiddleware {
+		return func(next sysd.StatusFunc) sysd.StatusFunc {
+			return func(ctx context.Context) error {
+				calls = append(calls, name+" before")
+				err := next(ctx)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+
+	h := sysdtest.NewHarness(t)
+	h.Systemd.UseStatusMiddleware(record("first"), record("second"))
+	h.Systemd.Use(sysd.AppMiddleware{Status: record("third")})
+	app := h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	app.FlapStatus(errors.New("unhealthy"))
+	h.Clock.Advance(sysd.StatusCheckInterval)
+	h.WaitForEvent(sysd.EventAppFailed, "app")
+	h.Stop()
+
+	want := []string{"first before", "second before", "third before", "third after", "second after", "first after"}
+	if len(calls) < len(want) {
+		t.Fatalf("calls are %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("calls are %v, want %v", calls[:len(want)], want)
+		}
+	}
+	if app.StatusChecks() == 0 {
+		t.Fatal("the middlewares did not call the app status")
+	}
+}
+
+func TestStatusMiddlewareWrapsStatus(t *testing.T) {
+	errReplaced := errors.New("replaced")
+	h := sysdtest.NewHarness(t)
+	h.Systemd.UseStatusMiddleware(func(next sysd.StatusFunc) sysd.StatusFunc {
+		return func(ctx context.Context) error {
+			if err := next(ctx); err != nil {
+				return errReplaced
+			}
+			return nil
+		}
+	})
+	app := h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	app.FlapStatus(errors.New("unhealthy"))
+	h.Clock.Advance(sysd.StatusCheckInterval)
+	if e := h.WaitForEvent(sysd.EventAppFailed, "app"); !errors.Is(e.Err, errReplaced) {
+		t.Fatalf("failure is %v, want the middleware error", e.Err)
+	}
+}
+
+func TestAppMiddlewareWrapsStart(t *testing.T) {
+	rec := &recorder{}
+	record := func(name string) sysd.AppMiddleware {
+		return sysd.AppMiddleware{Start: func(next sysd.StartFunc) sysd.StartFunc {
+			return func(ctx context.Context) error {
+				rec.record(name + " before " + sysd.AppName(ctx))
+				err := next(ctx)
+				rec.record(name + " after " + sysd.AppName(ctx))
+				return err
+			}
+		}}
+	}
+
+	h := sysdtest.NewHarness(t)
+	h.Systemd.Use(record("first"), record("second"))
+	app := h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+
+	want := []string{"first before app", "second before app", "second after app", "first after app"}
+	if calls := rec.take(); fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Fatalf("calls are %v, want %v", calls, want)
+	}
+	if app.Starts() != 1 {
+		t.Fatalf("the middlewares started the app %d times, want 1", app.Starts())
+	}
+}
+
+// metricsRecorder is a sysd.MetricsRecorder keeping the observed operations
+type metricsRecorder struct {
+	rec recorder
+}
+
+func (r *metricsRecorder) Observe(app string, op sysd.Operation, _ time.Duration, err error) {
+	r.rec.record(fmt.Sprintf("%s %s %v", app, op, err))
+}
+
+func TestMetricsMiddlewareObservesStartsAndStatusChecks(t *testing.T) {
+	metrics := &metricsRecorder{}
+	h := sysdtest.NewHarness(t)
+	h.Systemd.Use(sysd.MetricsMiddleware(metrics))
+	app := h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	app.FlapStatus(errors.New("unhealthy"))
+	h.Clock.Advance(sysd.StatusCheckInterval)
+	h.WaitForEvent(sysd.EventAppFailed, "app")
+	h.Stop()
+
+	observed := strings.Join(metrics.rec.take(), "\n")
+	for _, want := range []string{
+		fmt.Sprintf("app %s unhealthy", sysd.OperationStatusCheck),
+		fmt.Sprintf("app %s", sysd.OperationStart),
+	} {
+		if !strings.Contains(observed, want) {
+			t.Errorf("%q not observed in:\n%s", want, observed)
+		}
+	}
+}
+
+func TestLoggingMiddlewareLogsRuns(t *testing.T) {
+	var out syncBuffer
+	h := sysdtest.NewHarness(t)
+	h.Systemd.Use(sysd.LoggingMiddleware(log.New(&out, "", 0)))
+	h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	waitLogged(t, &out, `app "app" starting`)
+
+	h.Stop()
+	waitLogged(t, &out, `app "app" returned after`)
+}
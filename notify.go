@@ -0,0 +1,83 @@
+package sysd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// notifySocketEnv is set by systemd for services with Type=notify
+const notifySocketEnv = "NOTIFY_SOCKET"
+
+// SdNotify sends the state to the service manager as described in sd_notify(3), e.g. "STATUS=serving".
+// it returns false without an error if the process is not running under a service manager
+func SdNotify(state string) (bool, error) {
+	name := os.Getenv(notifySocketEnv)
+	if name == "" {
+		return false, nil
+	}
+	// abstract sockets are prefixed with @ in the environment
+	if strings.HasPrefix(name, "@") {
+		name = "\x00" + name[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// notify sends the state to the service manager, logging failures. nested groups do not notify,
+// neither does the old process after Upgrade
+func (s *Systemd) notify(state string) {
+	if s.nested || s.upgraded.Load() {
+		return
+	}
+	if _, err := SdNotify(state); err != nil {
+		s.logger.Error("Failed to notify service manager with %q: %v", state, err)
+	}
+}
+
+// notifyWhenReady sends READY=1 to the service manager once all apps are ready,
+// and reports ready to the previous process on Upgrade
+func (s *Systemd) notifyWhenReady(ctx context.Context) {
+	if os.Getenv(notifySocketEnv) == "" && os.Getenv(upgradeReadyFdEnv) == "" {
+		return
+	}
+
+	ticker := s.clock.NewTicker(dependencyCheckInterval)
+	defer ticker.Stop()
+
+	for !s.Ready(ctx) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+	s.notify("READY=1")
+	if err := notifyUpgradeReady(); err != nil {
+		s.logger.Error("Failed to report ready to the upgrading process: %v", err)
+	}
+}
+
+// checkWatchdogInterval warns if the status check interval is too long for the watchdog
+// of the service manager, which expects WATCHDOG=1 at least every WatchdogSec
+func (s *Systemd) checkWatchdogInterval(tick time.Duration) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+	if watchdog := time.Duration(usec) * time.Microsecond; tick >= watchdog/2 {
+		s.logger.Warn("Status check interval %s is too long for the watchdog timeout %s", tick, watchdog)
+	}
+}
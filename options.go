@@ -0,0 +1,250 @@
+package sysd
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Option configures a Systemd created with New
+type Option func(s *Systemd)
+
+// WithGracefulShutdownTimeout sets the graceful shutdown timeout
+func WithGracefulShutdownTimeout(t time.Duration) Option {
+	return func(s *Systemd) {
+		s.graceFullShutdownTimeout = t
+	}
+}
+
+// WithStatusCheckInterval sets the status check interval
+func WithStatusCheckInterval(t time.Duration) Option {
+	return func(s *Systemd) {
+		s.statusCheckInterval = t
+	}
+}
+
+// WithStatusCheckConcurrency sets the maximum number of status checks running at the same time
+func WithStatusCheckConcurrency(n int) Option {
+	return func(s *Systemd) {
+		s.statusCheckConcurrency = n
+	}
+}
+
+// WithStatusCheckTimeout fails status checks which do not return in time,
+// for apps without their own status timeout, see SetStatusCheckTimeout
+func WithStatusCheckTimeout(t time.Duration) Option {
+	return func(s *Systemd) {
+		s.statusCheckTimeout = t
+	}
+}
+
+// WithStartFailurePolicy sets what Start does when an app fails to start, see SetStartFailurePolicy
+func WithStartFailurePolicy(policy StartFailurePolicy) Option {
+	return func(s *Systemd) {
+		s.startFailure = policy
+	}
+}
+
+// WithStagedStart makes priorities act as stages, see SetStagedStart
+func WithStagedStart(stageTimeout time.Duration) Option {
+	return func(s *Systemd) {
+		s.stagedStart = true
+		s.stageTimeout = stageTimeout
+	}
+}
+
+// WithStartConcurrency sets how many apps may be starting at the same time, see SetStartConcurrency
+func WithStartConcurrency(n int) Option {
+	return func(s *Systemd) {
+		s.startLimiter.limit = n
+	}
+}
+
+// WithPanicPolicy sets what happens when apps panic, see SetPanicPolicy
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(s *Systemd) {
+		s.panicPolicy = policy
+	}
+}
+
+// WithLogger sets the logger
+func WithLogger(l Logger) Option {
+	return func(s *Systemd) {
+		s.logger.setLogger(l)
+	}
+}
+
+// WithSlog makes the systemd service log to the slog logger, see SetSlog
+func WithSlog(l *slog.Logger) Option {
+	return func(s *Systemd) {
+		s.logger.setSlog(l)
+	}
+}
+
+// WithLogLevel sets the minimum level of the messages logged, see SetLogLevel
+func WithLogLevel(level slog.Level) Option {
+	return func(s *Systemd) {
+		s.logger.setLevel(level)
+	}
+}
+
+// WithAuditSink adds a sink the lifecycle events are written to, see AddAuditSink
+func WithAuditSink(sink AuditSink) Option {
+	return func(s *Systemd) {
+		s.audit.sinks = append(s.audit.sinks, sink)
+	}
+}
+
+// WithNotifyOnFailure adds a notifier alerted on failures, see NotifyOnFailure
+func WithNotifyOnFailure(n Notifier) Option {
+	return func(s *Systemd) {
+		s.alerts.notifiers = append(s.alerts.notifiers, n)
+	}
+}
+
+// WithAlertDebounce sets how long repeated alerts are held back, see SetAlertDebounce
+func WithAlertDebounce(d time.Duration) Option {
+	return func(s *Systemd) {
+		s.alerts.debounce = d
+	}
+}
+
+// WithClock sets the clock of the systemd service, see SetClock
+func WithClock(c Clock) Option {
+	return func(s *Systemd) {
+		s.clock = c
+	}
+}
+
+// WithStateStore persists the supervisor state to the store, see SetStateStore
+func WithStateStore(store StateStore) Option {
+	return func(s *Systemd) {
+		s.persist.store = store
+	}
+}
+
+// WithDefaultOnFailure sets the default on failure action of the apps
+func WithDefaultOnFailure(onFailure *OnFailure) Option {
+	return func(s *Systemd) {
+		s.defaultOnFailure = onFailure.clone()
+	}
+}
+
+// AddOption configures an app added with Add
+type AddOption func(app *appItem)
+
+// WithPriority sets the priority of the app, lower priorities start first
+func WithPriority(priority int) AddOption {
+	return func(app *appItem) {
+		app.priority = priority
+	}
+}
+
+// WithOnFailure sets the on failure action of the app
+func WithOnFailure(onFailure *OnFailure) AddOption {
+	return func(app *appItem) {
+		app.onFailure = onFailure.clone()
+	}
+}
+
+// WithRestartPolicy restarts the app as long as and as late as the policy says, see OnFailurePolicy
+func WithRestartPolicy(policy RestartPolicy) AddOption {
+	return func(app *appItem) {
+		app.onFailure = OnFailurePolicy(policy)
+	}
+}
+
+// WithDependsOn sets the apps the app depends on, see SetAppDependencies.
+// dependencies must be added first, Add fails on unknown dependencies and cycles
+func WithDependsOn(deps ...string) AddOption {
+	return func(app *appItem) {
+		app.dependsOn = append([]string(nil), deps...)
+	}
+}
+
+// WithShutdownTimeout sets how long the app is given to stop, see SetAppShutdownTimeout
+func WithShutdownTimeout(timeout time.Duration) AddOption {
+	return func(app *appItem) {
+		app.shutdownTimeout = timeout
+	}
+}
+
+// WithIdleTimeout sets how long the app may be idle, see SetAppIdleTimeout
+func WithIdleTimeout(timeout time.Duration) AddOption {
+	return func(app *appItem) {
+		app.idleTimeout = timeout
+	}
+}
+
+// WithStatusInterval sets how often the status of the app is checked,
+// instead of the status check interval of the systemd service
+func WithStatusInterval(interval time.Duration) AddOption {
+	return func(app *appItem) {
+		app.statusInterval = interval
+	}
+}
+
+// WithStatusTimeout fails the status check of the app if it does not return in time,
+// instead of the status check timeout of the systemd service
+func WithStatusTimeout(timeout time.Duration) AddOption {
+	return func(app *appItem) {
+		app.statusTimeout = timeout
+	}
+}
+
+// WithStartupGrace sets how long after a (re)start the app is not status checked, see SetAppStartupGrace
+func WithStartupGrace(grace time.Duration) AddOption {
+	return func(app *appItem) {
+		app.startupGrace = grace
+	}
+}
+
+// WithHeartbeatTimeout fails the status check of the app if it does not call Heartbeat
+// within the timeout, see SetAppHeartbeatTimeout
+func WithHeartbeatTimeout(timeout time.Duration) AddOption {
+	return func(app *appItem) {
+		app.heartbeatTimeout = timeout
+	}
+}
+
+// WithTracer sets the tracer of lifecycle operations, see SetTracer
+func WithTracer(t Tracer) Option {
+	return func(s *Systemd) {
+		s.tracer = t
+	}
+}
+
+// WithListenerProvider sets the provider apps get their listeners from, see SetListenerProvider
+func WithListenerProvider(p ListenerProvider) Option {
+	return func(s *Systemd) {
+		s.listenerProvider = p
+	}
+}
+
+// WithReloadSignals sets the signals which reload the apps, see SetReloadSignals
+func WithReloadSignals(sig ...os.Signal) Option {
+	return func(s *Systemd) {
+		s.reloadSignals = append([]os.Signal{}, sig...)
+	}
+}
+
+// WithFinalizer registers a finalizer which runs once all apps have stopped, see AddFinalizer
+func WithFinalizer(name string, fn Finalizer) Option {
+	return func(s *Systemd) {
+		s.finalizers = append(s.finalizers, finalizer{name: name, fn: fn})
+	}
+}
+
+// WithStartTimeout fails a start of the app which is not ready within the timeout, see SetAppStartTimeout
+func WithStartTimeout(timeout time.Duration) AddOption {
+	return func(app *appItem) {
+		app.startTimeout = timeout
+	}
+}
+
+// WithAppPanicPolicy sets what happens when the app panics, see SetAppPanicPolicy
+func WithAppPanicPolicy(policy PanicPolicy) AddOption {
+	return func(app *appItem) {
+		app.panicPolicy = &policy
+	}
+}
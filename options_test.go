@@ -0,0 +1,67 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestWithGracefulShutdownTimeout(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithGracefulShutdownTimeout(time.Second))
+	slow := h.NewApp("slow").SetStopDelay(time.Hour)
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("slow", sysd.AppRunning)
+
+	begin := h.Clock.Now()
+	_ = h.Stop()
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventShutdownTimeout, "")
+	if waited := e.Time.Sub(begin); waited >= sysd.GracefulShutdownTimeout {
+		t.Errorf("shutdown timed out after %s, want the configured 1s", waited)
+	}
+
+	// let the app return so it does not outlive the test
+	h.Clock.Advance(time.Hour)
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); slow.Running() && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWithDependsOn(t *testing.T) {
+	s := sysd.New()
+	if err := s.Add(sysdtest.NewFakeApp("a"), sysd.WithDependsOn("b")); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Errorf("Add depending on a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+	if err := s.Add(sysdtest.NewFakeApp("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(sysdtest.NewFakeApp("a"), sysd.WithDependsOn("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetAppDependencies("b", "a"); !errors.Is(err, sysd.ErrDependencyCycle) {
+		t.Errorf("closing a dependency cycle returned %v, want %v", err, sysd.ErrDependencyCycle)
+	}
+}
+
+func TestWithDefaultOnFailureAndPriority(t *testing.T) {
+	s := sysd.New(sysd.WithDefaultOnFailure(sysd.OnFailureIgnore))
+	if err := s.Add(sysdtest.NewFakeApp("late"), sysd.WithPriority(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(sysdtest.NewFakeApp("early"), sysd.WithPriority(1), sysd.WithOnFailure(sysd.OnFailureShutdownAll)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := appNames(s); len(got) != 2 || got[0] != "early" || got[1] != "late" {
+		t.Errorf("apps are %q, want early before late", got)
+	}
+	if onFailure, _ := s.AppOnFailure("late"); !onFailure.Equal(sysd.OnFailureIgnore) {
+		t.Errorf("late app on failure is %s, want the default %s", onFailure, sysd.OnFailureIgnore)
+	}
+	if onFailure, _ := s.AppOnFailure("early"); !onFailure.Equal(sysd.OnFailureShutdownAll) {
+		t.Errorf("early app on failure is %s, want %s", onFailure, sysd.OnFailureShutdownAll)
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/mirzakhany/sysd/otel
+
+go 1.21.3
+
+require (
+	github.com/mirzakhany/sysd v0.1.2
+	go.opentelemetry.io/otel v1.19.0
+	go.opentelemetry.io/otel/sdk v1.19.0
+	go.opentelemetry.io/otel/trace v1.19.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.19.0 // indirect
+	golang.org/x/sys v0.12.0 // indirect
+)
+
+replace github.com/mirzakhany/sysd => ../
@@ -0,0 +1,46 @@
+package otel
+
+import (
+	"context"
+
+	"github.com/mirzakhany/sysd"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/mirzakhany/sysd"
+
+var _ sysd.Tracer = &Tracer{}
+
+// Tracer reports the lifecycle operations of a systemd service as OpenTelemetry spans
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a sysd tracer creating spans with the given tracer provider
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+// WithTracerProvider traces the lifecycle operations of the systemd service with the given tracer provider
+func WithTracerProvider(tp trace.TracerProvider) sysd.Option {
+	return sysd.WithTracer(NewTracer(tp))
+}
+
+// StartSpan implements sysd.Tracer, spans are named after the operation, e.g. sysd.start
+func (t *Tracer) StartSpan(ctx context.Context, op sysd.Operation, app string) (context.Context, func(err error)) {
+	var attrs []attribute.KeyValue
+	if app != "" {
+		attrs = append(attrs, attribute.String("sysd.app", app))
+	}
+
+	ctx, span := t.tracer.Start(ctx, "sysd."+string(op), trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,87 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	_, end := tracer.StartSpan(context.Background(), sysd.OperationStart, "api")
+	end(nil)
+	_, end = tracer.StartSpan(context.Background(), sysd.OperationShutdown, "")
+	end(errors.New("boom"))
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans ended, want 2", len(spans))
+	}
+
+	start := spans[0]
+	if start.Name() != "sysd.start" {
+		t.Errorf("span named %q, want sysd.start", start.Name())
+	}
+	if attrs := start.Attributes(); len(attrs) != 1 || attrs[0] != attribute.String("sysd.app", "api") {
+		t.Errorf("span has attributes %v, want the app name", attrs)
+	}
+	if start.Status().Code != codes.Unset {
+		t.Errorf("span has status %v, want it unset", start.Status())
+	}
+
+	shutdown := spans[1]
+	if shutdown.Name() != "sysd.shutdown" {
+		t.Errorf("span named %q, want sysd.shutdown", shutdown.Name())
+	}
+	if attrs := shutdown.Attributes(); len(attrs) != 0 {
+		t.Errorf("span of the whole service has attributes %v, want none", attrs)
+	}
+	if status := shutdown.Status(); status.Code != codes.Error || status.Description != "boom" {
+		t.Errorf("span has status %v, want the error", status)
+	}
+	if events := shutdown.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("span has events %v, want the recorded error", events)
+	}
+}
+
+func TestTracerProviderTracesLifecycle(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	h := sysdtest.NewHarness(t,
+		sysd.WithStatusCheckInterval(time.Second),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))),
+	)
+	app := h.NewApp("api").SetStatus(errors.New("unhealthy"))
+	h.Start()
+	h.WaitForState("api", sysd.AppRunning)
+	// the status check is traced in the background
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); app.StatusChecks() == 0 && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	ended := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range rec.Ended() {
+		ended[span.Name()] = span
+	}
+	for _, name := range []string{"sysd.start", "sysd.status_check", "sysd.shutdown"} {
+		if _, ok := ended[name]; !ok {
+			t.Errorf("no %s span ended, got %v", name, rec.Ended())
+		}
+	}
+	if check, ok := ended["sysd.status_check"]; ok && check.Status().Code != codes.Error {
+		t.Errorf("status check span has status %v, want the failed check", check.Status())
+	}
+}
@@ -0,0 +1,83 @@
+package sysd
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy represents what happens when an app panics in Start or Status
+type PanicPolicy int
+
+const (
+	// FailFastOnPanic marks the app as failed and shuts down all apps, Start returns the panic
+	FailFastOnPanic PanicPolicy = iota
+	// RestartOnPanic handles the panic like any other failure, following the app OnFailure policy
+	RestartOnPanic
+	// IgnorePanic leaves the app failed and stopped, the other apps keep running
+	IgnorePanic
+)
+
+// PanicError is the failure of an app which panicked, the stack is where the panic happened.
+// it is the Err of the AppFailed event, whose Stack is set too
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error implements error
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// newPanicError returns a PanicError for the recovered value, it must be called by the deferred
+// function which recovered so the stack still includes the panic
+func newPanicError(v any) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+// SetPanicPolicy sets what happens when apps without their own panic policy panic
+func (s *Systemd) SetPanicPolicy(policy PanicPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.panicPolicy = policy
+}
+
+// SetAppPanicPolicy sets what happens when a specific app panics
+func (s *Systemd) SetAppPanicPolicy(appName string, policy PanicPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app, ok := s.apps[appName]; ok {
+		app.panicPolicy = &policy
+		return nil
+	}
+
+	return ErrAppNotExists
+}
+
+// appPanicPolicy returns the panic policy of the app
+func (s *Systemd) appPanicPolicy(app *appItem) PanicPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if app.panicPolicy != nil {
+		return *app.panicPolicy
+	}
+	return s.panicPolicy
+}
+
+// recovered calls fn, turning a panic into a PanicError
+func recovered(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(r)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,108 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestPanicPolicies(t *testing.T) {
+	t.Run("fail fast", func(t *testing.T) {
+		h := sysdtest.NewHarness(t, sysd.WithPanicPolicy(sysd.FailFastOnPanic))
+		h.NewApp("app").PanicOnStart("boom")
+		h.Start()
+
+		e := h.WaitForEvent(sysd.EventAppFailed, "app")
+		if len(e.Stack) == 0 {
+			t.Fatal("the failed event has no stack")
+		}
+		var panicErr *sysd.PanicError
+		if err := h.Wait(); !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+			t.Fatalf("Start returned %v, want the PanicError", err)
+		}
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		h := sysdtest.NewHarness(t, sysd.WithPanicPolicy(sysd.RestartOnPanic))
+		app := h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureRestart.RetryTimeout(time.Second))).PanicOnStart("boom")
+		h.Start()
+
+		h.WaitForEvent(sysd.EventAppFailed, "app")
+		h.Clock.BlockUntil(2)
+		h.Clock.Advance(time.Second)
+		if !app.WaitRunning(sysdtest.WaitTimeout) {
+			t.Fatal("app was not restarted after panicking")
+		}
+		if app.Starts() != 2 {
+			t.Fatalf("app started %d times, want 2", app.Starts())
+		}
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		h := sysdtest.NewHarness(t, sysd.WithPanicPolicy(sysd.IgnorePanic))
+		h.NewApp("app").PanicOnStart("boom")
+		other := h.NewApp("other")
+		h.Start()
+
+		h.WaitForState("app", sysd.AppFailed)
+		if !other.WaitRunning(sysdtest.WaitTimeout) {
+			t.Fatal("other app is not running")
+		}
+		if h.Returned() {
+			t.Fatal("Start returned after an ignored panic")
+		}
+	})
+}
+
+// statusPanickingApp panics in its status check
+type statusPanickingApp struct {
+	*sysdtest.FakeApp
+}
+
+func (a *statusPanickingApp) Status(context.Context) error {
+	panic("status boom")
+}
+
+func TestAppPanicPolicyOverridesDefault(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithPanicPolicy(sysd.FailFastOnPanic))
+	h.NewApp("app", sysd.WithAppPanicPolicy(sysd.IgnorePanic)).PanicOnStart("boom")
+	other := h.NewApp("other")
+	h.Start()
+
+	h.WaitForState("app", sysd.AppFailed)
+	if !other.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("other app is not running")
+	}
+	if h.Returned() {
+		t.Fatal("Start returned after a panic the app ignores")
+	}
+}
+
+func TestStatusCheckPanicKeepsStack(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second), sysd.WithPanicPolicy(sysd.IgnorePanic))
+	app := &statusPanickingApp{FakeApp: sysdtest.NewFakeApp("app")}
+	if err := h.Systemd.Add(app); err != nil {
+		t.Fatal(err)
+	}
+	h.NewApp("other")
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "app")
+	var panicErr *sysd.PanicError
+	if !errors.As(e.Err, &panicErr) || panicErr.Value != "status boom" {
+		t.Fatalf("app failed with %v, want the PanicError", e.Err)
+	}
+	if len(e.Stack) == 0 {
+		t.Error("the failed event has no stack")
+	}
+	h.WaitForState("app", sysd.AppFailed)
+	if h.Returned() {
+		t.Fatal("Start returned after an ignored status check panic")
+	}
+}
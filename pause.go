@@ -0,0 +1,58 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrAppPaused is the cancellation cause of an app stopped by Pause
+	ErrAppPaused = errors.New("app paused")
+
+	// ErrAppNotPaused is returned by Resume for an app which is not paused
+	ErrAppNotPaused = errors.New("app not paused")
+)
+
+// Pause stops a running app and keeps it stopped until Resume, its configuration is kept and
+// its status is not checked meanwhile. the systemd service must be running
+func (s *Systemd) Pause(ctx context.Context, appName string) error {
+	s.mu.RLock()
+	app, ok := s.apps[appName]
+	run := s.run
+	s.mu.RUnlock()
+
+	if !ok {
+		return ErrAppNotExists
+	}
+	if run == nil {
+		return ErrNotRunning
+	}
+
+	s.logger.Info("Pausing app %q", appName)
+	// the actor holds the paused app, so it does not count as stopped for good
+	sent, err := s.sendCommand(app, command{kind: cmdPause, ctx: ctx})
+	if !sent {
+		return ErrNotRunning
+	}
+	return err
+}
+
+// Resume starts a paused app again, the systemd service must be running
+func (s *Systemd) Resume(appName string) error {
+	s.mu.RLock()
+	app, ok := s.apps[appName]
+	s.mu.RUnlock()
+
+	if !ok {
+		return ErrAppNotExists
+	}
+
+	// the actor checks the app is paused, a concurrent command may be starting it
+	if sent, err := s.sendCommand(app, command{kind: cmdResume}); sent {
+		return err
+	}
+	if s.appState(app) != AppPaused {
+		return ErrAppNotPaused
+	}
+	return ErrNotRunning
+}
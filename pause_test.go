@@ -0,0 +1,83 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// goroutines returns the number of goroutines running the function
+func goroutines(fn string) int {
+	buf := make([]byte, 1<<20)
+	return strings.Count(string(buf[:runtime.Stack(buf, true)]), fn)
+}
+
+// waitGoroutines waits until at most n goroutines run the function, failing the test otherwise.
+// goroutines leaked by other tests are counted in n, so a test only checks its own
+func waitGoroutines(t *testing.T, fn string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(sysdtest.WaitTimeout)
+	for goroutines(fn) > n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s still running after %s", fn, sysdtest.WaitTimeout)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// memoryStore is a StateStore keeping the state in memory
+type memoryStore struct {
+	state sysd.SavedState
+}
+
+func (m *memoryStore) Load(context.Context) (sysd.SavedState, error) { return m.state, nil }
+func (m *memoryStore) Save(context.Context, sysd.SavedState) error   { return nil }
+
+func TestPausedAppReleasedOnShutdown(t *testing.T) {
+	running := goroutines("sysd.drainErrors")
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app")
+	h.NewApp("other")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	if err := h.Systemd.Pause(context.Background(), "app"); err != nil {
+		t.Fatalf("Pause returned %v", err)
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	waitGoroutines(t, "sysd.drainErrors", running)
+
+	if err := h.Systemd.Resume("app"); !errors.Is(err, sysd.ErrNotRunning) {
+		t.Errorf("Resume after shutdown returned %v, want %v", err, sysd.ErrNotRunning)
+	}
+}
+
+func TestRestoredPausedAppReleasedOnShutdown(t *testing.T) {
+	running := goroutines("sysd.drainErrors")
+	h := sysdtest.NewHarness(t)
+	h.Systemd.SetStateStore(&memoryStore{state: sysd.SavedState{
+		Apps: map[string]sysd.SavedApp{"app": {Paused: true}},
+	}})
+	app := h.NewApp("app")
+	h.NewApp("other")
+	h.Start()
+	h.WaitForState("other", sysd.AppRunning)
+	h.WaitForState("app", sysd.AppPaused)
+	if app.Starts() != 0 {
+		t.Fatalf("paused app started %d times", app.Starts())
+	}
+
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	waitGoroutines(t, "sysd.drainErrors", running)
+}
@@ -0,0 +1,175 @@
+package sysd
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// stateSaveTimeout bounds loading and saving the persisted state
+const stateSaveTimeout = 10 * time.Second
+
+// SavedApp is the persisted state of an app
+type SavedApp struct {
+	Paused      bool `json:"paused,omitempty"`
+	Quarantined bool `json:"quarantined,omitempty"`
+	Restarts    int  `json:"restarts"`
+	Failures    int  `json:"failures"`
+}
+
+// SavedState is the supervisor state kept across process restarts by a StateStore
+type SavedState struct {
+	Apps map[string]SavedApp `json:"apps"`
+}
+
+// StateStore persists the supervisor state, e.g. FileStateStore. Load returns an empty state
+// if nothing was saved yet
+type StateStore interface {
+	Load(ctx context.Context) (SavedState, error)
+	Save(ctx context.Context, state SavedState) error
+}
+
+// persister saves the supervisor state to the store. changes only mark the state dirty,
+// it is saved by the goroutine running while the systemd service runs
+type persister struct {
+	mu    sync.Mutex
+	store StateStore
+	// wake is signalled when the state changed
+	wake chan struct{}
+}
+
+// SetStateStore persists paused and quarantined apps and the restart counters to the store,
+// and restores them when Start is called, so e.g. a paused app stays paused across a deploy
+func (s *Systemd) SetStateStore(store StateStore) {
+	s.persist.mu.Lock()
+	defer s.persist.mu.Unlock()
+
+	s.persist.store = store
+}
+
+// markDirty schedules saving the state if there is a store
+func (p *persister) markDirty() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.wake == nil {
+		return
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// restoreState applies the persisted state to the apps, paused apps are held by the run
+// and quarantined apps wait for Unquarantine. it returns the apps left to start
+func (s *Systemd) restoreState(apps []*appItem) []*appItem {
+	s.persist.mu.Lock()
+	store := s.persist.store
+	s.persist.mu.Unlock()
+
+	if store == nil {
+		return apps
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), stateSaveTimeout)
+	defer cancel()
+	state, err := store.Load(ctx)
+	if err != nil {
+		s.logger.Error("Failed to load the persisted state, starting all apps: %v", err)
+		return apps
+	}
+
+	start := make([]*appItem, 0, len(apps))
+	for _, app := range apps {
+		saved, ok := state.Apps[app.Name()]
+		if !ok {
+			start = append(start, app)
+			continue
+		}
+
+		s.mu.Lock()
+		app.restarts = saved.Restarts
+		app.totalFailures = saved.Failures
+		switch {
+		case saved.Quarantined:
+			app.state = AppQuarantined
+			app.lastErr = ErrAppQuarantined
+		case saved.Paused:
+			app.state = AppPaused
+		}
+		s.mu.Unlock()
+
+		switch {
+		case saved.Quarantined:
+			s.logger.Info("app %q was quarantined before, not starting it until Unquarantine", app.Name())
+		case saved.Paused:
+			s.logger.Info("app %q was paused before, not starting it until Resume", app.Name())
+		default:
+			start = append(start, app)
+		}
+	}
+	return start
+}
+
+// startPersist starts saving the state on changes, the returned function stops it once
+// the last state is saved
+func (s *Systemd) startPersist() func() {
+	p := &s.persist
+	wake := make(chan struct{}, 1)
+	p.mu.Lock()
+	p.wake = wake
+	p.mu.Unlock()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-wake:
+				s.saveState()
+			case <-stop:
+				s.saveState()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		p.mu.Lock()
+		p.wake = nil
+		p.mu.Unlock()
+	}
+}
+
+// saveState saves the current state of the apps to the store
+func (s *Systemd) saveState() {
+	s.persist.mu.Lock()
+	store := s.persist.store
+	s.persist.mu.Unlock()
+
+	if store == nil {
+		return
+	}
+
+	s.mu.RLock()
+	state := SavedState{Apps: make(map[string]SavedApp, len(s.apps))}
+	for name, app := range s.apps {
+		state.Apps[name] = SavedApp{
+			Paused:      app.state == AppPaused,
+			Quarantined: app.state == AppQuarantined,
+			Restarts:    app.restarts,
+			Failures:    app.totalFailures,
+		}
+	}
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), stateSaveTimeout)
+	defer cancel()
+	if err := store.Save(ctx, state); err != nil {
+		s.logger.Error("Failed to save the persisted state: %v", err)
+	}
+}
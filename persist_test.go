@@ -0,0 +1,87 @@
+package sysd_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStatePersistsAcrossRuns(t *testing.T) {
+	store := sysd.FileStateStore(filepath.Join(t.TempDir(), "state.json"))
+
+	first := sysdtest.NewHarness(t)
+	first.Systemd.SetStateStore(store)
+	first.NewApp("app")
+	first.NewApp("db")
+	first.Start()
+	first.WaitForState("app", sysd.AppRunning)
+	first.WaitForState("db", sysd.AppRunning)
+	if err := first.Systemd.Pause(context.Background(), "app"); err != nil {
+		t.Fatalf("Pause returned %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := first.Systemd.RestartApp(context.Background(), "db"); err != nil {
+			t.Fatalf("RestartApp returned %v", err)
+		}
+	}
+	if err := first.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+
+	// the next process keeps the app paused and the restart counters
+	second := sysdtest.NewHarness(t)
+	second.Systemd.SetStateStore(store)
+	app := second.NewApp("app")
+	second.NewApp("db")
+	second.Start()
+	second.WaitForState("db", sysd.AppRunning)
+	second.WaitForState("app", sysd.AppPaused)
+	if app.Starts() != 0 {
+		t.Fatalf("paused app started %d times", app.Starts())
+	}
+	for _, st := range second.Systemd.Stats() {
+		if st.Name == "db" && st.Restarts != 2 {
+			t.Errorf("db restarted %d times, want the 2 restarts of the previous run", st.Restarts)
+		}
+	}
+
+	if err := second.Systemd.Resume("app"); err != nil {
+		t.Fatalf("Resume returned %v", err)
+	}
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("resumed app not running")
+	}
+}
+
+func TestRestoredQuarantinedAppWaitsForUnquarantine(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.Systemd.SetStateStore(&memoryStore{state: sysd.SavedState{
+		Apps: map[string]sysd.SavedApp{"app": {Quarantined: true}},
+	}})
+	app := h.NewApp("app")
+	h.NewApp("other")
+	h.Start()
+	h.WaitForState("other", sysd.AppRunning)
+	h.WaitForState("app", sysd.AppQuarantined)
+	if app.Starts() != 0 {
+		t.Fatalf("quarantined app started %d times", app.Starts())
+	}
+
+	if err := h.Systemd.Unquarantine("app"); err != nil {
+		t.Fatalf("Unquarantine returned %v", err)
+	}
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("unquarantined app not running")
+	}
+}
+
+func TestFileStateStoreLoadsEmptyState(t *testing.T) {
+	store := sysd.FileStateStore(filepath.Join(t.TempDir(), "missing.json"))
+	state, err := store.Load(context.Background())
+	if err != nil || len(state.Apps) != 0 {
+		t.Fatalf("Load of a missing file returned %+v, %v, want an empty state", state, err)
+	}
+}
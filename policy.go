@@ -0,0 +1,43 @@
+package sysd
+
+import (
+	"math"
+	"time"
+)
+
+var _ RestartPolicy = &OnFailure{}
+
+// RestartPolicy decides whether and when a failed app is started again
+type RestartPolicy interface {
+	// NextDelay returns how long to wait before restarting the app after its attempt-th consecutive
+	// failure, counted from zero, and false if the app should not be restarted anymore
+	NextDelay(attempt int, lastErr error) (time.Duration, bool)
+}
+
+// RestartPolicyFunc is a function implementing RestartPolicy
+type RestartPolicyFunc func(attempt int, lastErr error) (time.Duration, bool)
+
+// NextDelay implements RestartPolicy
+func (f RestartPolicyFunc) NextDelay(attempt int, lastErr error) (time.Duration, bool) {
+	return f(attempt, lastErr)
+}
+
+// OnFailurePolicy returns an OnFailure restarting failed apps as long as and as late as the policy says
+func OnFailurePolicy(policy RestartPolicy) *OnFailure {
+	return &OnFailure{name: "policy", retry: math.MaxInt, policy: policy}
+}
+
+// NextDelay implements RestartPolicy, apps are restarted until they are started retry times,
+// waiting the retry timeout, or the backoff delay, in between
+func (o *OnFailure) NextDelay(attempt int, lastErr error) (time.Duration, bool) {
+	if o.policy != nil {
+		return o.policy.NextDelay(attempt, lastErr)
+	}
+	if o.fn == nil && !o.Equal(OnFailureRestart) {
+		return 0, false
+	}
+	if attempt >= max(o.retry, 1)-1 {
+		return 0, false
+	}
+	return o.delay(attempt), true
+}
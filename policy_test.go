@@ -0,0 +1,80 @@
+package sysd_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestOnFailureNextDelay(t *testing.T) {
+	failed := errors.New("failed")
+	restart := sysd.OnFailureRestart.Retry(3).RetryTimeout(2 * time.Second)
+	for attempt, want := range []bool{true, true, false} {
+		delay, ok := restart.NextDelay(attempt, failed)
+		if ok != want {
+			t.Errorf("restart attempt %d: got %v, want %v", attempt, ok, want)
+		}
+		if ok && delay != 2*time.Second {
+			t.Errorf("restart attempt %d: waits %s, want 2s", attempt, delay)
+		}
+	}
+	for _, onFailure := range []*sysd.OnFailure{sysd.OnFailureIgnore, sysd.OnFailureShutdownAll} {
+		if _, ok := onFailure.NextDelay(0, failed); ok {
+			t.Errorf("%s restarts the app", onFailure)
+		}
+	}
+}
+
+func TestRestartPolicyDecidesRestarts(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts []int
+		errs     []error
+	)
+	policy := sysd.RestartPolicyFunc(func(attempt int, lastErr error) (time.Duration, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, attempt)
+		errs = append(errs, lastErr)
+		return time.Duration(attempt+1) * time.Minute, attempt < 2
+	})
+
+	failed := errors.New("failed")
+	h := sysdtest.NewHarness(t, sysd.WithStartFailurePolicy(sysd.StartFailureContinue))
+	h.NewApp("other")
+	app := h.NewApp("app", sysd.WithRestartPolicy(policy))
+	app.FailStarts(10, failed)
+	h.Start()
+
+	h.WaitForEvent(sysd.EventAppFailed, "app")
+	// the policy delays the restart, the app is not started again before it
+	h.Clock.BlockUntil(1)
+	h.Clock.Advance(59 * time.Second)
+	time.Sleep(20 * time.Millisecond)
+	if starts := app.Starts(); starts != 1 {
+		t.Fatalf("app started %d times before the restart delay, want 1", starts)
+	}
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); app.Starts() < 3 && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	h.WaitForState("app", sysd.AppFailed)
+	if starts := app.Starts(); starts != 3 {
+		t.Errorf("app started %d times, want 3", starts)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 || attempts[0] != 0 || attempts[1] != 1 || attempts[2] != 2 {
+		t.Errorf("policy asked for attempts %v, want [0 1 2]", attempts)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, failed) {
+			t.Errorf("policy got error %v, want %v", err, failed)
+		}
+	}
+}
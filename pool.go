@@ -0,0 +1,43 @@
+package sysd
+
+import (
+	"context"
+	"sync"
+)
+
+// workerPool runs functions concurrently with at most size of them running at the same time
+type workerPool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func newWorkerPool(size int) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &workerPool{sem: make(chan struct{}, size)}
+}
+
+// Go runs fn in a new goroutine once a worker slot is free, without blocking the caller while all
+// slots are busy. fn is dropped if the context is done first. done is called after fn returned
+// or was dropped
+func (p *workerPool) Go(ctx context.Context, fn, done func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer done()
+
+		select {
+		case p.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		defer func() { <-p.sem }()
+		fn()
+	}()
+}
+
+// Wait blocks until all functions started with Go have returned or were dropped
+func (p *workerPool) Wait() {
+	p.wg.Wait()
+}
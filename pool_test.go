@@ -0,0 +1,115 @@
+package sysd_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStatusCheckConcurrency(t *testing.T) {
+	const apps, limit = 6, 2
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	release := make(chan struct{})
+	status := func(ctx context.Context) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+	current := func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return running, peak
+	}
+
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second), sysd.WithStatusCheckConcurrency(limit))
+	for i := 0; i < apps; i++ {
+		app := sysd.AppFunc(fmt.Sprintf("app-%d", i), func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}, status)
+		if err := h.Systemd.Add(app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Start()
+	for i := 0; i < apps; i++ {
+		h.WaitForState(fmt.Sprintf("app-%d", i), sysd.AppRunning)
+	}
+
+	h.Clock.Advance(time.Second)
+	deadline := time.Now().Add(sysdtest.WaitTimeout)
+	for n, _ := current(); n < limit; n, _ = current() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d status checks running, want %d", n, limit)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// give further checks the time to start if they were not bounded
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if _, p := current(); p != limit {
+		t.Errorf("at most %d status checks ran at the same time, want %d", p, limit)
+	}
+}
+
+func TestSlowStatusCheckDoesNotDelayOthers(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	if err := h.Systemd.Add(&hangingApp{FakeApp: sysdtest.NewFakeApp("hung")}); err != nil {
+		t.Fatal(err)
+	}
+	other := h.NewApp("other")
+	h.Start()
+	h.WaitForState("other", sysd.AppRunning)
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); other.StatusChecks() < 5 && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := other.StatusChecks(); n < 5 {
+		t.Errorf("other app status checked %d times while a check hangs, want it checked on every tick", n)
+	}
+}
+
+func TestWatcherTicksWhileAllChecksHang(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second), sysd.WithStatusCheckConcurrency(1))
+	for _, name := range []string{"hung", "waiting"} {
+		app := &hangingApp{FakeApp: sysdtest.NewFakeApp(name)}
+		if err := h.Systemd.Add(app, sysd.WithStatusTimeout(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Start()
+	h.WaitForState("hung", sysd.AppRunning)
+	h.WaitForState("waiting", sysd.AppRunning)
+
+	// one check holds the only slot, the other waits for it without stalling the watcher
+	for i := 0; i < 10; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !h.Systemd.Alive() {
+		t.Error("service not alive while status checks hang")
+	}
+	if err := h.Stop(); err != nil {
+		t.Errorf("Stop returned %v", err)
+	}
+}
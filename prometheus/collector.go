@@ -0,0 +1,135 @@
+package prometheus
+
+import (
+	"github.com/mirzakhany/sysd"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+var _ prom.Collector = &Collector{}
+
+var (
+	appStateDesc = prom.NewDesc(
+		"sysd_app_state",
+		"State of the app, 1 for the current state and 0 for the others.",
+		[]string{"app", "state"}, nil,
+	)
+	appRestartsDesc = prom.NewDesc(
+		"sysd_app_restarts_total",
+		"Number of times the app was restarted.",
+		[]string{"app"}, nil,
+	)
+	appStatusCheckFailuresDesc = prom.NewDesc(
+		"sysd_app_status_check_failures_total",
+		"Number of failed status checks of the app.",
+		[]string{"app"}, nil,
+	)
+	appStatusCheckDurationDesc = prom.NewDesc(
+		"sysd_app_status_check_duration_seconds",
+		"Time spent in status checks of the app.",
+		[]string{"app"}, nil,
+	)
+	appUptimeDesc = prom.NewDesc(
+		"sysd_app_uptime_seconds",
+		"Time since the current run of the app started, 0 if it is not running.",
+		[]string{"app"}, nil,
+	)
+	appTotalUptimeDesc = prom.NewDesc(
+		"sysd_app_uptime_seconds_total",
+		"Time the app spent running over all its runs.",
+		[]string{"app"}, nil,
+	)
+	appFailuresDesc = prom.NewDesc(
+		"sysd_app_failures_total",
+		"Number of failed starts, panics and failed status checks of the app.",
+		[]string{"app"}, nil,
+	)
+	appLastFailureDesc = prom.NewDesc(
+		"sysd_app_last_failure_timestamp_seconds",
+		"Unix time of the last failure of the app, 0 if it never failed.",
+		[]string{"app"}, nil,
+	)
+	appMTBFDesc = prom.NewDesc(
+		"sysd_app_mean_time_between_failures_seconds",
+		"Total uptime of the app divided by its failures, 0 if it never failed.",
+		[]string{"app"}, nil,
+	)
+	shutdownDurationDesc = prom.NewDesc(
+		"sysd_shutdown_duration_seconds",
+		"Time spent shutting down the apps.",
+		nil, nil,
+	)
+)
+
+// appStates are exported for every app so the state gauges do not disappear on change
+var appStates = []sysd.AppState{
+	sysd.AppPending,
+	sysd.AppStarting,
+	sysd.AppRunning,
+	sysd.AppDegraded,
+	sysd.AppRestarting,
+	sysd.AppStopped,
+	sysd.AppFailed,
+	sysd.AppQuarantined,
+	sysd.AppPaused,
+}
+
+// Collector exports the metrics of a systemd service to prometheus
+type Collector struct {
+	s *sysd.Systemd
+}
+
+// NewCollector returns a prometheus collector for the given systemd service
+// the collector should be registered once, e.g. prometheus.MustRegister(NewCollector(s))
+func NewCollector(s *sysd.Systemd) *Collector {
+	return &Collector{s: s}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	ch <- appStateDesc
+	ch <- appRestartsDesc
+	ch <- appStatusCheckFailuresDesc
+	ch <- appStatusCheckDurationDesc
+	ch <- appUptimeDesc
+	ch <- appTotalUptimeDesc
+	ch <- appFailuresDesc
+	ch <- appLastFailureDesc
+	ch <- appMTBFDesc
+	ch <- shutdownDurationDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	m := c.s.Metrics()
+
+	for _, app := range m.Apps {
+		for _, state := range appStates {
+			ch <- prom.MustNewConstMetric(appStateDesc, prom.GaugeValue, boolValue(app.State == state), app.Name, state.String())
+		}
+		ch <- prom.MustNewConstMetric(appRestartsDesc, prom.CounterValue, float64(app.Restarts), app.Name)
+		ch <- prom.MustNewConstMetric(appStatusCheckFailuresDesc, prom.CounterValue, float64(app.StatusCheckFailures), app.Name)
+		ch <- prom.MustNewConstSummary(appStatusCheckDurationDesc, uint64(app.StatusChecks),
+			app.StatusCheckDuration.Seconds(), nil, app.Name)
+	}
+
+	for _, app := range c.s.Stats() {
+		var lastFailure float64
+		if !app.LastFailure.IsZero() {
+			lastFailure = float64(app.LastFailure.UnixNano()) / 1e9
+		}
+		ch <- prom.MustNewConstMetric(appUptimeDesc, prom.GaugeValue, app.Uptime.Seconds(), app.Name)
+		ch <- prom.MustNewConstMetric(appTotalUptimeDesc, prom.CounterValue, app.TotalUptime.Seconds(), app.Name)
+		ch <- prom.MustNewConstMetric(appFailuresDesc, prom.CounterValue, float64(app.Failures), app.Name)
+		ch <- prom.MustNewConstMetric(appLastFailureDesc, prom.GaugeValue, lastFailure, app.Name)
+		ch <- prom.MustNewConstMetric(appMTBFDesc, prom.GaugeValue, app.MeanTimeBetweenFailures.Seconds(), app.Name)
+	}
+
+	ch <- prom.MustNewConstSummary(shutdownDurationDesc, uint64(m.Shutdowns), m.ShutdownDuration.Seconds(), nil)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
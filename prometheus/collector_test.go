@@ -0,0 +1,131 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestCollector(t *testing.T) {
+	// checks still running when the clock is advanced do not time out
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second), sysd.WithStatusCheckTimeout(time.Hour))
+	h.NewApp("api")
+	worker := h.NewApp("worker")
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("api", sysd.AppRunning)
+	h.WaitForState("worker", sysd.AppRunning)
+
+	// the failed status check restarts the worker
+	worker.SetStatus(errors.New("boom"))
+	deadline := time.After(sysdtest.WaitTimeout)
+	for restarted := false; !restarted; {
+		h.Clock.Advance(time.Second)
+		select {
+		case e := <-events:
+			restarted = e.Type == sysd.EventAppRestarted && e.App == "worker"
+		case <-time.After(5 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("worker not restarted")
+		}
+	}
+	worker.SetStatus(nil)
+	h.WaitForState("worker", sysd.AppRunning)
+
+	// the pedantic registry fails the gathering if a metric does not match the descriptions
+	reg := prom.NewPedanticRegistry()
+	reg.MustRegister(NewCollector(h.Systemd))
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 10 {
+		t.Errorf("gathered %d metric families, want 10", len(families))
+	}
+
+	for _, app := range []string{"api", "worker"} {
+		for _, state := range appStates {
+			want := boolValue(state == sysd.AppRunning)
+			if got := value(t, families, "sysd_app_state", "app", app, "state", state.String()); got != want {
+				t.Errorf("state %s of %s is %v, want %v", state, app, got, want)
+			}
+		}
+	}
+	if got := value(t, families, "sysd_app_uptime_seconds", "app", "api"); got < 1 {
+		t.Errorf("uptime of api is %v, want the seconds the clock advanced", got)
+	}
+	if got := value(t, families, "sysd_app_restarts_total", "app", "worker"); got != 1 {
+		t.Errorf("worker restarted %v times, want 1", got)
+	}
+	if got := value(t, families, "sysd_app_failures_total", "app", "worker"); got != 1 {
+		t.Errorf("worker failed %v times, want 1", got)
+	}
+	if got := value(t, families, "sysd_app_failures_total", "app", "api"); got != 0 {
+		t.Errorf("api failed %v times, want 0", got)
+	}
+
+	if err := h.Systemd.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	families, err = reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sampleCount(t, families, "sysd_shutdown_duration_seconds"); got != 1 {
+		t.Errorf("%d shutdowns observed, want 1", got)
+	}
+}
+
+// metric returns the metric of the family with the given name and label pairs, failing the test if there is none
+func metric(t *testing.T, families []*dto.MetricFamily, name string, labels ...string) *dto.Metric {
+	t.Helper()
+
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	next:
+		for _, m := range f.GetMetric() {
+			for i := 0; i < len(labels); i += 2 {
+				if !hasLabel(m, labels[i], labels[i+1]) {
+					continue next
+				}
+			}
+			return m
+		}
+	}
+	t.Fatalf("metric %s%q not gathered", name, labels)
+	return nil
+}
+
+func hasLabel(m *dto.Metric, name, value string) bool {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name && l.GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the value of a gauge or counter
+func value(t *testing.T, families []*dto.MetricFamily, name string, labels ...string) float64 {
+	t.Helper()
+
+	m := metric(t, families, name, labels...)
+	if m.GetGauge() != nil {
+		return m.GetGauge().GetValue()
+	}
+	return m.GetCounter().GetValue()
+}
+
+// sampleCount returns the number of observations of a summary
+func sampleCount(t *testing.T, families []*dto.MetricFamily, name string, labels ...string) uint64 {
+	t.Helper()
+	return metric(t, families, name, labels...).GetSummary().GetSampleCount()
+}
@@ -0,0 +1,80 @@
+package sysd
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrAppQuarantined is returned when an app restarted too many times in a short period
+// and is not restarted anymore until Unquarantine is called
+var ErrAppQuarantined = errors.New("app quarantined")
+
+// SetCrashLoopThreshold quarantines apps restarting more than the given number of times
+// within the window, instead of restarting them in a loop. zero restarts disables it
+func (s *Systemd) SetCrashLoopThreshold(restarts int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.crashLoopRestarts = restarts
+	s.crashLoopWindow = window
+}
+
+// checkCrashLoop records a restart of the app and quarantines it if it is restarting
+// too often, it returns ErrAppQuarantined if so
+func (s *Systemd) checkCrashLoop(app *appItem) error {
+	s.mu.RLock()
+	restarts, window := s.crashLoopRestarts, s.crashLoopWindow
+	s.mu.RUnlock()
+
+	app.crashLoop.mu.Lock()
+	app.crashLoop.max, app.crashLoop.window = restarts, window
+	app.crashLoop.mu.Unlock()
+
+	if app.crashLoop.record(s.clock.Now()) {
+		return nil
+	}
+
+	s.logger.with("app", app.Name(), "state", AppQuarantined.String()).Error("app %q restarted more than %d times in %s, quarantining it", app.Name(), restarts, window)
+	s.setState(app, AppQuarantined, ErrAppQuarantined)
+	s.emit(EventAppQuarantined, app.Name(), ErrAppQuarantined)
+	return ErrAppQuarantined
+}
+
+// Unquarantine clears the quarantine of an app, starting it again if the systemd service is running
+func (s *Systemd) Unquarantine(appName string) error {
+	s.mu.RLock()
+	app, ok := s.apps[appName]
+	s.mu.RUnlock()
+
+	if !ok {
+		return ErrAppNotExists
+	}
+
+	// the actor of the running systemd service starts the app again
+	if sent, err := s.sendCommand(app, command{kind: cmdUnquarantine}); sent {
+		return err
+	}
+	if s.clearQuarantine(app) {
+		s.logger.Info("Unquarantining app %q", appName)
+		s.saveState()
+	}
+	return nil
+}
+
+// clearQuarantine moves a quarantined app to stopped and forgets its restarts,
+// it returns false if the app is not quarantined
+func (s *Systemd) clearQuarantine(app *appItem) bool {
+	s.mu.Lock()
+	if app.state != AppQuarantined {
+		s.mu.Unlock()
+		return false
+	}
+	app.state = AppStopped
+	app.failures = 0
+	s.mu.Unlock()
+
+	app.crashLoop.mu.Lock()
+	app.crashLoop.restarts = nil
+	app.crashLoop.mu.Unlock()
+	return true
+}
@@ -0,0 +1,46 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestCrashLoopQuarantinesApp(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithDefaultOnFailure(sysd.OnFailureRestart.Retry(100).RetryTimeout(time.Second)))
+	h.Systemd.SetCrashLoopThreshold(2, time.Hour)
+	app := h.NewApp("app").FailStarts(3, errors.New("crash"))
+	// keep the stack running while the app is quarantined
+	h.NewApp("other")
+	events := h.Systemd.Subscribe()
+	h.Start()
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppQuarantined, "app")
+	if !errors.Is(e.Err, sysd.ErrAppQuarantined) {
+		t.Errorf("quarantined event carries %v, want %v", e.Err, sysd.ErrAppQuarantined)
+	}
+	h.WaitForState("app", sysd.AppQuarantined)
+
+	// a quarantined app is not restarted anymore
+	for i := 0; i < 10; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := app.Starts(); n != 3 {
+		t.Errorf("app started %d times, want 3 with the threshold of 2 restarts", n)
+	}
+
+	if err := h.Systemd.Unquarantine("missing"); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Errorf("Unquarantine of a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+	if err := h.Systemd.Unquarantine("app"); err != nil {
+		t.Fatalf("Unquarantine returned %v", err)
+	}
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app not started again after Unquarantine")
+	}
+	h.WaitForState("app", sysd.AppRunning)
+}
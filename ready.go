@@ -0,0 +1,134 @@
+package sysd
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReadyReporter is an optional interface for apps that signal when they are ready to be used,
+// e.g. once ports are bound or connections are made, by calling MarkReady from their Start.
+// apps not implementing it are ready as soon as they are started and pass their status check
+type ReadyReporter interface {
+	// ReportsReady returns true if the app calls MarkReady once it is ready
+	ReportsReady() bool
+}
+
+type readyKey struct{}
+
+// readyState tracks the readiness of a single run of an app
+type readyState struct {
+	once    sync.Once
+	c       chan struct{}
+	onReady func()
+}
+
+func newReadyState(onReady func()) *readyState {
+	return &readyState{c: make(chan struct{}), onReady: onReady}
+}
+
+func (r *readyState) mark() {
+	r.once.Do(func() {
+		close(r.c)
+		if r.onReady != nil {
+			r.onReady()
+		}
+	})
+}
+
+func (r *readyState) isReady() bool {
+	select {
+	case <-r.c:
+		return true
+	default:
+		return false
+	}
+}
+
+// MarkReady signals that the app owning the context is ready to be used,
+// it should be called with the context passed to the app Start and returns false
+// if the context does not belong to an app started by a systemd service
+func MarkReady(ctx context.Context) bool {
+	ready, ok := ctx.Value(readyKey{}).(*readyState)
+	if !ok {
+		return false
+	}
+	ready.mark()
+	return true
+}
+
+// IsAppReady returns true if the app is ready to be used
+func (s *Systemd) IsAppReady(ctx context.Context, appName string) (bool, error) {
+	s.mu.RLock()
+	app, ok := s.apps[appName]
+	s.mu.RUnlock()
+
+	if !ok {
+		return false, ErrAppNotExists
+	}
+	return s.appReady(ctx, app), nil
+}
+
+// Ready returns true if all apps are ready to be used
+func (s *Systemd) Ready(ctx context.Context) bool {
+	for _, app := range s.appList() {
+		if !s.appReady(ctx, app) {
+			return false
+		}
+	}
+	return true
+}
+
+// appReady returns true if the app is running and either marked itself ready
+// or, if it does not report readiness, passed its status check. the status is checked
+// at most once per status check interval, the last result is used meanwhile
+func (s *Systemd) appReady(ctx context.Context, app *appItem) bool {
+	// a task is ready once it has run successfully
+	if app.task {
+		return s.appState(app) == AppStopped
+	}
+
+	if app.startedAt.Load() == 0 {
+		return false
+	}
+
+	if reporter, ok := app.App.(ReadyReporter); ok && reporter.ReportsReady() {
+		s.mu.RLock()
+		ready := app.ready
+		s.mu.RUnlock()
+		return ready != nil && ready.isReady()
+	}
+
+	if err, ok := s.lastStatus(app); ok {
+		return err == nil
+	}
+	begin := s.clock.Now()
+	err := s.checkStatus(ctx, app)
+	s.recordStatus(app, begin, err)
+	return err == nil
+}
+
+// recordStatus records the result of a status check of the app started at the given time
+func (s *Systemd) recordStatus(app *appItem, at time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app.statusErr, app.statusAt = err, at
+}
+
+// lastStatus returns the result of the last status check of the current run of the app,
+// it returns false if there is none or it is older than the status check interval
+func (s *Systemd) lastStatus(app *appItem) (error, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	interval := app.statusInterval
+	if interval == 0 {
+		interval = s.statusCheckInterval
+	}
+	startedAt := time.Unix(0, app.startedAt.Load())
+	if app.statusAt.IsZero() || app.statusAt.Before(startedAt) || s.clock.Now().Sub(app.statusAt) >= interval {
+		return nil, false
+	}
+	return app.statusErr, true
+}
@@ -0,0 +1,103 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestDependencyReadinessUsesStatusInterval(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	dep := h.NewApp("dep", sysd.WithOnFailure(sysd.OnFailureIgnore))
+	dep.SetStatus(errors.New("warming up"))
+	app := h.NewApp("app", sysd.WithDependsOn("dep"))
+	h.Start()
+	if !dep.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("dependency did not start")
+	}
+
+	// the dependency is polled every 100ms, its status only once per interval
+	for i := 0; i < 9; i++ {
+		h.Clock.Advance(100 * time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := dep.StatusChecks(); n != 1 {
+		t.Errorf("dependency status checked %d times in one interval, want once", n)
+	}
+	if app.Starts() != 0 {
+		t.Fatal("app started before its dependency is ready")
+	}
+
+	dep.SetStatus(nil)
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !app.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(100 * time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !app.Running() {
+		t.Fatal("app did not start once its dependency got ready")
+	}
+}
+
+// readyApp is a fake app marking itself ready once released
+type readyApp struct {
+	*sysdtest.FakeApp
+	release chan struct{}
+}
+
+func (a *readyApp) ReportsReady() bool { return true }
+
+func (a *readyApp) Start(ctx context.Context) error {
+	go func() {
+		select {
+		case <-a.release:
+			sysd.MarkReady(ctx)
+		case <-ctx.Done():
+		}
+	}()
+	return a.FakeApp.Start(ctx)
+}
+
+func TestMarkReady(t *testing.T) {
+	if sysd.MarkReady(context.Background()) {
+		t.Error("MarkReady without an app context returned true")
+	}
+
+	h := sysdtest.NewHarness(t)
+	dep := &readyApp{FakeApp: sysdtest.NewFakeApp("dep"), release: make(chan struct{})}
+	if err := h.Systemd.Add(dep); err != nil {
+		t.Fatal(err)
+	}
+	app := h.NewApp("app", sysd.WithDependsOn("dep"))
+	h.Start()
+	if !dep.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("dependency did not start")
+	}
+
+	// a started app passing its status check is not ready until it marks itself ready
+	for i := 0; i < 10; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ready, _ := h.Systemd.IsAppReady(context.Background(), "dep"); ready {
+		t.Error("dependency ready before MarkReady")
+	}
+	if app.Starts() != 0 {
+		t.Fatal("app started before its dependency marked itself ready")
+	}
+
+	close(dep.release)
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !app.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(100 * time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !app.Running() {
+		t.Fatal("app did not start once its dependency marked itself ready")
+	}
+	if ready, _ := h.Systemd.IsAppReady(context.Background(), "dep"); !ready {
+		t.Error("dependency not ready after MarkReady")
+	}
+}
@@ -0,0 +1,41 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Reloader is an optional interface for apps which can reload their configuration without a restart
+type Reloader interface {
+	// Reload reloads the app configuration, a failed reload keeps the app running
+	Reload(ctx context.Context) error
+}
+
+// SetReloadSignals sets the signals which reload the apps while the systemd service is running,
+// SIGHUP by default. calling it without signals disables reloading on signals
+func (s *Systemd) SetReloadSignals(sig ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadSignals = append([]os.Signal{}, sig...)
+}
+
+// Reload calls Reload on all running apps implementing Reloader, in start order.
+// the errors of all failed reloads are returned together
+func (s *Systemd) Reload(ctx context.Context) error {
+	var errs []error
+	for _, app := range s.startOrder(s.appList()) {
+		reloader, ok := app.App.(Reloader)
+		if !ok || !s.appState(app).running() {
+			continue
+		}
+
+		s.logger.Info("Reloading app %q", app.Name())
+		if err := reloader.Reload(s.appContext(ctx, app.Name())); err != nil {
+			s.logger.Error("Failed to reload app %q: %v", app.Name(), err)
+			errs = append(errs, fmt.Errorf("app %q: %w", app.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// crashingApp runs until crash receives, then returns an error
+type crashingApp struct {
+	name   string
+	crash  chan struct{}
+	starts atomic.Int32
+}
+
+func (a *crashingApp) Name() string { return a.name }
+
+func (a *crashingApp) Start(ctx context.Context) error {
+	a.starts.Add(1)
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-a.crash:
+		return errors.New("crashed")
+	}
+}
+
+func (a *crashingApp) Status(context.Context) error { return nil }
+
+// waitStarts waits until the app was started n times
+func (a *crashingApp) waitStarts(t *testing.T, n int32) {
+	t.Helper()
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); a.starts.Load() < n; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("app started %d times, want %d", a.starts.Load(), n)
+		}
+	}
+}
+
+func TestResetAfterForgetsFailuresOfHealthyApps(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStartFailurePolicy(sysd.StartFailureContinue))
+	h.NewApp("other")
+	app := &crashingApp{name: "app", crash: make(chan struct{})}
+	onFailure := sysd.OnFailureRestart.Retry(2).RetryTimeout(time.Second).ResetAfter(time.Minute)
+	if err := h.Systemd.Add(app, sysd.WithOnFailure(onFailure)); err != nil {
+		t.Fatal(err)
+	}
+	events := h.Systemd.Subscribe()
+	h.Start()
+
+	for i := int32(1); i <= 6; i++ {
+		app.waitStarts(t, i)
+		h.Clock.Advance(2 * time.Minute)
+		app.crash <- struct{}{}
+		advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "app")
+	}
+	app.waitStarts(t, 7)
+}
+
+func TestWithoutResetAfterFailuresUseUpRetries(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStartFailurePolicy(sysd.StartFailureContinue))
+	h.NewApp("other")
+	app := &crashingApp{name: "app", crash: make(chan struct{})}
+	onFailure := sysd.OnFailureRestart.Retry(2).RetryTimeout(time.Second)
+	if err := h.Systemd.Add(app, sysd.WithOnFailure(onFailure)); err != nil {
+		t.Fatal(err)
+	}
+	events := h.Systemd.Subscribe()
+	h.Start()
+
+	app.waitStarts(t, 1)
+	h.Clock.Advance(2 * time.Minute)
+	app.crash <- struct{}{}
+	advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "app")
+	app.waitStarts(t, 2)
+	h.Clock.Advance(2 * time.Minute)
+	app.crash <- struct{}{}
+	h.WaitForState("app", sysd.AppFailed)
+	if starts := app.starts.Load(); starts != 2 {
+		t.Errorf("app started %d times, want 2", starts)
+	}
+}
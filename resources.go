@@ -0,0 +1,90 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrResourceType is returned when a resource is resolved with a type it does not have
+	ErrResourceType = errors.New("resource type mismatch")
+	// ErrNoResources is returned when a resource is resolved with a context which does not
+	// belong to a systemd service
+	ErrNoResources = errors.New("context does not carry resources")
+)
+
+// resources is the registry of values apps share with each other
+type resources struct {
+	mu     sync.Mutex
+	values map[string]any
+	// provided is closed and replaced every time a resource is provided, to wake up resolvers
+	provided chan struct{}
+}
+
+type resourcesKey struct{}
+
+// Provide publishes a value other apps can resolve by name, e.g. the pool of a database app
+// once it is connected. providing a name again replaces its value
+func (s *Systemd) Provide(name string, value any) {
+	r := &s.resources
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.values == nil {
+		r.values = make(map[string]any)
+	}
+	r.values[name] = value
+	if r.provided != nil {
+		close(r.provided)
+		r.provided = nil
+	}
+}
+
+// lookup returns the resource, or a channel closed once any resource is provided if it is not provided yet
+func (r *resources) lookup(name string) (any, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if value, ok := r.values[name]; ok {
+		return value, true, nil
+	}
+	if r.provided == nil {
+		r.provided = make(chan struct{})
+	}
+	return nil, false, r.provided
+}
+
+// resourcesContext returns a context carrying the resources of the systemd service
+func (s *Systemd) resourcesContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, resourcesKey{}, &s.resources)
+}
+
+// Resolve returns the resource provided under the name by an app of the systemd service, waiting
+// until it is provided or the context is done. it should be called with the context passed to the
+// app Start or Status, and returns ErrResourceType if the resource is not a T
+func Resolve[T any](ctx context.Context, name string) (T, error) {
+	var zero T
+	r, ok := ctx.Value(resourcesKey{}).(*resources)
+	if !ok {
+		return zero, ErrNoResources
+	}
+
+	for {
+		value, ok, provided := r.lookup(name)
+		if ok {
+			typed, ok := value.(T)
+			if !ok {
+				return zero, fmt.Errorf("%w: %q is %T, not %T", ErrResourceType, name, value, zero)
+			}
+			return typed, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return zero, fmt.Errorf("resource %q was not provided: %w", name, context.Cause(ctx))
+		case <-provided:
+		}
+	}
+}
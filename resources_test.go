@@ -0,0 +1,82 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+type pool struct{ dsn string }
+
+func TestResolveWaitsForProvide(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	resolved := make(chan *pool, 1)
+	resolveErr := make(chan error, 1)
+	err := h.Systemd.Add(sysd.AppFunc("http", func(ctx context.Context) error {
+		p, err := sysd.Resolve[*pool](ctx, "db")
+		if err != nil {
+			resolveErr <- err
+			return err
+		}
+		resolved <- p
+		_, err = sysd.Resolve[string](ctx, "db")
+		resolveErr <- err
+		<-ctx.Done()
+		return nil
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+
+	select {
+	case p := <-resolved:
+		t.Fatalf("resolved %v before it was provided", p)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	h.Systemd.Provide("db", &pool{dsn: "postgres://db"})
+	select {
+	case p := <-resolved:
+		if p.dsn != "postgres://db" {
+			t.Errorf("resolved %+v, want the provided pool", p)
+		}
+	case err := <-resolveErr:
+		t.Fatalf("Resolve returned %v", err)
+	case <-time.After(sysdtest.WaitTimeout):
+		t.Fatal("resource not resolved once provided")
+	}
+	if err := <-resolveErr; !errors.Is(err, sysd.ErrResourceType) {
+		t.Errorf("resolving the wrong type returned %v, want %v", err, sysd.ErrResourceType)
+	}
+}
+
+func TestResolveOutsideSystemd(t *testing.T) {
+	if _, err := sysd.Resolve[int](context.Background(), "db"); !errors.Is(err, sysd.ErrNoResources) {
+		t.Errorf("Resolve returned %v, want %v", err, sysd.ErrNoResources)
+	}
+}
+
+func TestResolveReturnsWhenStopped(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	resolveErr := make(chan error, 1)
+	err := h.Systemd.Add(sysd.AppFunc("http", func(ctx context.Context) error {
+		_, err := sysd.Resolve[*pool](ctx, "db")
+		resolveErr <- err
+		return nil
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("http", sysd.AppRunning)
+	h.Stop()
+
+	if err := <-resolveErr; !errors.Is(err, sysd.ErrShutdown) {
+		t.Errorf("Resolve returned %v once the app stopped, want %v", err, sysd.ErrShutdown)
+	}
+}
@@ -0,0 +1,181 @@
+package sysd
+
+import (
+	"context"
+	"time"
+)
+
+// Stopper is an optional interface for apps that need to do their cleanup with a context
+// which is not cancelled yet. Stop is called after the app context is cancelled, with a
+// context bound to the app shutdown timeout
+type Stopper interface {
+	// Stop stops the app, it should make Start return
+	Stop(ctx context.Context) error
+}
+
+// Drainer is an optional interface for apps that can report they have finished
+// their in-flight work during shutdown, so the graceful shutdown wait can end
+// before the app Start returns
+type Drainer interface {
+	// Drained returns a channel that is closed once the app is drained
+	Drained() <-chan struct{}
+}
+
+// stopAppsInOrder cancels the apps one by one in shutdown order, waiting for each to stop
+// or report drained before the next, the returned channel is closed once all are done
+func (s *Systemd) stopAppsInOrder(cause error) <-chan struct{} {
+	c := make(chan struct{})
+	go func() {
+		defer close(c)
+		for _, app := range s.shutdownOrder(s.appList()) {
+			s.mu.RLock()
+			cancel := app.cancel
+			s.mu.RUnlock()
+
+			if cancel == nil {
+				continue
+			}
+			s.logger.Info("Stopping app %q", app.Name())
+			s.signalStop(app, cancel, cause)
+
+			// an app without its own timeout gets the graceful shutdown timeout, like stopApp gives it
+			timeout := s.appShutdownTimeout(app)
+			if !s.waitForAppDrained(app, timeout) {
+				s.logger.Error("app %q did not stop in %s, stopping the next app", app.Name(), timeout)
+			}
+		}
+	}()
+	return c
+}
+
+// runningApps returns the names of the apps which have not returned from their last run
+func (s *Systemd) runningApps() []string {
+	var names []string
+	for _, app := range s.appList() {
+		s.mu.RLock()
+		done := app.done
+		s.mu.RUnlock()
+
+		if done == nil {
+			continue
+		}
+		select {
+		case <-done:
+		default:
+			names = append(names, app.Name())
+		}
+	}
+	return names
+}
+
+// signalStop cancels the app context with the cause and calls Stop if the app
+// implements Stopper and has not returned yet
+func (s *Systemd) signalStop(app *appItem, cancel context.CancelCauseFunc, cause error) {
+	cancel(cause)
+
+	stopper, ok := app.App.(Stopper)
+	if !ok {
+		return
+	}
+
+	s.mu.RLock()
+	done := app.done
+	s.mu.RUnlock()
+	select {
+	case <-done:
+		return
+	default:
+	}
+
+	ctx, cancelStop := withClockTimeout(s.appContext(context.Background(), app.Name()), s.clock, s.appShutdownTimeout(app))
+	defer cancelStop()
+	if err := stopper.Stop(ctx); err != nil {
+		s.logger.Error("app %q stop failed: %v", app.Name(), err)
+	}
+}
+
+// cancelApps cancels all apps at once without waiting for them
+func (s *Systemd) cancelApps(cause error) {
+	for _, app := range s.appList() {
+		s.mu.RLock()
+		cancel := app.cancel
+		s.mu.RUnlock()
+
+		if cancel != nil {
+			cancel(cause)
+		}
+	}
+}
+
+// waitForAppDrained waits for the app to stop or report drained, at most for the timeout
+// if not zero. it returns false if the timeout expired first
+func (s *Systemd) waitForAppDrained(app *appItem, timeout time.Duration) bool {
+	s.mu.RLock()
+	done := app.done
+	s.mu.RUnlock()
+
+	if done == nil {
+		return true
+	}
+
+	var drained <-chan struct{}
+	if drainer, ok := app.App.(Drainer); ok {
+		drained = drainer.Drained()
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := s.clock.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C()
+	}
+
+	select {
+	case <-done:
+	case <-drained:
+		s.logger.Info("app %q drained", app.Name())
+	case <-expired:
+		return false
+	}
+	return true
+}
+
+// SetAppShutdownTimeout sets how long a specific app is given to stop during shutdown,
+// the graceful shutdown timeout is extended if needed to cover it
+func (s *Systemd) SetAppShutdownTimeout(appName string, timeout time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app, ok := s.apps[appName]; ok {
+		app.shutdownTimeout = timeout
+		return nil
+	}
+
+	return ErrAppNotExists
+}
+
+// appShutdownTimeout returns the time the app is given to stop
+func (s *Systemd) appShutdownTimeout(app *appItem) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if app.shutdownTimeout > 0 {
+		return app.shutdownTimeout
+	}
+	return s.graceFullShutdownTimeout
+}
+
+// shutdownTimeout returns the time all apps are given to stop, the graceful shutdown
+// timeout or the longest app shutdown timeout if greater
+func (s *Systemd) shutdownTimeout() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	timeout := s.graceFullShutdownTimeout
+	for _, app := range s.apps {
+		if app.shutdownTimeout > timeout {
+			timeout = app.shutdownTimeout
+		}
+	}
+	return timeout
+}
@@ -0,0 +1,235 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// drainingApp is a fake app reporting drained as soon as it is cancelled,
+// it only returns once released
+type drainingApp struct {
+	*sysdtest.FakeApp
+	once    sync.Once
+	drained chan struct{}
+	release chan struct{}
+}
+
+func (a *drainingApp) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		a.once.Do(func() { close(a.drained) })
+	}()
+	err := a.FakeApp.Start(ctx)
+	<-a.release
+	return err
+}
+
+func (a *drainingApp) Drained() <-chan struct{} {
+	return a.drained
+}
+
+func TestShutdownEndsWhenAppDrained(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithGracefulShutdownTimeout(time.Hour))
+	app := &drainingApp{FakeApp: sysdtest.NewFakeApp("app"), drained: make(chan struct{}), release: make(chan struct{})}
+	// the app does not return during the test, so only reporting drained ends the wait
+	defer close(app.release)
+	if err := h.Systemd.Add(app); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), sysdtest.WaitTimeout)
+	defer cancel()
+	if err := h.Systemd.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown returned %v, want it to end once the app drained", err)
+	}
+}
+
+// stoppingApp is a fake app recording its name once it stopped
+type stoppingApp struct {
+	*sysdtest.FakeApp
+	rec *recorder
+}
+
+func (a *stoppingApp) Start(ctx context.Context) error {
+	err := a.FakeApp.Start(ctx)
+	a.rec.record(a.Name())
+	return err
+}
+
+func TestShutdownStopsDependentsFirst(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	rec := &recorder{}
+	add := func(name string, opts ...sysd.AddOption) *stoppingApp {
+		app := &stoppingApp{FakeApp: sysdtest.NewFakeApp(name), rec: rec}
+		if err := h.Systemd.Add(app, opts...); err != nil {
+			t.Fatal(err)
+		}
+		return app
+	}
+	add("cache")
+	// api depends on db despite a lower priority, so it stops before db
+	add("db", sysd.WithPriority(5))
+	api := add("api", sysd.WithPriority(1), sysd.WithDependsOn("db"))
+	h.Start()
+	// api polls for db to be ready on the clock
+	deadline := time.Now().Add(sysdtest.WaitTimeout)
+	for !api.Running() && time.Now().Before(deadline) {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	h.WaitForState("api", sysd.AppRunning)
+
+	if err := h.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rec.take(), []string{"api", "db", "cache"}; !slices.Equal(got, want) {
+		t.Errorf("apps stopped in order %q, want %q", got, want)
+	}
+}
+
+func TestShutdownStopsInReversePriority(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	rec := &recorder{}
+	for i, name := range []string{"db", "queue", "http"} {
+		app := &stoppingApp{FakeApp: sysdtest.NewFakeApp(name), rec: rec}
+		if name == "http" {
+			// the next app is not cancelled before http has drained
+			app.SetStopDelay(20 * time.Millisecond)
+		}
+		if err := h.Systemd.Add(app, sysd.WithPriority(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Start()
+	h.WaitForState("http", sysd.AppRunning)
+
+	if err := h.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rec.take(), []string{"http", "queue", "db"}; !slices.Equal(got, want) {
+		t.Errorf("apps stopped in order %q, want %q", got, want)
+	}
+}
+
+func TestAppShutdownTimeout(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithGracefulShutdownTimeout(10*time.Second))
+	consumer := h.NewApp("consumer").SetStopDelay(time.Minute)
+	h.NewApp("http")
+	if err := h.Systemd.SetAppShutdownTimeout("missing", time.Minute); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Errorf("SetAppShutdownTimeout of a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+	// the consumer drains for longer than the graceful shutdown timeout
+	if err := h.Systemd.SetAppShutdownTimeout("consumer", 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("consumer", sysd.AppRunning)
+
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Start returned %v, want the consumer to drain in its own timeout", err)
+	}
+	if consumer.Running() {
+		t.Error("consumer still running after shutdown")
+	}
+}
+
+// stopperApp is a fake app cleaning up in Stop until the stop context is done
+type stopperApp struct {
+	*sysdtest.FakeApp
+	stopped chan struct{}
+
+	mu        sync.Mutex
+	name      string
+	errAtCall error
+	cause     error
+}
+
+func (a *stopperApp) Start(ctx context.Context) error {
+	<-ctx.Done()
+	<-a.stopped
+	return nil
+}
+
+func (a *stopperApp) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	a.name, a.errAtCall = sysd.AppName(ctx), ctx.Err()
+	a.mu.Unlock()
+
+	<-ctx.Done()
+	a.mu.Lock()
+	a.cause = context.Cause(ctx)
+	a.mu.Unlock()
+	close(a.stopped)
+	return nil
+}
+
+func TestStopperGetsDeadlineContext(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := &stopperApp{FakeApp: sysdtest.NewFakeApp("app"), stopped: make(chan struct{})}
+	if err := h.Systemd.Add(app, sysd.WithShutdownTimeout(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	if app.errAtCall != nil {
+		t.Errorf("Stop called with a done context: %v", app.errAtCall)
+	}
+	if app.name != "app" {
+		t.Errorf("Stop context belongs to app %q, want app", app.name)
+	}
+	if !errors.Is(app.cause, context.DeadlineExceeded) {
+		t.Errorf("Stop context ended with %v, want the shutdown timeout of the app", app.cause)
+	}
+}
+
+// cancelTimeApp is a fake app sending the clock time its context is cancelled at
+type cancelTimeApp struct {
+	*sysdtest.FakeApp
+	clock *sysdtest.Clock
+	at    chan time.Time
+}
+
+func (a *cancelTimeApp) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		a.at <- a.clock.Now()
+	}()
+	return a.FakeApp.Start(ctx)
+}
+
+func TestHungAppDoesNotHoldBackShutdownOfOthers(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithGracefulShutdownTimeout(10*time.Second))
+	db := &cancelTimeApp{FakeApp: sysdtest.NewFakeApp("db"), clock: h.Clock, at: make(chan time.Time, 1)}
+	// the long timeout of db makes the whole shutdown wait longer than http stops
+	if err := h.Systemd.Add(db, sysd.WithPriority(0), sysd.WithShutdownTimeout(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// http has no timeout of its own and stops after the graceful shutdown timeout
+	h.NewApp("http", sysd.WithPriority(1)).SetStopDelay(30 * time.Second)
+	h.Start()
+	h.WaitForState("db", sysd.AppRunning)
+	h.WaitForState("http", sysd.AppRunning)
+
+	begin := h.Clock.Now()
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	if waited := (<-db.at).Sub(begin); waited >= 30*time.Second {
+		t.Errorf("db cancelled after %s, want after the %s graceful shutdown timeout of http", waited, 10*time.Second)
+	}
+}
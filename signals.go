@@ -2,26 +2,45 @@ package sysd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 )
 
 // ContextWithSignals returns a context with by default is listening to
-// SIGHUP, SIGINT, SIGTERM, SIGQUIT os signals to cancel
+// SIGINT, SIGTERM, SIGQUIT os signals to cancel. SIGHUP reloads the apps instead, see SetReloadSignals
 func ContextWithSignals(sig ...os.Signal) context.Context {
+	ctx, _ := ContextWithSignalsStop(sig...)
+	return ctx
+}
+
+// ContextWithSignalsStop is like ContextWithSignals but also returns a stop function
+// that removes the signal handlers, restoring the default behavior, and cancels the context.
+// the cause of the context, see context.Cause, wraps ErrSignalReceived and names the signal
+func ContextWithSignalsStop(sig ...os.Signal) (context.Context, context.CancelFunc) {
 	if len(sig) == 0 {
-		sig = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+		sig = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
 	}
 
 	s := make(chan os.Signal, 1)
 	signal.Notify(s, sig...)
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancelCause(context.Background())
 	go func() {
-		<-s
-		cancel()
+		select {
+		case received := <-s:
+			cancel(fmt.Errorf("%w: %s", ErrSignalReceived, received))
+		case <-ctx.Done():
+		}
 	}()
-	return ctx
+
+	stop := func() {
+		signal.Stop(s)
+		cancel(nil)
+	}
+	return ctx, stop
 }
 
 // ShutdownGracefully will listen for context cancellation and call callback function if provided
@@ -33,9 +52,147 @@ func ShutdownGracefully(ctx context.Context, callback func() error) error {
 	return nil
 }
 
+type shutdownKey struct{}
+
+// shutdownState lets apps shut down the systemd service running them and see why it shuts down
+type shutdownState struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+func shutdownContext(ctx context.Context, shutdown context.CancelCauseFunc) context.Context {
+	return context.WithValue(ctx, shutdownKey{}, &shutdownState{ctx: ctx, cancel: shutdown})
+}
+
+// RequestShutdown asks the systemd service running the app to gracefully shut down all apps.
+// it should be called with the context passed to the app Start or Status, and returns false
+// if the context does not belong to a running systemd service
+func RequestShutdown(ctx context.Context) bool {
+	cause := ErrShutdownRequested
+	if name := AppName(ctx); name != "" {
+		cause = fmt.Errorf("%w by app %q", ErrShutdownRequested, name)
+	}
+	return requestShutdown(ctx, cause)
+}
+
+// requestShutdown shuts down the systemd service the context belongs to with the given cause
+func requestShutdown(ctx context.Context, cause error) bool {
+	state, ok := ctx.Value(shutdownKey{}).(*shutdownState)
+	if !ok {
+		return false
+	}
+	state.cancel(cause)
+	return true
+}
+
+// ShutdownCause returns why the systemd service running the app is shutting down, or nil if it is not.
+// the cause wraps ErrSignalReceived, ErrShutdownRequested or the error of an app which failed fatally,
+// so apps can decide how to drain. it should be called with the context passed to the app Start or Status
+func ShutdownCause(ctx context.Context) error {
+	state, ok := ctx.Value(shutdownKey{}).(*shutdownState)
+	if !ok {
+		return context.Cause(ctx)
+	}
+	return context.Cause(state.ctx)
+}
+
+// shutdownCause returns the cancellation cause of apps stopped by the shutdown of the running
+// systemd service, it wraps ErrShutdown and the shutdown cause
+func (s *Systemd) shutdownCause() error {
+	s.mu.RLock()
+	run := s.run
+	s.mu.RUnlock()
+
+	if run == nil {
+		return ErrShutdown
+	}
+	cause := ShutdownCause(run.ctx)
+	if cause == nil || errors.Is(cause, context.Canceled) {
+		return ErrShutdown
+	}
+	return fmt.Errorf("%w: %w", ErrShutdown, cause)
+}
+
 // WaitExitSignal get os signals
 func WaitExitSignal() os.Signal {
+	sig, _ := WaitExitSignalContext(context.Background())
+	return sig
+}
+
+// WaitExitSignalContext waits for an exit signal or the context to be done,
+// the signal handlers are removed before it returns
+func WaitExitSignalContext(ctx context.Context) (os.Signal, error) {
 	quit := make(chan os.Signal, 6)
 	signal.Notify(quit, syscall.SIGABRT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT)
-	return <-quit
+	defer signal.Stop(quit)
+
+	select {
+	case sig := <-quit:
+		return sig, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SignalHandler is called with the context of the running systemd service when a signal is received
+type SignalHandler func(ctx context.Context)
+
+// OnSignal registers a handler for the signal, called while the systemd service is running,
+// e.g. to dump goroutine stacks on SIGUSR1. handlers are called one at a time in registration order,
+// so they should return quickly. shutdown signals keep cancelling the context passed to Start
+func (s *Systemd) OnSignal(sig os.Signal, fn SignalHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.signalHandlers == nil {
+		s.signalHandlers = make(map[os.Signal][]SignalHandler)
+	}
+	s.signalHandlers[sig] = append(s.signalHandlers[sig], fn)
+	if s.signals != nil {
+		signal.Notify(s.signals, sig)
+	}
+}
+
+// handleSignals reloads the apps on reload signals and calls the registered signal handlers
+// until the context is done
+func (s *Systemd) handleSignals(ctx context.Context) {
+	c := make(chan os.Signal, 1)
+
+	s.mu.Lock()
+	s.signals = c
+	sig := append([]os.Signal{}, s.reloadSignals...)
+	for handled := range s.signalHandlers {
+		sig = append(sig, handled)
+	}
+	s.mu.Unlock()
+
+	if len(sig) > 0 {
+		signal.Notify(c, sig...)
+	}
+	defer func() {
+		s.mu.Lock()
+		s.signals = nil
+		s.mu.Unlock()
+		signal.Stop(c)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case received := <-c:
+			s.mu.RLock()
+			reload := slices.Contains(s.reloadSignals, received)
+			handlers := s.signalHandlers[received]
+			s.mu.RUnlock()
+
+			if reload {
+				s.logger.Info("Received %s, reloading apps", received)
+				_ = s.Reload(ctx)
+			}
+			for _, fn := range handlers {
+				fn(ctx)
+			}
+		}
+	}
 }
@@ -0,0 +1,219 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestRequestShutdown(t *testing.T) {
+	if sysd.RequestShutdown(context.Background()) {
+		t.Error("RequestShutdown without a systemd service returned true")
+	}
+
+	h := sysdtest.NewHarness(t)
+	other := h.NewApp("other")
+	requested := make(chan bool, 1)
+	err := h.Systemd.Add(sysd.AppFunc("fatal", func(ctx context.Context) error {
+		requested <- sysd.RequestShutdown(ctx)
+		<-ctx.Done()
+		return nil
+	}, nil), sysd.WithPriority(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+
+	if !<-requested {
+		t.Fatal("RequestShutdown from an app returned false")
+	}
+	if err := h.Wait(); err != nil {
+		t.Errorf("Start returned %v, want a graceful shutdown", err)
+	}
+	cause := other.Cause()
+	if !errors.Is(cause, sysd.ErrShutdownRequested) || !strings.Contains(cause.Error(), `"fatal"`) {
+		t.Errorf("other app cancelled with %v, want the shutdown requested by the fatal app", cause)
+	}
+}
+
+func TestContextWithSignalsStop(t *testing.T) {
+	running := goroutines("sysd.ContextWithSignalsStop.func")
+	ctx, stop := sysd.ContextWithSignalsStop(syscall.SIGUSR2)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(sysdtest.WaitTimeout):
+		t.Fatal("context not cancelled by the signal")
+	}
+	if cause := context.Cause(ctx); !errors.Is(cause, sysd.ErrSignalReceived) {
+		t.Errorf("context cancelled with %v, want %v", cause, sysd.ErrSignalReceived)
+	}
+	stop()
+
+	ctx, stop = sysd.ContextWithSignalsStop(syscall.SIGUSR2)
+	stop()
+	if cause := context.Cause(ctx); !errors.Is(cause, context.Canceled) {
+		t.Errorf("stopped context cancelled with %v, want %v", cause, context.Canceled)
+	}
+	waitGoroutines(t, "sysd.ContextWithSignalsStop.func", running)
+}
+
+// TestSignalsRestoredAfterStop checks in a child process that a signal kills the process
+// once the handlers are removed, since the default disposition can not be restored in the test process
+func TestSignalsRestoredAfterStop(t *testing.T) {
+	if mode := os.Getenv("SYSD_TEST_SIGNALS"); mode != "" {
+		switch mode {
+		case "context":
+			_, stop := sysd.ContextWithSignalsStop(syscall.SIGTERM)
+			stop()
+		case "wait":
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, _ = sysd.WaitExitSignalContext(ctx)
+		}
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		time.Sleep(sysdtest.WaitTimeout)
+		os.Exit(0)
+	}
+
+	for _, mode := range []string{"context", "wait"} {
+		t.Run(mode, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestSignalsRestoredAfterStop$")
+			cmd.Env = append(os.Environ(), "SYSD_TEST_SIGNALS="+mode)
+			err := cmd.Run()
+
+			var exit *exec.ExitError
+			if !errors.As(err, &exit) {
+				t.Fatalf("child returned %v, want it killed by SIGTERM", err)
+			}
+			status, ok := exit.Sys().(syscall.WaitStatus)
+			if !ok || !status.Signaled() || status.Signal() != syscall.SIGTERM {
+				t.Errorf("child exited with %v, want it killed by SIGTERM", err)
+			}
+		})
+	}
+}
+
+func TestOnSignal(t *testing.T) {
+	// the test process must survive the signals if they arrive before the handlers are registered
+	ignored := make(chan os.Signal, 2)
+	signal.Notify(ignored, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(ignored)
+
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app")
+	before := make(chan context.Context, 1)
+	h.Systemd.OnSignal(syscall.SIGUSR1, func(ctx context.Context) { before <- ctx })
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	// handlers registered while running are called too
+	during := make(chan struct{}, 1)
+	h.Systemd.OnSignal(syscall.SIGUSR2, func(context.Context) { during <- struct{}{} })
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ctx := <-before:
+		if ctx.Err() != nil {
+			t.Errorf("handler called with a done context: %v", ctx.Err())
+		}
+	case <-time.After(sysdtest.WaitTimeout):
+		t.Fatal("SIGUSR1 handler not called")
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-during:
+	case <-time.After(sysdtest.WaitTimeout):
+		t.Fatal("handler registered while running not called")
+	}
+
+	if h.Returned() || !app.Running() {
+		t.Error("handled signals shut down the apps")
+	}
+}
+
+func TestShutdownCause(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name     string
+		shutdown func(t *testing.T, s *sysd.Systemd, h *sysd.RunHandle)
+		want     error
+	}{
+		{
+			name: "signal",
+			shutdown: func(t *testing.T, _ *sysd.Systemd, _ *sysd.RunHandle) {
+				if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: sysd.ErrSignalReceived,
+		},
+		{
+			name: "operator",
+			shutdown: func(_ *testing.T, _ *sysd.Systemd, h *sysd.RunHandle) {
+				_ = h.Shutdown(context.Background())
+			},
+			want: sysd.ErrShutdownRequested,
+		},
+		{
+			name: "fatal app",
+			shutdown: func(t *testing.T, s *sysd.Systemd, _ *sysd.RunHandle) {
+				if err := s.Add(sysd.AppFunc("fatal", func(context.Context) error { return boom }, nil),
+					sysd.WithOnFailure(sysd.OnFailureShutdownAll)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: boom,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, stop := sysd.ContextWithSignalsStop(syscall.SIGUSR1)
+			defer stop()
+
+			s := sysd.New()
+			causes := make(chan error, 1)
+			err := s.Add(sysd.AppFunc("app", func(ctx context.Context) error {
+				<-ctx.Done()
+				causes <- sysd.ShutdownCause(ctx)
+				return nil
+			}, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h, err := s.StartAsync(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sysdtest.WaitForState(t, s, "app", sysd.AppRunning)
+
+			tt.shutdown(t, s, h)
+			select {
+			case cause := <-causes:
+				if !errors.Is(cause, tt.want) {
+					t.Errorf("shutdown cause is %v, want %v", cause, tt.want)
+				}
+			case <-time.After(sysdtest.WaitTimeout):
+				t.Fatal("app not stopped")
+			}
+			_ = h.Wait()
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrStageTimeout is returned by Start when the apps of a priority stage are not ready in time
+var ErrStageTimeout = errors.New("stage timeout")
+
+// SetStagedStart makes priorities act as stages: the apps of a priority are started only once all
+// apps of the previous priority are ready, or stopped. Start fails with ErrStageTimeout if a stage
+// is not ready within the stage timeout, zero waits as long as it takes
+func (s *Systemd) SetStagedStart(enabled bool, stageTimeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stagedStart = enabled
+	s.stageTimeout = stageTimeout
+}
+
+// startStages starts the apps, in stages of the same priority if staged start is enabled.
+// it returns the error of a stage which did not get ready, or of an app which failed meanwhile
+func (s *Systemd) startStages(run *runState, apps []*appItem) error {
+	s.mu.RLock()
+	staged, timeout := s.stagedStart, s.stageTimeout
+	s.mu.RUnlock()
+
+	if !staged {
+		for _, app := range apps {
+			_, _ = s.sendCommand(app, command{kind: cmdStart})
+		}
+		return nil
+	}
+
+	all := stages(apps)
+	for i, stage := range all {
+		for _, app := range stage {
+			_, _ = s.sendCommand(app, command{kind: cmdStart})
+		}
+		if i == len(all)-1 {
+			break
+		}
+
+		if err := s.waitForStage(run.ctx, stage, timeout); err != nil {
+			return err
+		}
+		// do not start the next stage if an app of this one brought the stack down
+		if err := run.firstFailure(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stages splits the apps, in start order, into runs of apps with the same priority
+func stages(apps []*appItem) [][]*appItem {
+	var all [][]*appItem
+	for i, app := range apps {
+		if i == 0 || app.priority != apps[i-1].priority {
+			all = append(all, nil)
+		}
+		all[len(all)-1] = append(all[len(all)-1], app)
+	}
+	return all
+}
+
+// waitForStage waits until every app of the stage is ready or no longer starting,
+// returns nil if the context is done first, Start handles the shutdown
+func (s *Systemd) waitForStage(ctx context.Context, stage []*appItem, timeout time.Duration) error {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := s.clock.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C()
+	}
+
+	ticker := s.clock.NewTicker(dependencyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		var waiting []string
+		for _, app := range stage {
+			if !s.stageSettled(ctx, app) {
+				waiting = append(waiting, app.Name())
+			}
+		}
+		if len(waiting) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline:
+			return fmt.Errorf("%w: priority %d apps not ready: %s", ErrStageTimeout, stage[0].priority, strings.Join(waiting, ", "))
+		case <-ticker.C():
+		}
+	}
+}
+
+// stageSettled returns true if the app is ready, or has stopped and will not get ready
+func (s *Systemd) stageSettled(ctx context.Context, app *appItem) bool {
+	switch s.appState(app) {
+	case AppStopped, AppFailed, AppQuarantined, AppPaused:
+		return true
+	}
+	return s.appReady(ctx, app)
+}
@@ -0,0 +1,118 @@
+package sysd
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// startLimiter limits how many apps start at the same time, waiting apps are let in by priority
+type startLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	seq     uint64
+	waiting []*startWaiter
+}
+
+type startWaiter struct {
+	priority int
+	seq      uint64
+	c        chan struct{}
+}
+
+// SetStartConcurrency sets how many apps may be starting at the same time, apps are starting until
+// they are ready or return from Start. apps over the limit wait in priority order, zero means no limit
+func (s *Systemd) SetStartConcurrency(n int) {
+	s.startLimiter.setLimit(n)
+}
+
+func (l *startLimiter) setLimit(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = n
+	l.wake()
+}
+
+// acquire waits for a free start slot or the context to be done
+func (l *startLimiter) acquire(ctx context.Context, priority int) error {
+	l.mu.Lock()
+	if l.limit <= 0 || (l.running < l.limit && len(l.waiting) == 0) {
+		l.running++
+		l.mu.Unlock()
+		return nil
+	}
+
+	l.seq++
+	w := &startWaiter{priority: priority, seq: l.seq, c: make(chan struct{})}
+	l.waiting = append(l.waiting, w)
+	sort.SliceStable(l.waiting, func(i, j int) bool {
+		if l.waiting[i].priority != l.waiting[j].priority {
+			return l.waiting[i].priority < l.waiting[j].priority
+		}
+		return l.waiting[i].seq < l.waiting[j].seq
+	})
+	l.mu.Unlock()
+
+	select {
+	case <-w.c:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, other := range l.waiting {
+			if other == w {
+				l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// the slot was given meanwhile, pass it on
+		l.running--
+		l.wake()
+		return ctx.Err()
+	}
+}
+
+// release frees a start slot
+func (l *startLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running--
+	l.wake()
+}
+
+// wake lets waiting apps in while there are free slots, l.mu must be held
+func (l *startLimiter) wake() {
+	for len(l.waiting) > 0 && (l.limit <= 0 || l.running < l.limit) {
+		w := l.waiting[0]
+		l.waiting = l.waiting[1:]
+		l.running++
+		close(w.c)
+	}
+}
+
+// limited returns true if a start concurrency limit is set
+func (l *startLimiter) limited() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit > 0
+}
+
+// releaseWhenReady calls release once the app is ready or done is closed, for apps
+// which do not report readiness themselves
+func (s *Systemd) releaseWhenReady(ctx context.Context, app *appItem, done <-chan struct{}, release func()) {
+	ticker := s.clock.NewTicker(dependencyCheckInterval)
+	defer ticker.Stop()
+
+	for !s.appReady(ctx, app) {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			release()
+			return
+		case <-ticker.C():
+		}
+	}
+	release()
+}
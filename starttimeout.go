@@ -0,0 +1,44 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStartTimeout is the failure of an app which did not get ready within its start timeout
+var ErrStartTimeout = errors.New("start timeout")
+
+// SetAppStartTimeout fails a start of the app which is not ready within the timeout, the app
+// is cancelled and its OnFailure policy applies. apps implementing ReadyReporter are ready once
+// they call MarkReady, others once they pass their status check. zero disables it
+func (s *Systemd) SetAppStartTimeout(appName string, timeout time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app, ok := s.apps[appName]; ok {
+		app.startTimeout = timeout
+		return nil
+	}
+
+	return ErrAppNotExists
+}
+
+// enforceStartTimeout cancels the start of the app with ErrStartTimeout if it is not ready
+// once the timeout expires, done is closed when the start returns
+func (s *Systemd) enforceStartTimeout(ctx context.Context, app *appItem, timeout time.Duration, done <-chan struct{}, cancel context.CancelCauseFunc) {
+	timer := s.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	case <-timer.C():
+		if s.appReady(ctx, app) {
+			return
+		}
+		s.logger.Error("app %q is not ready after %s, cancelling it", app.Name(), timeout)
+		cancel(fmt.Errorf("%w after %s", ErrStartTimeout, timeout))
+	}
+}
@@ -0,0 +1,53 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStartTimeoutAppliesOnFailure(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Hour))
+	// the app never marks itself ready, like a startup hung on a DNS blackhole
+	hung := &readyApp{FakeApp: sysdtest.NewFakeApp("hung"), release: make(chan struct{})}
+	err := h.Systemd.Add(hung, sysd.WithStartTimeout(10*time.Second),
+		sysd.WithOnFailure(sysd.OnFailureRestart.RetryTimeout(time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := h.Systemd.Subscribe()
+	h.Start()
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "hung")
+	if !errors.Is(e.Err, sysd.ErrStartTimeout) {
+		t.Errorf("app restarted for %v, want %v", e.Err, sysd.ErrStartTimeout)
+	}
+	if !errors.Is(hung.Cause(), sysd.ErrStartTimeout) {
+		t.Errorf("app cancelled with %v, want %v", hung.Cause(), sysd.ErrStartTimeout)
+	}
+}
+
+func TestStartTimeoutEndsOnceReady(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Hour))
+	app := &readyApp{FakeApp: sysdtest.NewFakeApp("app"), release: make(chan struct{})}
+	if err := h.Systemd.Add(app, sysd.WithStartTimeout(10*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app did not start")
+	}
+	close(app.release)
+	h.WaitForState("app", sysd.AppRunning)
+
+	for i := 0; i < 30; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	if app.Cause() != nil || app.Starts() != 1 {
+		t.Errorf("ready app cancelled with %v after %d starts, want it kept running", app.Cause(), app.Starts())
+	}
+}
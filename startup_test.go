@@ -0,0 +1,156 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStartFailurePolicy(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name   string
+		policy sysd.StartFailurePolicy
+	}{
+		{name: "abort", policy: sysd.StartFailureAbort},
+		{name: "continue", policy: sysd.StartFailureContinue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := sysdtest.NewHarness(t, sysd.WithStartFailurePolicy(tt.policy),
+				sysd.WithDefaultOnFailure(sysd.OnFailureRestart.Retry(2).RetryTimeout(time.Second)))
+			other := h.NewApp("other")
+			h.NewApp("batch").FailStarts(2, boom)
+			events := h.Systemd.Subscribe()
+			h.Start()
+			advanceUntilEvent(t, h, events, time.Second, sysd.EventAppStopped, "batch")
+
+			if tt.policy == sysd.StartFailureAbort {
+				if err := h.Wait(); !errors.Is(err, boom) {
+					t.Errorf("Start returned %v, want %v", err, boom)
+				}
+				return
+			}
+			h.WaitForState("batch", sysd.AppFailed)
+			if h.Returned() || !other.Running() {
+				t.Error("the other apps stopped with the failed app")
+			}
+		})
+	}
+}
+
+func TestStartFailureContinueKeepsShutdownAll(t *testing.T) {
+	boom := errors.New("boom")
+	h := sysdtest.NewHarness(t, sysd.WithStartFailurePolicy(sysd.StartFailureContinue))
+	h.NewApp("other")
+	h.NewApp("critical", sysd.WithOnFailure(sysd.OnFailureShutdownAll)).FailStarts(1, boom)
+	h.Start()
+
+	if err := h.Wait(); !errors.Is(err, boom) {
+		t.Errorf("Start returned %v, want the failure of the critical app %v", err, boom)
+	}
+}
+
+func TestStagedStartWaitsForPreviousStage(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStagedStart(0))
+	db := &readyApp{FakeApp: sysdtest.NewFakeApp("db"), release: make(chan struct{})}
+	if err := h.Systemd.Add(db, sysd.WithPriority(0)); err != nil {
+		t.Fatal(err)
+	}
+	api := h.NewApp("api", sysd.WithPriority(1))
+	h.Start()
+	if !db.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("first stage not started")
+	}
+
+	for i := 0; i < 10; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if api.Starts() != 0 {
+		t.Fatal("second stage started before the first one is ready")
+	}
+
+	close(db.release)
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !api.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(100 * time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !api.Running() {
+		t.Fatal("second stage not started once the first one is ready")
+	}
+}
+
+func TestStagedStartTimeout(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStagedStart(10*time.Second))
+	db := &readyApp{FakeApp: sysdtest.NewFakeApp("db"), release: make(chan struct{})}
+	if err := h.Systemd.Add(db, sysd.WithPriority(0)); err != nil {
+		t.Fatal(err)
+	}
+	api := h.NewApp("api", sysd.WithPriority(1))
+	h.Start()
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !h.Returned() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := h.Wait(); !errors.Is(err, sysd.ErrStageTimeout) {
+		t.Errorf("Start returned %v, want %v", err, sysd.ErrStageTimeout)
+	}
+	if api.Starts() != 0 {
+		t.Error("second stage started after the first one timed out")
+	}
+}
+
+func TestStartConcurrency(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStartConcurrency(1))
+	apps := make(map[string]*readyApp)
+	add := func(name string, priority int) {
+		t.Helper()
+		apps[name] = &readyApp{FakeApp: sysdtest.NewFakeApp(name), release: make(chan struct{})}
+		if err := h.Systemd.Add(apps[name], sysd.WithPriority(priority)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	started := func(name string) {
+		t.Helper()
+		if !apps[name].WaitRunning(sysdtest.WaitTimeout) {
+			t.Fatalf("app %q not started", name)
+		}
+	}
+	notStarted := func(names ...string) {
+		t.Helper()
+		time.Sleep(50 * time.Millisecond)
+		for _, name := range names {
+			if apps[name].Starts() != 0 {
+				t.Fatalf("app %q started over the start concurrency", name)
+			}
+		}
+	}
+
+	// the first app holds the only start slot until it is ready
+	add("first", 0)
+	h.Start()
+	started("first")
+
+	// apps added meanwhile wait, out of priority order
+	add("c", 3)
+	add("b", 2)
+	add("a", 1)
+	notStarted("a", "b", "c")
+
+	// every ready app lets in the next waiting app by priority
+	close(apps["first"].release)
+	started("a")
+	notStarted("b", "c")
+	close(apps["a"].release)
+	started("b")
+	notStarted("c")
+	close(apps["b"].release)
+	started("c")
+	close(apps["c"].release)
+}
@@ -0,0 +1,101 @@
+package sysd
+
+import "time"
+
+// AppState is the state of an app as seen by the systemd service
+type AppState int
+
+const (
+	// AppPending is an app which is not started yet, or is waiting for its dependencies
+	AppPending AppState = iota
+	// AppStarting is an app which is started but has not reported ready yet
+	AppStarting
+	// AppRunning is an app which is started and ready
+	AppRunning
+	// AppDegraded is a running app which reported degraded health in its last status check
+	AppDegraded
+	// AppRestarting is an app which failed and is about to be started again
+	AppRestarting
+	// AppStopped is an app which returned from Start without a failure
+	AppStopped
+	// AppFailed is an app which failed and will not be started again
+	AppFailed
+	// AppQuarantined is an app which restarted too often and waits for Unquarantine
+	AppQuarantined
+	// AppPaused is an app stopped by Pause which waits for Resume
+	AppPaused
+)
+
+var appStateNames = map[AppState]string{
+	AppPending:     "pending",
+	AppStarting:    "starting",
+	AppRunning:     "running",
+	AppDegraded:    "degraded",
+	AppRestarting:  "restarting",
+	AppStopped:     "stopped",
+	AppFailed:      "failed",
+	AppQuarantined: "quarantined",
+	AppPaused:      "paused",
+}
+
+// String returns the string representation of the AppState
+func (s AppState) String() string {
+	if name, ok := appStateNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// running returns true if the app is started and has not returned yet
+func (s AppState) running() bool {
+	return s == AppStarting || s == AppRunning || s == AppDegraded
+}
+
+// AppStatus is a point in time view of an app
+type AppStatus struct {
+	Name      string
+	State     AppState
+	Uptime    time.Duration
+	Restarts  int
+	LastError error
+}
+
+// Snapshot returns the status of all apps, sorted by priority then name
+func (s *Systemd) Snapshot() []AppStatus {
+	apps := s.appList()
+	now := s.clock.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]AppStatus, 0, len(apps))
+	for _, app := range apps {
+		statuses = append(statuses, AppStatus{
+			Name:      app.name,
+			State:     app.state,
+			Uptime:    app.uptime(now),
+			Restarts:  app.restarts,
+			LastError: app.lastErr,
+		})
+	}
+	return statuses
+}
+
+// setState moves the app to the given state, recording the error if any
+func (s *Systemd) setState(app *appItem, state AppState, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		app.lastErr = err
+	}
+	app.state = state
+	s.persist.markDirty()
+}
+
+// appState returns the current state of the app
+func (s *Systemd) appState(app *appItem) AppState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return app.state
+}
@@ -0,0 +1,73 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// appStatus returns the snapshot status of the app
+func appStatus(t *testing.T, s *sysd.Systemd, name string) sysd.AppStatus {
+	t.Helper()
+
+	for _, status := range s.Snapshot() {
+		if status.Name == name {
+			return status
+		}
+	}
+	t.Fatalf("app %q not in the snapshot", name)
+	return sysd.AppStatus{}
+}
+
+func TestSnapshotTracksAppStates(t *testing.T) {
+	boom := errors.New("boom")
+	// checks in flight must not time out as the clock is advanced
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second), sysd.WithStatusCheckTimeout(time.Hour))
+	app := h.NewApp("app")
+	h.NewApp("flaky", sysd.WithOnFailure(sysd.OnFailureRestart.RetryTimeout(time.Second))).FailStarts(1, boom)
+	for _, name := range []string{"app", "flaky"} {
+		if state := appStatus(t, h.Systemd, name).State; state != sysd.AppPending {
+			t.Errorf("app %q is %s before Start, want %s", name, state, sysd.AppPending)
+		}
+	}
+
+	events := h.Systemd.Subscribe()
+	h.Start()
+	advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "flaky")
+	h.WaitForState("flaky", sysd.AppRunning)
+	flaky := appStatus(t, h.Systemd, "flaky")
+	if flaky.Restarts != 1 || !errors.Is(flaky.LastError, boom) {
+		t.Errorf("flaky app restarted %d times with last error %v, want once with %v", flaky.Restarts, flaky.LastError, boom)
+	}
+
+	h.WaitForState("app", sysd.AppRunning)
+	before := appStatus(t, h.Systemd, "app").Uptime
+	app.SetStatus(sysd.Degraded(errors.New("slow")))
+	// a check in flight may see the degraded status already, the clock moves on at least once
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); ; {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+		if appStatus(t, h.Systemd, "app").State == sysd.AppDegraded || time.Now().After(deadline) {
+			break
+		}
+	}
+	status := appStatus(t, h.Systemd, "app")
+	if status.State != sysd.AppDegraded {
+		t.Errorf("app is %s with a degraded status, want %s", status.State, sysd.AppDegraded)
+	}
+	if status.Uptime <= before {
+		t.Errorf("app uptime is %s, want it to grow with the clock past %s", status.Uptime, before)
+	}
+
+	if err := h.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"app", "flaky"} {
+		if state := appStatus(t, h.Systemd, name).State; state != sysd.AppStopped {
+			t.Errorf("app %q is %s after shutdown, want %s", name, state, sysd.AppStopped)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package sysd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+type fileStateStore struct {
+	path string
+}
+
+// FileStateStore persists the supervisor state to a JSON file at path, the file is replaced
+// atomically on every save
+func FileStateStore(path string) StateStore {
+	return &fileStateStore{path: path}
+}
+
+func (f *fileStateStore) Load(_ context.Context) (SavedState, error) {
+	var state SavedState
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(b, &state)
+	return state, err
+}
+
+func (f *fileStateStore) Save(_ context.Context, state SavedState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
@@ -0,0 +1,70 @@
+package sysd
+
+import "time"
+
+// AppStats is the uptime and failure statistics of an app, e.g. for SLO reporting
+type AppStats struct {
+	Name  string
+	State AppState
+	// Uptime is the time since the current run of the app started, zero if it is not running.
+	// TotalUptime also counts all finished runs
+	Uptime      time.Duration
+	TotalUptime time.Duration
+	Restarts    int
+	// Failures counts every failed start, panic and failed status check of the app
+	Failures int
+	// LastFailure and LastFailureReason describe the last failure, zero if the app never failed
+	LastFailure       time.Time
+	LastFailureReason string
+	// MeanTimeBetweenFailures is the total uptime divided by the failures, zero if the app never failed
+	MeanTimeBetweenFailures time.Duration
+}
+
+// Stats returns the uptime and failure statistics of the apps, sorted by priority then name
+func (s *Systemd) Stats() []AppStats {
+	apps := s.appList()
+	now := s.clock.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]AppStats, 0, len(apps))
+	for _, app := range apps {
+		uptime := app.uptime(now)
+		st := AppStats{
+			Name:        app.name,
+			State:       app.state,
+			Uptime:      uptime,
+			TotalUptime: app.runTime + uptime,
+			Restarts:    app.restarts,
+			Failures:    app.totalFailures,
+			LastFailure: app.lastFailure,
+		}
+		if app.lastFailureErr != nil {
+			st.LastFailureReason = app.lastFailureErr.Error()
+		}
+		if st.Failures > 0 {
+			st.MeanTimeBetweenFailures = st.TotalUptime / time.Duration(st.Failures)
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+// recordFailure records a failure of the app for Stats
+func (s *Systemd) recordFailure(app *appItem, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app.totalFailures++
+	app.lastFailure = s.clock.Now()
+	app.lastFailureErr = err
+}
+
+// recordRun records a finished run of the app for Stats
+func (s *Systemd) recordRun(app *appItem, took time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app.runTime += took
+}
@@ -0,0 +1,53 @@
+package sysd_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStatsTrackRestartsAndFailures(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app")
+	h.NewApp("other")
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	h.Clock.Advance(10 * time.Second)
+
+	app.FlapStatus(errors.New("unhealthy"))
+	failed := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "app")
+	advanceUntilEvent(t, h, events, 0, sysd.EventAppStarted, "app")
+	h.Clock.Advance(5 * time.Second)
+
+	var st sysd.AppStats
+	for _, s := range h.Systemd.Stats() {
+		if s.Name == "app" {
+			st = s
+		}
+	}
+	if st.Restarts != 1 || st.Failures != 1 {
+		t.Errorf("app restarted %d times and failed %d times, want 1 and 1", st.Restarts, st.Failures)
+	}
+	if !st.LastFailure.Equal(failed.Time) || !strings.Contains(st.LastFailureReason, "unhealthy") {
+		t.Errorf("last failure is %q at %s, want unhealthy at %s", st.LastFailureReason, st.LastFailure, failed.Time)
+	}
+	if st.Uptime != 5*time.Second {
+		t.Errorf("uptime is %s, want 5s since the restart", st.Uptime)
+	}
+	if st.TotalUptime < 15*time.Second {
+		t.Errorf("total uptime is %s, want both runs counted", st.TotalUptime)
+	}
+	if st.MeanTimeBetweenFailures != st.TotalUptime {
+		t.Errorf("mean time between failures is %s, want the total uptime %s", st.MeanTimeBetweenFailures, st.TotalUptime)
+	}
+	for _, s := range h.Systemd.Stats() {
+		if s.Name == "other" && (s.Failures != 0 || s.MeanTimeBetweenFailures != 0 || !s.LastFailure.IsZero()) {
+			t.Errorf("healthy app stats are %+v", s)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package sysd_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestPerAppStatusInterval(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Hour))
+	fast := h.NewApp("cache", sysd.WithStatusInterval(time.Second))
+	slow := h.NewApp("db", sysd.WithStatusInterval(30*time.Second))
+	h.Start()
+	h.WaitForState("db", sysd.AppRunning)
+
+	for i := 0; i < 60; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := fast.StatusChecks(); n < 30 {
+		t.Errorf("cheap check ran %d times in a minute, want about every second", n)
+	}
+	if n := slow.StatusChecks(); n < 1 || n > 3 {
+		t.Errorf("expensive check ran %d times in a minute, want about every 30s", n)
+	}
+}
+
+func TestPerAppStatusTimeout(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	app := &hangingApp{FakeApp: sysdtest.NewFakeApp("hung")}
+	if err := h.Systemd.Add(app, sysd.WithStatusTimeout(2*time.Second), sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+	other := h.NewApp("other")
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("hung", sysd.AppRunning)
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "hung")
+	if !errors.Is(e.Err, sysd.ErrStatusTimeout) {
+		t.Errorf("app failed with %v, want %v", e.Err, sysd.ErrStatusTimeout)
+	}
+	// the hung check does not block the checks of other apps
+	if other.StatusChecks() == 0 {
+		t.Error("other app not status checked while the check of the hung app hangs")
+	}
+}
+
+func TestStatusCheckTimesOutAfterInterval(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	// no status timeout is set, the check of the app times out once the next one is due
+	app := &hangingApp{FakeApp: sysdtest.NewFakeApp("hung")}
+	if err := h.Systemd.Add(app, sysd.WithStatusInterval(3*time.Second), sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+	h.NewApp("other")
+	events := h.Systemd.Subscribe()
+	h.Start()
+	h.WaitForState("hung", sysd.AppRunning)
+
+	e := advanceUntilEvent(t, h, events, time.Second, sysd.EventAppFailed, "hung")
+	if !errors.Is(e.Err, sysd.ErrStatusTimeout) || !strings.Contains(e.Err.Error(), "after 3s") {
+		t.Errorf("app failed with %v, want %v after the status interval", e.Err, sysd.ErrStatusTimeout)
+	}
+}
@@ -0,0 +1,93 @@
+package sysd
+
+import "errors"
+
+// ErrRestartedWithApp is the cancellation cause of an app restarted by the restart strategy
+// because another app failed
+var ErrRestartedWithApp = errors.New("restarted with a failed app")
+
+// RestartStrategy decides which apps are restarted along with a failed app
+type RestartStrategy int
+
+const (
+	// OneForOne restarts only the failed app
+	OneForOne RestartStrategy = iota
+	// OneForAll restarts all running apps when one of them fails
+	OneForAll
+	// RestForOne restarts the failed app and the running apps started after it,
+	// which includes the apps depending on it
+	RestForOne
+)
+
+// SetRestartStrategy sets which apps are restarted along with an app that failed its status check
+func (s *Systemd) SetRestartStrategy(strategy RestartStrategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restartStrategy = strategy
+}
+
+// restartGroup returns the apps to restart along with the failed app, in start order
+func (s *Systemd) restartGroup(failed *appItem) []*appItem {
+	s.mu.RLock()
+	strategy := s.restartStrategy
+	s.mu.RUnlock()
+
+	if strategy == OneForOne {
+		return []*appItem{failed}
+	}
+
+	var group []*appItem
+	found := false
+	for _, app := range s.startOrder(s.appList()) {
+		if app == failed {
+			found = true
+			group = append(group, app)
+			continue
+		}
+		if !s.appState(app).running() {
+			continue
+		}
+		if strategy == OneForAll || found {
+			group = append(group, app)
+		}
+	}
+	return group
+}
+
+// restartApps asks the actors of the apps to stop them in reverse order, then to start
+// them again in order. apps stopped or paused meanwhile are left alone
+func (s *Systemd) restartApps(run *runState, failed *appItem, group []*appItem, cause error) {
+	// strategies restarting several apps must not interleave
+	s.restartMu.Lock()
+	defer s.restartMu.Unlock()
+
+	_, end := s.startSpan(run.ctx, OperationRestart, failed.Name())
+	defer end(cause)
+
+	if run.ctx.Err() != nil {
+		return
+	}
+
+	restarted := make([]*appItem, 0, len(group))
+	for i := len(group) - 1; i >= 0; i-- {
+		appCause := cause
+		if group[i] != failed {
+			appCause = ErrRestartedWithApp
+		}
+		// the stopped apps are held, so the stack is not seen as stopped in between
+		sent, err := s.sendCommand(group[i], command{kind: cmdStop, cause: appCause, hold: true, onlyRunning: true})
+		if !sent || errors.Is(err, errAppNotRunning) {
+			continue
+		}
+		if group[i] != failed {
+			s.logger.Info("Restarting app %q along with %q", group[i].Name(), failed.Name())
+		}
+		s.setState(group[i], AppRestarting, nil)
+		restarted = append([]*appItem{group[i]}, restarted...)
+	}
+
+	for _, app := range restarted {
+		s.emit(EventAppRestarted, app.Name(), cause)
+		_, _ = s.sendCommand(app, command{kind: cmdStart, restored: true})
+	}
+}
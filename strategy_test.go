@@ -0,0 +1,79 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestStatusFailureRestartsOnlyApp(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(20*time.Millisecond))
+	app := h.NewApp("only", sysd.WithOnFailure(sysd.OnFailureRestart.Retry(3).RetryTimeout(10*time.Millisecond)))
+	h.Start()
+	h.WaitForState("only", sysd.AppRunning)
+
+	app.FlapStatus(errors.New("unhealthy"))
+	h.Clock.Advance(20 * time.Millisecond)
+	h.WaitForEvent(sysd.EventAppRestarted, "only")
+	h.WaitForState("only", sysd.AppRunning)
+
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app was not started again")
+	}
+	if got := app.Starts(); got != 2 {
+		t.Fatalf("app started %d times, want 2", got)
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Start returned %v, want nil", err)
+	}
+}
+
+func TestRestartStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy sysd.RestartStrategy
+		want     map[string]int
+	}{
+		{name: "one for one", strategy: sysd.OneForOne, want: map[string]int{"producer": 1, "pipeline": 2, "consumer": 1}},
+		{name: "one for all", strategy: sysd.OneForAll, want: map[string]int{"producer": 2, "pipeline": 2, "consumer": 2}},
+		{name: "rest for one", strategy: sysd.RestForOne, want: map[string]int{"producer": 1, "pipeline": 2, "consumer": 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(20*time.Millisecond))
+			h.Systemd.SetRestartStrategy(tt.strategy)
+			names := []string{"producer", "pipeline", "consumer"}
+			apps := make(map[string]*sysdtest.FakeApp)
+			for i, name := range names {
+				apps[name] = h.NewApp(name, sysd.WithPriority(i),
+					sysd.WithOnFailure(sysd.OnFailureRestart.Retry(3).RetryTimeout(10*time.Millisecond)))
+			}
+			h.Start()
+			h.WaitForState("consumer", sysd.AppRunning)
+
+			apps["pipeline"].FlapStatus(errors.New("unhealthy"))
+			h.Clock.Advance(20 * time.Millisecond)
+			// restarted apps are started again in start order
+			for _, name := range names {
+				if tt.want[name] > 1 {
+					h.WaitForEvent(sysd.EventAppRestarted, name)
+				}
+			}
+			for name, app := range apps {
+				h.WaitForState(name, sysd.AppRunning)
+				if got := app.Starts(); got != tt.want[name] {
+					t.Errorf("app %q started %d times, want %d", name, got, tt.want[name])
+				}
+			}
+			for name, starts := range tt.want {
+				if cause := apps[name].Cause(); starts > 1 && name != "pipeline" && !errors.Is(cause, sysd.ErrRestartedWithApp) {
+					t.Errorf("app %q cancelled with %v, want %v", name, cause, sysd.ErrRestartedWithApp)
+				}
+			}
+		})
+	}
+}
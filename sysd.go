@@ -5,7 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -14,6 +20,8 @@ const (
 	GracefulShutdownTimeout = 20 * time.Second
 	// StatusCheckInterval is the default status check interval
 	StatusCheckInterval = 5 * time.Second
+	// StatusCheckConcurrency is the default number of status checks running at the same time
+	StatusCheckConcurrency = 10
 )
 
 var (
@@ -21,6 +29,11 @@ var (
 	OnFailureRestart *OnFailure = &OnFailure{name: "restart", retry: 3, retryTimeout: 5 * time.Second}
 	// OnFailureIgnore will ignore the app failure
 	OnFailureIgnore *OnFailure = &OnFailure{name: "ignore"}
+	// OnFailureShutdownAll will gracefully shut down all apps if the app fails, Start returns the app error
+	OnFailureShutdownAll *OnFailure = &OnFailure{name: "shutdown_all"}
+	// OnFailureRestartBackoff will restart the app every time it fails, waiting exponentially longer
+	// between consecutive restarts, from one second up to one minute
+	OnFailureRestartBackoff *OnFailure = OnFailureRestart.Retry(math.MaxInt).RetryTimeout(time.Second).Backoff(time.Minute, 2, 0.1)
 
 	// ErrAppAlreadyExists is returned when an app is added to the systemd service
 	// but an app with the same name already exists
@@ -28,6 +41,70 @@ var (
 
 	// ErrAppNotExists is returned when an app is not found in the systemd service
 	ErrAppNotExists = errors.New("app not exists")
+
+	// ErrStatusCheckFailed is the cancellation cause of an app stopped after a failed status check,
+	// apps can read it with context.Cause during cleanup
+	ErrStatusCheckFailed = errors.New("status check failed")
+
+	// ErrShutdown is the cancellation cause of apps stopped because the systemd service is shutting down
+	ErrShutdown = errors.New("shutdown")
+
+	// ErrSignalReceived is the shutdown cause when a shutdown signal is received, see ContextWithSignals
+	ErrSignalReceived = errors.New("signal received")
+
+	// ErrShutdownRequested is the shutdown cause when an app or the operator requested a shutdown
+	ErrShutdownRequested = errors.New("shutdown requested")
+
+	// ErrAppStopped is the cancellation cause of an app stopped with StopApp
+	ErrAppStopped = errors.New("app stopped")
+
+	// ErrAppRestarted is the cancellation cause of an app stopped by RestartApp
+	ErrAppRestarted = errors.New("app restarted")
+
+	// ErrAppRemoved is the cancellation cause of an app stopped because it was removed
+	ErrAppRemoved = errors.New("app removed")
+
+	// ErrAppReplaced is the cancellation cause of an app stopped because it was replaced
+	ErrAppReplaced = errors.New("app replaced")
+
+	// ErrRestartBudgetExceeded is returned by Start when apps restarted more than the
+	// restart budget allows, the apps are shut down gracefully before it is returned
+	ErrRestartBudgetExceeded = errors.New("restart budget exceeded")
+
+	// ErrAlreadyRunning is returned when starting a systemd service which is already running
+	ErrAlreadyRunning = errors.New("systemd is already running")
+
+	// ErrNotRunning is returned when an operation needs the systemd service to be running
+	ErrNotRunning = errors.New("systemd is not running")
+
+	// ErrAllAppsStopped is returned by Start when all apps have stopped on their own
+	// and the AllStoppedError policy is set
+	ErrAllAppsStopped = errors.New("all apps stopped")
+)
+
+// AllStoppedPolicy represents what Start does when all apps have stopped on their own
+// while the context is still alive
+type AllStoppedPolicy int
+
+const (
+	// AllStoppedReturn makes Start return nil once all apps have stopped
+	AllStoppedReturn AllStoppedPolicy = iota
+	// AllStoppedError makes Start return ErrAllAppsStopped once all apps have stopped
+	AllStoppedError
+	// AllStoppedWait keeps Start blocking until the context is cancelled
+	AllStoppedWait
+)
+
+// StartFailurePolicy represents what Start does when an app fails to start
+// after its OnFailure retries are used up
+type StartFailurePolicy int
+
+const (
+	// StartFailureAbort shuts down all apps and makes Start return the error of the failed app
+	StartFailureAbort StartFailurePolicy = iota
+	// StartFailureContinue marks the failed app as failed and keeps the other apps running,
+	// apps whose OnFailure policy shuts down all apps still do
+	StartFailureContinue
 )
 
 // OnFailure is an enum that represents the action to take when an app fails
@@ -35,6 +112,10 @@ type OnFailure struct {
 	name         string
 	retry        int
 	retryTimeout time.Duration
+	resetAfter   time.Duration
+	backoff      *backoff
+	fn           FailureFunc
+	policy       RestartPolicy
 }
 
 // Equal returns true if the OnFailure is equal to the target
@@ -47,16 +128,35 @@ func (o *OnFailure) String() string {
 	return o.name
 }
 
-// Retry set OnFailure number of retries
+// Retry returns a copy of the OnFailure with the given number of retries
 func (o *OnFailure) Retry(retry int) *OnFailure {
-	o.retry = retry
-	return o
+	c := o.clone()
+	c.retry = retry
+	return c
 }
 
-// RetryTimeout set OnFailure retry timeout
+// RetryTimeout returns a copy of the OnFailure with the given retry timeout
 func (o *OnFailure) RetryTimeout(retryTimeout time.Duration) *OnFailure {
-	o.retryTimeout = retryTimeout
-	return o
+	c := o.clone()
+	c.retryTimeout = retryTimeout
+	return c
+}
+
+// ResetAfter returns a copy of the OnFailure which forgets earlier failures of an app which ran
+// for at least d before failing, so apps failing once in a long while do not use up their retries
+func (o *OnFailure) ResetAfter(d time.Duration) *OnFailure {
+	c := o.clone()
+	c.resetAfter = d
+	return c
+}
+
+func (o *OnFailure) clone() *OnFailure {
+	c := *o
+	if o.backoff != nil {
+		b := *o.backoff
+		c.backoff = &b
+	}
+	return &c
 }
 
 // App is an interface that represents an app
@@ -75,51 +175,335 @@ type appItem struct {
 	name      string
 	onFailure *OnFailure
 	priority  int
+	dependsOn []string
+
+	// shutdownTimeout is how long the app is given to stop, zero uses the graceful shutdown timeout
+	shutdownTimeout time.Duration
+
+	// statusInterval and statusTimeout override the status check interval and timeout of the app,
+	// zero uses the systemd service ones
+	statusInterval time.Duration
+	statusTimeout  time.Duration
+	// lastStatusCheck is the time the status of the app was last checked
+	lastStatusCheck time.Time
+	// statusErr is the result of the last status check, started at statusAt, see appReady
+	statusErr error
+	statusAt  time.Time
+	// checking is true while a status check of the app is in flight
+	checking atomic.Bool
+
+	// maintenance suppresses acting on failed status checks of the app
+	maintenance maintenance
+
+	// actor handles the lifecycle commands of the app while the systemd service is running
+	actor *actor
+
+	// panicPolicy overrides the panic policy of the systemd service if set
+	panicPolicy *PanicPolicy
+
+	// task is set for apps running a Task, they run to completion and are not health checked
+	task bool
+
+	// startTimeout is how long a start may take until the app is ready
+	startTimeout time.Duration
+
+	// startupGrace is how long after a (re)start the app status is not checked, unless it is ready
+	startupGrace time.Duration
+
+	// statusChecks, statusCheckFailures and statusCheckDuration are collected for Metrics
+	statusChecks        int
+	statusCheckFailures int
+	statusCheckDuration time.Duration
+
+	// totalFailures, lastFailure and lastFailureErr track every failure of the app for Stats,
+	// runTime is the total time spent in finished runs
+	totalFailures  int
+	lastFailure    time.Time
+	lastFailureErr error
+	runTime        time.Duration
+
+	// heartbeatTimeout is how long the app may go without calling Heartbeat, lastHeartbeat
+	// holds the unix nano time of the last call
+	heartbeatTimeout time.Duration
+	lastHeartbeat    atomic.Int64
+
+	// idleTimeout is how long the app may report no activity before it is unhealthy
+	idleTimeout time.Duration
+
+	// state, restarts and lastErr track the runtime state of the app
+	state    AppState
+	restarts int
+	lastErr  error
+	// failures counts the consecutive failed status checks followed by a restart
+	failures int
+	// crashLoop counts the restarts of the app within the crash loop window
+	crashLoop restartBudget
+
+	// ready tracks the readiness of the last started run of the app
+	ready *readyState
+
+	// startedAt holds the unix nano time of the last (re)start, zero if the app is not running
+	startedAt atomic.Int64
+	// done is closed when the last started run of the app returns, nil if never started
+	done chan struct{}
+	// cancel stops the last started run of the app with a cause, nil if never started
+	cancel context.CancelCauseFunc
+}
+
+// uptime returns the duration since the last (re)start of the app
+func (a *appItem) uptime(now time.Time) time.Duration {
+	startedAt := a.startedAt.Load()
+	if startedAt == 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(0, startedAt))
 }
 
 // Systemd is a struct that represents a systemd service
 type Systemd struct {
-	apps             map[string]appItem
+	mu               sync.RWMutex
+	apps             map[string]*appItem
 	defaultOnFailure *OnFailure
+	allStoppedPolicy AllStoppedPolicy
+	startFailure     StartFailurePolicy
+
+	// panicPolicy is what happens when apps panic
+	panicPolicy PanicPolicy
+
+	// maintenance suppresses acting on failed status checks of all apps
+	maintenance maintenance
+
+	// stagedStart starts the apps of a priority once the previous priority is ready
+	stagedStart  bool
+	stageTimeout time.Duration
+
+	statusMiddlewares []StatusMiddleware
+	startMiddlewares  []StartMiddleware
+
+	// handle is the run started by Run
+	handle *RunHandle
+
+	// frozen pauses status checks while apps are frozen
+	frozen atomic.Bool
+	// watcherTick and watcherInterval are the last tick and the interval of the running
+	// status watcher, see Alive
+	watcherTick     atomic.Int64
+	watcherInterval atomic.Int64
+
+	// restartBudget limits the restarts of all apps together
+	restartBudget restartBudget
+
+	// startLimiter limits how many apps start at the same time
+	startLimiter startLimiter
+
+	// restartStrategy decides which apps are restarted along with a failed app
+	restartStrategy RestartStrategy
+	restartMu       sync.Mutex
+
+	// listenerProvider hands pre-opened listeners to apps, nil for InheritedListeners
+	listenerProvider ListenerProvider
+
+	// reloadSignals reload the apps while running, signalHandlers are called on their signals
+	// which are delivered to signals while running
+	reloadSignals  []os.Signal
+	signalHandlers map[os.Signal][]SignalHandler
+	signals        chan os.Signal
+
+	// tracer traces lifecycle operations, nil when tracing is disabled
+	tracer Tracer
+
+	// shutdowns and shutdownDuration are collected for Metrics
+	shutdowns        int
+	shutdownDuration time.Duration
+
+	// crashLoopRestarts restarts within crashLoopWindow quarantine an app
+	crashLoopRestarts int
+	crashLoopWindow   time.Duration
+
+	// nested is set for Groups, which leave the service manager and the signals to the root
+	nested bool
+	// upgraded is set once the service manager was told the new process is the main one on Upgrade
+	upgraded atomic.Bool
+
+	// run is the state of the running Start call, nil if not running
+	run *runState
+
+	subscribers []chan Event
+
+	// finalizers run once all apps have stopped
+	finalizers []finalizer
+
+	// resources are the values apps share with Provide and Resolve
+	resources resources
+	bus       Bus
+	// audit writes the lifecycle events to the audit sinks
+	audit auditor
+	// alerts notifies the failure notifiers
+	alerts alerter
+	// persist saves the supervisor state to the state store
+	persist persister
 
 	logger *logger
+	// clock is the time source of the timers and tickers
+	clock Clock
 
 	graceFullShutdownTimeout time.Duration
 	statusCheckInterval      time.Duration
+	statusCheckConcurrency   int
+	statusCheckTimeout       time.Duration
+	// statusIntervalChanged notifies the running status watcher to reset its ticker
+	statusIntervalChanged chan struct{}
 }
 
-// New returns a new Systemd struct
-func New() *Systemd {
-	return &Systemd{
+// New returns a new Systemd struct configured with the given options
+func New(opts ...Option) *Systemd {
+	s := &Systemd{
 		graceFullShutdownTimeout: GracefulShutdownTimeout,
 		statusCheckInterval:      StatusCheckInterval,
+		statusCheckConcurrency:   StatusCheckConcurrency,
+		statusIntervalChanged:    make(chan struct{}, 1),
 
 		defaultOnFailure: OnFailureRestart,
-		logger:           &logger{l: log.Default()},
+		reloadSignals:    []os.Signal{syscall.SIGHUP},
+		logger:           newLogger(log.Default()),
+		clock:            RealClock,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Add adds an app to the systemd service
-func (s *Systemd) Add(app App) error {
+// Add adds an app to the systemd service configured with the given options,
+// if the systemd service is running the app is started right away
+func (s *Systemd) Add(app App, opts ...AddOption) error {
+	s.mu.Lock()
 	if s.apps == nil {
-		s.apps = make(map[string]appItem)
+		s.apps = make(map[string]*appItem)
 	}
 	if _, ok := s.apps[app.Name()]; ok {
+		s.mu.Unlock()
 		s.logger.Error("app %q is already exist in systemd stack", app.Name())
 		return ErrAppAlreadyExists
 	}
-	s.apps[app.Name()] = appItem{
+	item := &appItem{
 		App:       app,
 		name:      app.Name(),
-		onFailure: s.defaultOnFailure,
+		onFailure: s.defaultOnFailure.clone(),
 		priority:  0,
 	}
+	for _, opt := range opts {
+		opt(item)
+	}
+	if err := s.checkNewDependencies(item.name, item.dependsOn); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.apps[app.Name()] = item
+	run := s.run
+	s.mu.Unlock()
+
+	if run != nil {
+		if item.statusInterval > 0 {
+			s.notifyStatusIntervalChanged()
+		}
+		if s.spawnActor(run, item, false) {
+			_, _ = s.sendCommand(item, command{kind: cmdStart})
+		}
+	}
+	return nil
+}
+
+// Remove removes an app from the systemd service, stopping it if it is running.
+// if drain is true Remove waits for the app to stop, at most for its shutdown timeout
+func (s *Systemd) Remove(appName string, drain bool) error {
+	s.mu.Lock()
+	app, ok := s.apps[appName]
+	if !ok {
+		s.mu.Unlock()
+		return ErrAppNotExists
+	}
+	delete(s.apps, appName)
+	cancel := app.cancel
+	s.mu.Unlock()
+
+	s.logger.Info("Removing app %q", appName)
+	if sent, _ := s.sendCommand(app, command{kind: cmdRemove, cause: ErrAppRemoved, drain: drain}); sent {
+		return nil
+	}
+	if drain {
+		_ = s.stopApp(context.Background(), app, ErrAppRemoved)
+	} else if cancel != nil {
+		cancel(ErrAppRemoved)
+	}
+	return nil
+}
+
+// Replace swaps the app registered under the same name with the given one, keeping its
+// configuration unless overridden by the given options. if the systemd service is running,
+// the old app is stopped and the new one is started
+func (s *Systemd) Replace(app App, opts ...AddOption) error {
+	s.mu.Lock()
+	old, ok := s.apps[app.Name()]
+	if !ok {
+		s.mu.Unlock()
+		return ErrAppNotExists
+	}
+	item := &appItem{
+		App:              app,
+		name:             app.Name(),
+		onFailure:        old.onFailure.clone(),
+		priority:         old.priority,
+		dependsOn:        old.dependsOn,
+		shutdownTimeout:  old.shutdownTimeout,
+		statusInterval:   old.statusInterval,
+		statusTimeout:    old.statusTimeout,
+		startupGrace:     old.startupGrace,
+		startTimeout:     old.startTimeout,
+		panicPolicy:      old.panicPolicy,
+		task:             old.task,
+		heartbeatTimeout: old.heartbeatTimeout,
+		idleTimeout:      old.idleTimeout,
+	}
+	for _, opt := range opts {
+		opt(item)
+	}
+	if err := s.checkNewDependencies(item.name, item.dependsOn); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.apps[app.Name()] = item
+	run := s.run
+	s.mu.Unlock()
+
+	// the new app is held until started, so the stack is not seen as stopped in between
+	started := run != nil && s.spawnActor(run, item, true)
+
+	s.logger.Info("Replacing app %q", app.Name())
+	if sent, _ := s.sendCommand(old, command{kind: cmdRemove, cause: ErrAppReplaced, drain: true}); !sent {
+		_ = s.stopApp(context.Background(), old, ErrAppReplaced)
+	}
+
+	if started {
+		_, _ = s.sendCommand(item, command{kind: cmdStart})
+	}
+	return nil
+}
+
+// AddAll adds the apps to the systemd service in order,
+// it stops and returns on the first app that can not be added
+func (s *Systemd) AddAll(apps ...App) error {
+	for _, app := range apps {
+		if err := s.Add(app); err != nil {
+			return fmt.Errorf("add app %q: %w", app.Name(), err)
+		}
+	}
 	return nil
 }
 
 // SetLogger sets the logger
 func (s *Systemd) SetLogger(l Logger) {
-	s.logger = &logger{l: l}
+	s.logger.setLogger(l)
 }
 
 // SetGraceFulShutdownTimeout sets the graceful shutdown timeout
@@ -127,157 +511,827 @@ func (s *Systemd) SetGraceFulShutdownTimeout(t time.Duration) {
 	s.graceFullShutdownTimeout = t
 }
 
-// SetStatusCheckInterval sets the status check interval
+// SetStatusCheckInterval sets the status check interval,
+// it can be called while the systemd service is running
 func (s *Systemd) SetStatusCheckInterval(t time.Duration) {
+	s.mu.Lock()
 	s.statusCheckInterval = t
+	s.mu.Unlock()
+
+	s.notifyStatusIntervalChanged()
+}
+
+// SetStatusCheckConcurrency sets the maximum number of status checks running at the same time,
+// it takes effect on the next Start
+func (s *Systemd) SetStatusCheckConcurrency(n int) {
+	s.statusCheckConcurrency = n
+}
+
+// SetStatusCheckTimeout fails status checks which do not return in time,
+// for apps without their own status timeout. zero, the default, times a check out
+// after the status check interval of the app
+func (s *Systemd) SetStatusCheckTimeout(t time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCheckTimeout = t
+}
+
+// statusTickInterval returns how often the status watcher wakes up, the shortest
+// of the status check interval and the app status intervals
+func (s *Systemd) statusTickInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tick := s.statusCheckInterval
+	for _, app := range s.apps {
+		if app.statusInterval > 0 && app.statusInterval < tick {
+			tick = app.statusInterval
+		}
+	}
+	return tick
+}
+
+// SetAppStartupGrace sets how long after a (re)start a specific app is not status checked,
+// the grace ends early once an app implementing ReadyReporter is ready
+func (s *Systemd) SetAppStartupGrace(appName string, grace time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if app, ok := s.apps[appName]; ok {
+		app.startupGrace = grace
+		return nil
+	}
+
+	return ErrAppNotExists
+}
+
+// inStartupGrace returns true if the app was (re)started within its startup grace and is not ready yet
+func (s *Systemd) inStartupGrace(app *appItem) bool {
+	s.mu.RLock()
+	grace, ready := app.startupGrace, app.ready
+	s.mu.RUnlock()
+
+	if grace == 0 {
+		return false
+	}
+	if reporter, ok := app.App.(ReadyReporter); ok && reporter.ReportsReady() && ready != nil && ready.isReady() {
+		return false
+	}
+	return app.uptime(s.clock.Now()) < grace
 }
 
-// SetDefaultOnFailure sets the default on failure action
+// statusCheckDue returns true if the app status should be checked at this tick,
+// and records the check if so
+func (s *Systemd) statusCheckDue(app *appItem, now time.Time, tick time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	interval := app.statusInterval
+	if interval == 0 {
+		interval = s.statusCheckInterval
+	}
+	// ticks are not exact, allow half a tick early
+	if now.Sub(app.lastStatusCheck) < interval-tick/2 {
+		return false
+	}
+	app.lastStatusCheck = now
+	return true
+}
+
+// notifyStatusIntervalChanged wakes the status watcher up to pick the new intervals
+func (s *Systemd) notifyStatusIntervalChanged() {
+	select {
+	case s.statusIntervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+// SetDefaultOnFailure sets the default on failure action,
+// it only applies to apps added afterwards
 func (s *Systemd) SetDefaultOnFailure(onFailure *OnFailure) {
-	s.defaultOnFailure = onFailure
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultOnFailure = onFailure.clone()
+}
+
+// UseStatusMiddleware registers middlewares applied around every app status check,
+// middlewares are applied in the order they are registered, the first one being the outermost
+func (s *Systemd) UseStatusMiddleware(middlewares ...StatusMiddleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statusMiddlewares = append(s.statusMiddlewares, middlewares...)
+}
+
+// SetRestartBudget limits the number of restarts of all apps together within the window,
+// once exceeded all apps are shut down and Start returns ErrRestartBudgetExceeded.
+// zero max disables the limit
+func (s *Systemd) SetRestartBudget(max int, window time.Duration) {
+	s.restartBudget.mu.Lock()
+	defer s.restartBudget.mu.Unlock()
+
+	s.restartBudget.max = max
+	s.restartBudget.window = window
+	s.restartBudget.restarts = nil
+}
+
+// recordRestart counts a restart of the app and records it against the restart budget
+func (s *Systemd) recordRestart(app *appItem) error {
+	s.mu.Lock()
+	app.restarts++
+	s.mu.Unlock()
+
+	if !s.restartBudget.record(s.clock.Now()) {
+		s.logger.Error("Restart budget exceeded while restarting app %q", app.Name())
+		return ErrRestartBudgetExceeded
+	}
+	return nil
+}
+
+// SetAllStoppedPolicy sets what Start does when all apps have stopped on their own
+func (s *Systemd) SetAllStoppedPolicy(policy AllStoppedPolicy) {
+	s.allStoppedPolicy = policy
+}
+
+// SetStartFailurePolicy sets what Start does when an app fails to start
+func (s *Systemd) SetStartFailurePolicy(policy StartFailurePolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startFailure = policy
 }
 
 // SetAppOnFailure sets the on failure action for a specific app
 func (s *Systemd) SetAppOnFailure(appName string, onFailure *OnFailure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if app, ok := s.apps[appName]; ok {
-		app.onFailure = onFailure
-		s.apps[appName] = app
+		app.onFailure = onFailure.clone()
+		return nil
 	}
 
 	return ErrAppNotExists
 }
 
+// AppOnFailure returns a copy of the on failure action of a specific app
+func (s *Systemd) AppOnFailure(appName string) (*OnFailure, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if app, ok := s.apps[appName]; ok {
+		return app.onFailure.clone(), nil
+	}
+
+	return nil, ErrAppNotExists
+}
+
 // SetAppPriority sets the priority for a specific app
 func (s *Systemd) SetAppPriority(appName string, priority int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if app, ok := s.apps[appName]; ok {
 		app.priority = priority
-		s.apps[appName] = app
+		return nil
 	}
 
 	return ErrAppNotExists
 }
 
+// Uptime returns how long the app has been running since its last (re)start.
+// it returns zero if the app is not running
+func (s *Systemd) Uptime(appName string) (time.Duration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if app, ok := s.apps[appName]; ok {
+		return app.uptime(s.clock.Now()), nil
+	}
+
+	return 0, ErrAppNotExists
+}
+
 // Start starts the systemd service, and all apps within.
 // it will return an error if any of the apps fail to start
 // or block until the context is cancelled
-func (s *Systemd) Start(ctx context.Context) error {
-	// Start apps in parallel
-	errs := make(chan error, len(s.apps))
-	wg := sync.WaitGroup{}
+func (s *Systemd) Start(ctx context.Context) (err error) {
+	// apps can request a shutdown of the whole stack through the context
+	ctx, shutdown := context.WithCancelCause(ctx)
+	defer shutdown(nil)
+	ctx = shutdownContext(ctx, shutdown)
+	ctx = s.resourcesContext(ctx)
+
+	if err := s.checkDependencies(); err != nil {
+		return err
+	}
 
-	apps := make([]appItem, 0, len(s.apps))
+	// take the apps snapshot and mark as running at once, apps added afterwards are started by Add
+	s.mu.Lock()
+	if s.run != nil {
+		s.mu.Unlock()
+		return ErrAlreadyRunning
+	}
+	apps := make([]*appItem, 0, len(s.apps))
 	for _, app := range s.apps {
 		apps = append(apps, app)
 	}
+	run := newRunState(ctx)
+	s.run = run
+	s.mu.Unlock()
 
-	// sort apps by priority
+	// Start apps in parallel
 	sortByPriority(apps)
+	apps = s.startOrder(apps)
+	s.resetTasks(apps)
+	start := s.restoreState(apps)
+	defer s.startAudit()()
+	defer s.startPersist()()
+	defer func() {
+		s.mu.Lock()
+		s.run = nil
+		s.mu.Unlock()
+		run.err = err
+		close(run.done)
+	}()
 
+	// every app gets its actor, apps paused before are held by the run until resumed
 	for _, app := range apps {
-		s.startApp(ctx, app, &wg, errs)
+		s.spawnActor(run, app, s.appState(app) == AppPaused)
+	}
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+
+	go s.watchForStatus(watchCtx)
+	// a nested group is an app of the root, only the root talks to the service manager
+	// and handles signals, which it passes on to the group by reloading it
+	if !s.nested {
+		go s.notifyWhenReady(watchCtx)
+		go s.handleSignals(watchCtx)
 	}
 
-	go s.watchForStatus(ctx, &wg, errs)
+	if err := s.startStages(run, start); err != nil {
+		return s.shutdownOnError(shutdown, run, err)
+	}
 
-	// wait for all apps to start or context to be cancelled
+	// wait for all apps to stop or context to be cancelled
+	stopped := run.allStopped()
 	for {
 		select {
 		case <-ctx.Done():
-			s.WaitForAppsStop(&wg) // wait for all apps to stop
+			s.logger.Info("Shutting down all apps: %v", context.Cause(ctx))
+			s.emit(EventShutdownBegun, "", nil)
+			s.notify("STOPPING=1")
+			run.close()
+			s.WaitForAppsStop(&run.actors) // wait for all apps to stop
 			return nil
-		case err := <-errs:
-			if !errors.Is(err, context.Canceled) {
-				return err
+		case <-run.failed:
+			return s.shutdownOnError(shutdown, run, run.firstFailure())
+		case <-stopped:
+			// failures are recorded before the app stops counting as running, pick up any left over
+			select {
+			case <-run.failed:
+				return s.shutdownOnError(shutdown, run, run.firstFailure())
+			default:
+			}
+
+			switch s.allStoppedPolicy {
+			case AllStoppedReturn:
+				s.logger.Info("All apps stopped on their own")
+				s.runFinalizers(s.clock.Now().Add(s.shutdownTimeout()))
+				return nil
+			case AllStoppedError:
+				s.logger.Error("All apps stopped on their own")
+				s.runFinalizers(s.clock.Now().Add(s.shutdownTimeout()))
+				return ErrAllAppsStopped
+			case AllStoppedWait:
+				stopped = nil
 			}
 		}
 	}
 }
 
-func sortByPriority(apps []appItem) {
-	for i := 0; i < len(apps); i++ {
-		for j := i + 1; j < len(apps); j++ {
-			if apps[i].priority > apps[j].priority {
-				apps[i], apps[j] = apps[j], apps[i]
-			}
-		}
+// shutdownOnError gracefully stops the remaining apps after a fatal error and returns the error,
+// joined with the errors of apps which failed meanwhile
+func (s *Systemd) shutdownOnError(shutdown context.CancelCauseFunc, run *runState, err error) error {
+	s.logger.Error("Shutting down all apps: %v", err)
+	s.emit(EventShutdownBegun, "", err)
+	s.notify("STOPPING=1")
+	shutdown(err)
+	run.close()
+	s.WaitForAppsStop(&run.actors)
+	return run.joinFailures(err)
+}
+
+// appList returns a snapshot of the registered apps, sorted by priority then name
+// so iteration order is the same across runs
+func (s *Systemd) appList() []*appItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	apps := make([]*appItem, 0, len(s.apps))
+	for _, app := range s.apps {
+		apps = append(apps, app)
 	}
+	sortByPriority(apps)
+	return apps
 }
 
-func (s *Systemd) startApp(ctx context.Context, app appItem, wg *sync.WaitGroup, errs chan error) {
-	wg.Add(1)
-	go func(app appItem) {
+func sortByPriority(apps []*appItem) {
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].priority != apps[j].priority {
+			return apps[i].priority < apps[j].priority
+		}
+		return apps[i].name < apps[j].name
+	})
+}
+
+// startApp starts the app in a goroutine of its own, failures ending the run are reported to it.
+// it returns a channel closed once the app returns, nil if the app was not started
+func (s *Systemd) startApp(ctx context.Context, app *appItem, run *runState) <-chan struct{} {
+	// the app would return right away, don't spin a goroutine for nothing
+	if ctx.Err() != nil {
+		s.logger.Warn("Not starting app %q, context is already done", app.Name())
+		return nil
+	}
+
+	// apps are stopped one by one on shutdown, they don't follow the parent cancellation
+	appCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	s.mu.Lock()
+	app.done = done
+	app.cancel = cancel
+	s.mu.Unlock()
+
+	go func(app *appItem) {
+		defer close(done)
+		defer cancel(nil)
 		defer func() {
-			wg.Done()
 			if r := recover(); r != nil {
-				if err, ok := r.(error); ok {
-					errs <- err
-				} else {
-					errs <- fmt.Errorf("%v", r)
-				}
+				err := newPanicError(r)
+				s.setState(app, AppFailed, err)
+				s.recordFailure(app, err)
+				s.emit(EventAppFailed, app.Name(), err)
+				s.emit(EventAppStopped, app.Name(), err)
+				run.fail(newAppError(app.Name(), PhaseStart, err))
 			}
 		}()
-		s.logger.Info("Starting app: %q", app.Name())
+		// give a restarted app some rest, growing with consecutive failures
+		if delay := s.restartDelay(app); delay > 0 {
+			s.logger.with("app", app.Name(), "state", AppRestarting, "delay", delay).Info("Restarting app %q in %s", app.Name(), delay)
+			select {
+			case <-appCtx.Done():
+				s.setState(app, AppStopped, nil)
+				s.emit(EventAppStopped, app.Name(), nil)
+				return
+			case <-s.clock.After(delay):
+			}
+		}
+
+		// hold the app until the apps it depends on are healthy, and the tasks before it finished
+		err := s.waitForDependencies(appCtx, app)
+		if err == nil {
+			err = s.waitForTasks(appCtx, app)
+		}
+		if err != nil {
+			s.logger.Info("app %q stopped while waiting for its dependencies: %v", app.Name(), context.Cause(appCtx))
+			s.setState(app, AppStopped, nil)
+			s.emit(EventAppStopped, app.Name(), nil)
+			return
+		}
+
+		s.logger.with("app", app.Name(), "state", AppStarting.String()).Info("Starting app: %q", app.Name())
 		// start the app with retry and timeout if configured
-		if err := startWithRetry(ctx, app); err != nil {
-			errs <- err
+		if err := s.startWithRetry(s.appContext(appCtx, app.Name()), app); err != nil {
+			// the app was stopped on purpose, its error is not a failure of the stack
+			if appCtx.Err() != nil {
+				s.logger.with("app", app.Name(), "state", AppStopped.String()).Info("app %q stopped: %v", app.Name(), context.Cause(appCtx))
+				s.setState(app, AppStopped, nil)
+				s.emit(EventAppStopped, app.Name(), nil)
+				return
+			}
+			// a quarantined app waits for Unquarantine, it does not bring the stack down
+			if errors.Is(err, ErrAppQuarantined) {
+				s.emit(EventAppStopped, app.Name(), err)
+				return
+			}
+			// an ignored app stays failed, it does not bring the stack down either
+			if errors.Is(err, ErrAppIgnored) {
+				s.logger.with("app", app.Name(), "error", err).Info("Ignoring app %q failure: %v", app.Name(), err)
+				s.setState(app, AppFailed, err)
+				s.emit(EventAppStopped, app.Name(), err)
+				return
+			}
+			s.setState(app, AppFailed, err)
+			s.emit(EventAppStopped, app.Name(), err)
+			if !errors.As(err, new(*criticalError)) && s.startFailurePolicy() == StartFailureContinue {
+				s.logger.with("app", app.Name(), "state", AppFailed, "error", err).Error("app %q failed, keeping the other apps running: %v", app.Name(), err)
+				return
+			}
+			run.fail(newAppError(app.Name(), PhaseStart, err))
+			return
 		}
+		s.setState(app, AppStopped, nil)
+		s.emit(EventAppStopped, app.Name(), nil)
 	}(app)
+	return done
+}
+
+// stopApp cancels the running app with the given cause and waits for it to return,
+// at most for its shutdown timeout or until the context is done
+func (s *Systemd) stopApp(ctx context.Context, app *appItem, cause error) error {
+	s.mu.RLock()
+	cancel, done := app.cancel, app.done
+	s.mu.RUnlock()
+
+	if cancel == nil {
+		return nil
+	}
+	s.signalStop(app, cancel, cause)
+
+	timeout := s.appShutdownTimeout(app)
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.clock.After(timeout):
+		s.logger.Error("app %q did not stop in %s", app.Name(), timeout)
+		return fmt.Errorf("app %q did not stop in %s", app.Name(), timeout)
+	}
+}
+
+// StopApp stops a specific app, keeping it registered so it can be started again with RestartApp.
+// it waits for the app to stop, at most for its shutdown timeout or until the context is done
+func (s *Systemd) StopApp(ctx context.Context, appName string) error {
+	s.mu.RLock()
+	app, ok := s.apps[appName]
+	s.mu.RUnlock()
+
+	if !ok {
+		return ErrAppNotExists
+	}
+
+	s.logger.Info("Stopping app %q", appName)
+	// the stopped app is held like a paused app, stopping the last app must not end the run
+	// while it can still be started again
+	if sent, err := s.sendCommand(app, command{kind: cmdStop, ctx: ctx, cause: ErrAppStopped, hold: true}); sent {
+		return err
+	}
+	return s.stopApp(ctx, app, ErrAppStopped)
 }
 
-func startWithRetry(ctx context.Context, app appItem) error {
+// RestartApp stops a specific app if it is running and starts it again,
+// the systemd service must be running. once the app is cancelled it is waited for up to its
+// shutdown timeout regardless of the context, and started again even if it did not stop in time,
+// in which case the error is returned
+func (s *Systemd) RestartApp(ctx context.Context, appName string) error {
+	s.mu.RLock()
+	app, ok := s.apps[appName]
+	run := s.run
+	s.mu.RUnlock()
+
+	if !ok {
+		return ErrAppNotExists
+	}
+	if run == nil {
+		return ErrNotRunning
+	}
+
+	s.logger.Info("Restarting app %q", appName)
+	sent, err := s.sendCommand(app, command{kind: cmdRestart, ctx: ctx})
+	if !sent {
+		return ErrNotRunning
+	}
+	return err
+}
+
+func (s *Systemd) startWithRetry(ctx context.Context, app *appItem) error {
+	s.mu.RLock()
+	onFailure := app.onFailure
+	priority := app.priority
+	startTimeout := app.startTimeout
+	s.mu.RUnlock()
+
 	var err error
-	for i := 0; i < app.onFailure.retry; i++ {
-		if err = app.Start(ctx); err != nil {
-			time.Sleep(app.onFailure.retryTimeout)
+	// the app is started at least once, the restart policy decides about the retries
+	for i := 0; ; i++ {
+		if i > 0 {
+			if err := s.checkCrashLoop(app); err != nil {
+				return err
+			}
+			if err := s.recordRestart(app); err != nil {
+				return err
+			}
+			s.emit(EventAppRestarted, app.Name(), err)
+		}
+
+		// wait for a start slot, it is held until the app is ready or returns from Start
+		if err := s.startLimiter.acquire(ctx, priority); err != nil {
+			return err
+		}
+		var releaseOnce sync.Once
+		release := func() { releaseOnce.Do(s.startLimiter.release) }
+		attemptDone := make(chan struct{})
+
+		// the start span ends once the app is running, or returns early from Start
+		op := OperationStart
+		if i > 0 {
+			op = OperationRestart
+		}
+		spanCtx, endSpan := s.startSpan(ctx, op, app.Name())
+		var spanOnce sync.Once
+		end := func(err error) {
+			spanOnce.Do(func() { endSpan(err) })
+		}
+
+		// apps reporting readiness are running once ready, others as soon as started
+		state := AppRunning
+		if reporter, ok := app.App.(ReadyReporter); ok && reporter.ReportsReady() {
+			state = AppStarting
+		}
+		ready := newReadyState(func() {
+			s.setState(app, AppRunning, nil)
+			end(nil)
+			release()
+		})
+		s.mu.Lock()
+		app.ready = ready
+		s.mu.Unlock()
+		s.setState(app, state, nil)
+		if state == AppRunning {
+			end(nil)
+		}
+
+		app.startedAt.Store(s.clock.Now().UnixNano())
+		if state == AppRunning {
+			// apps not reporting readiness hold their slot until they pass their status check
+			if s.startLimiter.limited() {
+				go s.releaseWhenReady(ctx, app, attemptDone, release)
+			} else {
+				release()
+			}
+		}
+		s.emit(EventAppStarted, app.Name(), nil)
+		runCtx := context.WithValue(s.listenerContext(spanCtx), readyKey{}, ready)
+		runCtx = context.WithValue(runCtx, heartbeatKey{}, heartbeat{app: app, clock: s.clock})
+		runCtx, cancelAttempt := context.WithCancelCause(runCtx)
+		if startTimeout > 0 {
+			go s.enforceStartTimeout(ctx, app, startTimeout, attemptDone, cancelAttempt)
+		}
+		startedAt := s.clock.Now()
+		s.mu.RLock()
+		start := chainStart(app.Start, s.startMiddlewares)
+		s.mu.RUnlock()
+		err = recovered(func() error { return start(runCtx) })
+		app.startedAt.Store(0)
+		s.recordRun(app, s.clock.Now().Sub(startedAt))
+		close(attemptDone)
+		release()
+		// a start cancelled for taking too long is a failure, whatever the app returned
+		if cause := context.Cause(runCtx); ctx.Err() == nil && errors.Is(cause, ErrStartTimeout) {
+			err = cause
+		}
+		cancelAttempt(nil)
+		end(err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			s.setState(app, AppRestarting, err)
+			s.recordFailure(app, err)
+			s.emit(EventAppFailed, app.Name(), err)
+			s.logger.with("app", app.Name(), "attempt", i+1, "error", err).Error("app %q start attempt %d failed: %v", app.Name(), i+1, err)
+
+			var panicErr *PanicError
+			if errors.As(err, &panicErr) {
+				s.logger.with("app", app.Name(), "error", err).Error("app %q panicked: %v\n%s", app.Name(), panicErr.Value, panicErr.Stack)
+				switch s.appPanicPolicy(app) {
+				case FailFastOnPanic:
+					return &criticalError{err: err}
+				case IgnorePanic:
+					return fmt.Errorf("%w: %w", ErrAppIgnored, err)
+				}
+			}
+
+			action := onFailure.action(ctx, app.Name(), err)
+			if action == ActionIgnore {
+				return fmt.Errorf("%w: %v", ErrAppIgnored, err)
+			}
+			if action == ActionShutdown {
+				return &criticalError{err: err}
+			}
+			// the app was healthy long enough, count this as its first failure
+			if ranFor := s.clock.Now().Sub(startedAt); onFailure.resetAfter > 0 && ranFor >= onFailure.resetAfter {
+				s.logger.Info("app %q ran for %s before failing, resetting its retries", app.Name(), ranFor.Round(time.Second))
+				i = 0
+			}
+			delay, ok := onFailure.NextDelay(i, err)
+			if !ok {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return err
+			case <-s.clock.After(delay):
+			}
 			continue
 		}
 		return nil
 	}
-	return err
+}
+
+// criticalError is the error of an app whose OnFailure policy shuts down all apps
+type criticalError struct {
+	err error
+}
+
+func (e *criticalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *criticalError) Unwrap() error {
+	return e.err
+}
+
+// startFailurePolicy returns what to do when an app fails to start
+func (s *Systemd) startFailurePolicy() StartFailurePolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.startFailure
 }
 
 // WaitForAppsStop waits for all apps to stop or context to be cancelled
+// apps are stopped one by one, dependents before their dependencies and in reverse priority order
+// then the finalizers run with what is left of the shutdown timeout
 func (s *Systemd) WaitForAppsStop(wg *sync.WaitGroup) {
+	defer func(begin time.Time) { s.recordShutdown(s.clock.Now().Sub(begin)) }(s.clock.Now())
+	_, end := s.startSpan(context.Background(), OperationShutdown, "")
+	cause := s.shutdownCause()
+	timeout := s.shutdownTimeout()
+	deadline := s.clock.Now().Add(timeout)
+
 	// wait for all apps to stop or context to be cancelled
 	select {
-	case <-time.After(s.graceFullShutdownTimeout):
-		s.logger.Error("Shutdown timeout, forcefully stopping apps")
-		return
+	case <-s.clock.After(timeout):
+		running := strings.Join(s.runningApps(), ", ")
+		s.logger.Error("Shutdown timeout, forcefully stopping apps: %s", running)
+		s.emit(EventShutdownTimeout, "", fmt.Errorf("apps did not stop in %s: %s", timeout, running))
+		s.cancelApps(cause)
+		end(context.DeadlineExceeded)
 	case <-waitForGroup(wg):
 		s.logger.Info("All apps stopped")
-		return
+		end(nil)
+	case <-s.stopAppsInOrder(cause):
+		s.logger.Info("All apps stopped or drained")
+		end(nil)
 	}
+	s.runFinalizers(deadline)
 }
 
-func (s *Systemd) watchForStatus(ctx context.Context, wg *sync.WaitGroup, errs chan error) {
-	ticker := time.NewTicker(s.statusCheckInterval)
+func (s *Systemd) watchForStatus(ctx context.Context) {
+	// drop changes made before the watcher started, the ticker already uses the latest interval
+	select {
+	case <-s.statusIntervalChanged:
+	default:
+	}
+
+	tick := s.statusTickInterval()
+	ticker := s.clock.NewTicker(tick)
 	defer ticker.Stop()
+	s.checkWatchdogInterval(tick)
+	s.markAlive(s.clock.Now(), tick)
+	defer s.markAlive(time.Time{}, 0)
+
+	pool := newWorkerPool(s.statusCheckConcurrency)
 
 	for {
 		select {
 		case <-ctx.Done():
-			errs <- ctx.Err()
-		case <-ticker.C:
-			for _, app := range s.apps {
-				if err := app.Status(ctx); err != nil {
-					s.logger.Error("app %q status check failed: %v", app.Name(), err)
-					switch app.onFailure {
-					case OnFailureRestart:
-						s.logger.Info("Restarting app %q", app.Name())
-						s.startApp(restoredContext(ctx), app, wg, errs)
-					case OnFailureIgnore:
-						s.logger.Info("Ignoring app %q failure", app.Name())
-						// remove app from apps list
-						delete(s.apps, app.Name())
-						// remove app from wait group
-						wg.Add(-1)
-					}
+			pool.Wait()
+			return
+		case <-s.statusIntervalChanged:
+			tick = s.statusTickInterval()
+			s.logger.Info("Status check interval changed to %s", tick)
+			s.checkWatchdogInterval(tick)
+			s.markAlive(s.clock.Now(), tick)
+			ticker.Reset(tick)
+		case now := <-ticker.C():
+			// the service manager watchdog is fed while the watcher is alive
+			s.notify("WATCHDOG=1")
+			s.markAlive(s.clock.Now(), tick)
+			if s.frozen.Load() {
+				continue
+			}
+			for _, app := range s.appList() {
+				// the app is not running, or is a task, there is nothing to check
+				if app.task || !s.appState(app).running() {
+					continue
+				}
+				if s.inStartupGrace(app) || !s.statusCheckDue(app, now, tick) {
+					continue
 				}
+				// a slow check only delays the next check of its own app
+				if !app.checking.CompareAndSwap(false, true) {
+					s.logger.with("app", app.Name()).Warn("Status check of app %q is still running, not checking it again", app.Name())
+					continue
+				}
+				app := app
+				// the watcher keeps ticking while all slots are busy, the check waits for one
+				pool.Go(ctx, func() {
+					begin := s.clock.Now()
+					spanCtx, end := s.startSpan(ctx, OperationStatusCheck, app.Name())
+					err := s.checkStatus(spanCtx, app)
+					end(err)
+					s.recordStatusCheck(app, s.clock.Now().Sub(begin), err)
+					s.recordStatus(app, begin, err)
+					switch HealthOf(err) {
+					case HealthHealthy:
+						s.statusPassed(app)
+					case HealthDegraded:
+						s.statusDegraded(app, err)
+					case HealthUnhealthy:
+						// the actor of the app acts on the failure, the app is not checked meanwhile
+						_, _ = s.sendCommand(app, command{kind: cmdStatus, cause: err})
+					}
+				}, func() { app.checking.Store(false) })
 			}
 		}
 	}
 }
 
+// statusPassed resets the failure tracking of an app which passed its status check
+func (s *Systemd) statusPassed(app *appItem) {
+	s.mu.Lock()
+	app.failures = 0
+	degraded := app.state == AppDegraded
+	s.mu.Unlock()
+
+	if degraded {
+		s.setState(app, AppRunning, nil)
+	}
+}
+
+// restartAllowed returns false if the custom restart policy of the app gave up on it
+func (s *Systemd) restartAllowed(app *appItem, err error) bool {
+	s.mu.RLock()
+	policy, failures := app.onFailure.policy, app.failures
+	s.mu.RUnlock()
+
+	if policy == nil {
+		return true
+	}
+	_, ok := policy.NextDelay(failures, err)
+	return ok
+}
+
+// restartDelay returns how long to wait before starting an app restarted after failed
+// status checks, only apps with a backoff policy wait
+func (s *Systemd) restartDelay(app *appItem) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if app.failures == 0 || app.state != AppRestarting {
+		return 0
+	}
+	if app.onFailure.policy != nil {
+		delay, _ := app.onFailure.policy.NextDelay(app.failures-1, app.lastErr)
+		return delay
+	}
+	if app.onFailure.backoff == nil {
+		return 0
+	}
+	return app.onFailure.delay(app.failures - 1)
+}
+
+// checkStatus runs the app status check wrapped with the registered middlewares
+func (s *Systemd) checkStatus(ctx context.Context, app *appItem) error {
+	s.mu.RLock()
+	status := StatusFunc(app.Status)
+	timeout := app.statusTimeout
+	if timeout == 0 {
+		timeout = s.statusCheckTimeout
+	}
+	// a hung check must not disable checking the app for good, it fails once the next is due
+	if timeout == 0 {
+		timeout = app.statusInterval
+	}
+	if timeout == 0 {
+		timeout = s.statusCheckInterval
+	}
+	status = statusTimeout(s.clock, timeout)(status)
+	status = chainStatus(status, s.statusMiddlewares)
+	s.mu.RUnlock()
+
+	if err := recovered(func() error { return status(s.appContext(ctx, app.Name())) }); err != nil {
+		return err
+	}
+	if err := s.checkHeartbeat(app); err != nil {
+		return err
+	}
+	return s.checkIdle(app)
+}
+
 func waitForGroup(wg *sync.WaitGroup) <-chan struct{} {
 	c := make(chan struct{})
 	go func() {
@@ -289,6 +1343,18 @@ func waitForGroup(wg *sync.WaitGroup) <-chan struct{} {
 
 type restoredTask struct{}
 
+type appNameKey struct{}
+
+func appNameContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, appNameKey{}, name)
+}
+
+// AppName returns the name of the app the context belongs to, or empty string if not set
+func AppName(ctx context.Context) string {
+	name, _ := ctx.Value(appNameKey{}).(string)
+	return name
+}
+
 func restoredContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, restoredTask{}, true)
 }
@@ -0,0 +1,435 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// appNames returns the names of the apps of the systemd service in snapshot order
+func appNames(s *sysd.Systemd) []string {
+	var names []string
+	for _, app := range s.Snapshot() {
+		names = append(names, app.Name)
+	}
+	return names
+}
+
+func TestAddAll(t *testing.T) {
+	s := sysd.New()
+	err := s.AddAll(sysdtest.NewFakeApp("a"), sysdtest.NewFakeApp("b"))
+	if err != nil {
+		t.Fatalf("AddAll returned %v", err)
+	}
+
+	err = s.AddAll(sysdtest.NewFakeApp("c"), sysdtest.NewFakeApp("a"), sysdtest.NewFakeApp("d"))
+	if !errors.Is(err, sysd.ErrAppAlreadyExists) {
+		t.Fatalf("AddAll with a duplicate returned %v, want %v", err, sysd.ErrAppAlreadyExists)
+	}
+	// apps before the duplicate are added, the ones after are not
+	if got := appNames(s); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("apps are %q, want a, b and c", got)
+	}
+}
+
+func TestSetStatusCheckIntervalWhileRunning(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Hour))
+	app := h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	h.Systemd.SetStatusCheckInterval(time.Second)
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); app.StatusChecks() < 2 && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := app.StatusChecks(); n < 2 {
+		t.Errorf("app status checked %d times, want the new interval to apply", n)
+	}
+}
+
+func TestAppNotStartedDuringShutdown(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.NewApp("slow").SetStopDelay(10 * time.Second)
+	h.Start()
+	h.WaitForState("slow", sysd.AppRunning)
+
+	go func() { _ = h.Systemd.Shutdown(context.Background()) }()
+	h.WaitForEvent(sysd.EventShutdownBegun, "")
+
+	// the stack is still stopping, an app added now must not be started
+	late := h.NewApp("late")
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	if n := late.Starts(); n != 0 {
+		t.Errorf("app added during shutdown started %d times", n)
+	}
+}
+
+// failOnStopApp is a fake app returning an error once it is cancelled, and failing its status
+// check once the status watcher is stopped
+type failOnStopApp struct {
+	*sysdtest.FakeApp
+	checking chan struct{}
+	once     sync.Once
+}
+
+func (a *failOnStopApp) Start(ctx context.Context) error {
+	_ = a.FakeApp.Start(ctx)
+	return errors.New("failed while stopping")
+}
+
+func (a *failOnStopApp) Status(ctx context.Context) error {
+	a.once.Do(func() { close(a.checking) })
+	<-ctx.Done()
+	return errors.New("unhealthy while stopping")
+}
+
+func TestFailedAppNotRestartedDuringShutdown(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithStatusCheckInterval(time.Second))
+	app := &failOnStopApp{FakeApp: sysdtest.NewFakeApp("app"), checking: make(chan struct{})}
+	err := h.Systemd.Add(app, sysd.WithOnFailure(sysd.OnFailureRestart.Retry(3)), sysd.WithStatusTimeout(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	// a status check is in flight when the shutdown begins
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); ; time.Sleep(5 * time.Millisecond) {
+		h.Clock.Advance(time.Second)
+		select {
+		case <-app.checking:
+		default:
+			if time.Now().Before(deadline) {
+				continue
+			}
+			t.Fatal("app not status checked")
+		}
+		break
+	}
+
+	_ = h.Stop()
+	// neither the failed status check nor the failed Start restart the app once the context is done
+	if n := app.Starts(); n != 1 {
+		t.Errorf("app started %d times, want it not restarted during shutdown", n)
+	}
+}
+
+func TestAppNotRestartedDuringShutdown(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.NewApp("slow").SetStopDelay(10 * time.Second)
+	app := h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	go func() { _ = h.Systemd.Shutdown(context.Background()) }()
+	h.WaitForEvent(sysd.EventShutdownBegun, "")
+
+	// the stack is still stopping, the restart stops the app without starting it again
+	_ = h.Systemd.RestartApp(context.Background(), "app")
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	if n := app.Starts(); n != 1 {
+		t.Errorf("app restarted during shutdown started %d times, want 1", n)
+	}
+}
+
+func TestAppsIterateInStableOrder(t *testing.T) {
+	want := []string{"b", "d", "a", "c", "e"}
+	priorities := map[string]int{"a": 2, "b": 1, "c": 2, "d": 1, "e": 3}
+
+	for run := 0; run < 20; run++ {
+		s := sysd.New()
+		// map iteration registers the apps in a random order
+		for name, priority := range priorities {
+			if err := s.Add(sysdtest.NewFakeApp(name), sysd.WithPriority(priority)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := appNames(s); !slices.Equal(got, want) {
+			t.Fatalf("snapshot order is %q, want %q", got, want)
+		}
+		for i, stats := range s.Stats() {
+			if stats.Name != want[i] {
+				t.Fatalf("stats order differs from the snapshot at %d: %q", i, stats.Name)
+			}
+		}
+	}
+}
+
+func TestDefaultOnFailureIsCopiedOnAdd(t *testing.T) {
+	h := sysdtest.NewHarness(t, sysd.WithDefaultOnFailure(sysd.OnFailureRestart.Retry(2).RetryTimeout(0)))
+	restarted := h.NewApp("restarted")
+	restarted.FailStarts(1, errors.New("boom"))
+
+	// changing the default afterwards only applies to apps added later
+	h.Systemd.SetDefaultOnFailure(sysd.OnFailureIgnore)
+	ignored := h.NewApp("ignored")
+	ignored.FailStarts(1, errors.New("boom"))
+
+	for name, want := range map[string]*sysd.OnFailure{"restarted": sysd.OnFailureRestart, "ignored": sysd.OnFailureIgnore} {
+		got, err := h.Systemd.AppOnFailure(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("app %q on failure is %s, want %s", name, got, want)
+		}
+	}
+
+	events := h.Systemd.Subscribe()
+	h.Start()
+	advanceUntilEvent(t, h, events, time.Second, sysd.EventAppRestarted, "restarted")
+	h.WaitForState("restarted", sysd.AppRunning)
+	h.WaitForState("ignored", sysd.AppFailed)
+	if got := ignored.Starts(); got != 1 {
+		t.Errorf("ignored app started %d times, want 1", got)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	if err := h.Systemd.Replace(sysdtest.NewFakeApp("app")); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Fatalf("Replace of a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+
+	old := h.NewApp("app", sysd.WithOnFailure(sysd.OnFailureIgnore))
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	replacement := sysdtest.NewFakeApp("app")
+	if err := h.Systemd.Replace(replacement); err != nil {
+		t.Fatalf("Replace returned %v", err)
+	}
+	if !errors.Is(old.Cause(), sysd.ErrAppReplaced) {
+		t.Errorf("old app cancelled with %v, want %v", old.Cause(), sysd.ErrAppReplaced)
+	}
+	if !replacement.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("replacement app not started")
+	}
+	h.WaitForState("app", sysd.AppRunning)
+	if old.Running() {
+		t.Error("old app still running")
+	}
+	// the replacement keeps the configuration of the old app
+	if onFailure, _ := h.Systemd.AppOnFailure("app"); !onFailure.Equal(sysd.OnFailureIgnore) {
+		t.Errorf("replacement on failure is %s, want %s", onFailure, sysd.OnFailureIgnore)
+	}
+}
+
+func TestAppOnFailure(t *testing.T) {
+	s := sysd.New(sysd.WithDefaultOnFailure(sysd.OnFailureShutdownAll))
+	if err := s.Add(sysdtest.NewFakeApp("defaulted")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(sysdtest.NewFakeApp("explicit"), sysd.WithOnFailure(sysd.OnFailureIgnore)); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]*sysd.OnFailure{"defaulted": sysd.OnFailureShutdownAll, "explicit": sysd.OnFailureIgnore} {
+		got, err := s.AppOnFailure(name)
+		if err != nil {
+			t.Fatalf("AppOnFailure(%q) returned %v", name, err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("app %q on failure is %s, want %s", name, got, want)
+		}
+	}
+
+	if err := s.SetAppOnFailure("defaulted", sysd.OnFailureRestart); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.AppOnFailure("defaulted"); !got.Equal(sysd.OnFailureRestart) {
+		t.Errorf("app on failure is %s after setting it, want %s", got, sysd.OnFailureRestart)
+	}
+	if _, err := s.AppOnFailure("missing"); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Errorf("AppOnFailure of a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+}
+
+func TestAddAndRemoveWhileRunning(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.NewApp("base")
+	h.Start()
+	h.WaitForState("base", sysd.AppRunning)
+
+	// apps added concurrently while running are started on the fly
+	apps := make([]*sysdtest.FakeApp, 5)
+	errs := make(chan error, len(apps))
+	for i := range apps {
+		apps[i] = sysdtest.NewFakeApp(string(rune('a' + i)))
+		go func(app sysd.App) { errs <- h.Systemd.Add(app) }(apps[i])
+	}
+	for range apps {
+		if err := <-errs; err != nil {
+			t.Fatalf("Add while running returned %v", err)
+		}
+	}
+	for _, app := range apps {
+		if !app.WaitRunning(sysdtest.WaitTimeout) {
+			t.Fatalf("app %q added while running not started", app.Name())
+		}
+	}
+
+	if err := h.Systemd.Remove("a", true); err != nil {
+		t.Fatalf("Remove returned %v", err)
+	}
+	if apps[0].Running() {
+		t.Error("drained app still running after Remove")
+	}
+	if names := appNames(h.Systemd); slices.Contains(names, "a") {
+		t.Errorf("removed app still registered in %q", names)
+	}
+	if err := h.Systemd.Remove("a", true); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Errorf("Remove of a removed app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+}
+
+func TestStopAndRestartApp(t *testing.T) {
+	s := sysd.New()
+	if err := s.Add(sysdtest.NewFakeApp("app")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RestartApp(context.Background(), "app"); !errors.Is(err, sysd.ErrNotRunning) {
+		t.Errorf("RestartApp while not running returned %v, want %v", err, sysd.ErrNotRunning)
+	}
+
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app")
+	other := h.NewApp("other")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	if err := h.Systemd.StopApp(context.Background(), "missing"); !errors.Is(err, sysd.ErrAppNotExists) {
+		t.Errorf("StopApp of a missing app returned %v, want %v", err, sysd.ErrAppNotExists)
+	}
+	if err := h.Systemd.StopApp(context.Background(), "app"); err != nil {
+		t.Fatalf("StopApp returned %v", err)
+	}
+	h.WaitForEvent(sysd.EventAppStopped, "app")
+	h.WaitForState("app", sysd.AppStopped)
+	if !other.Running() {
+		t.Error("other app stopped with the app")
+	}
+
+	if err := h.Systemd.RestartApp(context.Background(), "app"); err != nil {
+		t.Fatalf("RestartApp returned %v", err)
+	}
+	h.WaitForEvent(sysd.EventAppRestarted, "app")
+	// the state is running just before Start is called, wait for the app itself
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app not running again")
+	}
+	if n := app.Starts(); n != 2 {
+		t.Errorf("app started %d times, want 2", n)
+	}
+}
+
+func TestStopLastAppKeepsRunning(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	if err := h.Systemd.StopApp(context.Background(), "app"); err != nil {
+		t.Fatalf("StopApp returned %v", err)
+	}
+	h.WaitForState("app", sysd.AppStopped)
+	time.Sleep(20 * time.Millisecond)
+	if h.Returned() {
+		t.Fatal("Start returned once the last app was stopped")
+	}
+
+	if err := h.Systemd.RestartApp(context.Background(), "app"); err != nil {
+		t.Fatalf("RestartApp of the stopped last app returned %v", err)
+	}
+	// the state is running just before Start is called, wait for the app itself
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app not running again")
+	}
+	if n := app.Starts(); n != 2 {
+		t.Errorf("app started %d times, want 2", n)
+	}
+	if err := h.Stop(); err != nil {
+		t.Errorf("Stop returned %v", err)
+	}
+}
+
+func TestRestartAppOutlivesContext(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app").SetStopDelay(time.Second)
+	h.NewApp("other")
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	restarted := make(chan error, 1)
+	go func() { restarted <- h.Systemd.RestartApp(ctx, "app") }()
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !errors.Is(app.Cause(), sysd.ErrAppRestarted); {
+		if time.Now().After(deadline) {
+			t.Fatal("app not cancelled by RestartApp")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// the caller goes away while the app is still stopping
+	cancel()
+
+	deadline := time.After(sysdtest.WaitTimeout)
+	for done := false; !done; {
+		select {
+		case err := <-restarted:
+			if err != nil {
+				t.Fatalf("RestartApp returned %v", err)
+			}
+			done = true
+		case <-time.After(5 * time.Millisecond):
+			h.Clock.Advance(100 * time.Millisecond)
+		case <-deadline:
+			t.Fatal("RestartApp did not return once the app stopped")
+		}
+	}
+	// the state is running just before Start is called, wait for the app itself
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app not running again")
+	}
+	if n := app.Starts(); n != 2 {
+		t.Errorf("app started %d times, want 2", n)
+	}
+}
+
+func TestStopAppRespectsShutdownTimeout(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app", sysd.WithShutdownTimeout(time.Second)).SetStopDelay(time.Hour)
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- h.Systemd.StopApp(context.Background(), "app") }()
+	deadline := time.After(sysdtest.WaitTimeout)
+	for {
+		select {
+		case err := <-stopped:
+			if err == nil {
+				t.Error("StopApp returned no error for an app exceeding its shutdown timeout")
+			}
+			// let the app return so it does not outlive the test
+			h.Clock.Advance(time.Hour)
+			for deadline := time.Now().Add(sysdtest.WaitTimeout); app.Running() && time.Now().Before(deadline); {
+				time.Sleep(5 * time.Millisecond)
+			}
+			return
+		case <-time.After(5 * time.Millisecond):
+			h.Clock.Advance(100 * time.Millisecond)
+		case <-deadline:
+			t.Fatal("StopApp did not return after the shutdown timeout")
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Package sysdtest provides helpers to test sysd apps and supervision logic without real sleeps
+package sysdtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.Clock = &Clock{}
+
+// Clock is a fake sysd.Clock whose time only moves when Advance is called, timers and tickers
+// fire as the time passes their deadline
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	// changed is closed and replaced every time a waiter is added
+	changed chan struct{}
+}
+
+// waiter is a pending timer or ticker of the fake clock
+type waiter struct {
+	at time.Time
+	// period is the interval of a ticker, zero for timers
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewClock returns a fake clock starting at the given time
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the current time of the fake clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel receiving the time once the clock advanced by d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a timer firing once the clock advanced by d
+func (c *Clock) NewTimer(d time.Duration) sysd.Timer {
+	w := &waiter{c: make(chan time.Time, 1)}
+	c.add(w, d, 0)
+	return &fakeTimer{clock: c, w: w}
+}
+
+// NewTicker returns a ticker firing every time the clock advanced by d
+func (c *Clock) NewTicker(d time.Duration) sysd.Ticker {
+	if d <= 0 {
+		panic("sysdtest: non-positive interval for NewTicker")
+	}
+	w := &waiter{c: make(chan time.Time, 1)}
+	c.add(w, d, d)
+	return &fakeTicker{clock: c, w: w}
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers due meanwhile in order
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+		if len(c.waiters) == 0 || c.waiters[0].at.After(end) {
+			break
+		}
+		w := c.waiters[0]
+		c.now = w.at
+		// like time.Ticker, ticks are dropped for slow receivers
+		select {
+		case w.c <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = end
+}
+
+// Waiters returns the number of pending timers and tickers
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until there are at least n pending timers and tickers, e.g. to advance the
+// clock only once the code under test waits on it
+func (c *Clock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		pending, changed := len(c.waiters), c.changed
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// add schedules the waiter to fire after d, and then every period if it is not zero
+func (c *Clock) add(w *waiter, d, period time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w.at, w.period = c.now.Add(d), period
+	c.waiters = append(c.waiters, w)
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// remove drops the waiter, it returns false if it was not pending
+func (c *Clock) remove(w *waiter) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, pending := range c.waiters {
+		if pending == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock *Clock
+	w     *waiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.w.c }
+func (t *fakeTimer) Stop() bool          { return t.clock.remove(t.w) }
+
+type fakeTicker struct {
+	clock *Clock
+	w     *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.w.c }
+func (t *fakeTicker) Stop()               { t.clock.remove(t.w) }
+
+// Reset stops the ticker and restarts it with the new interval from the current time
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("sysdtest: non-positive interval for Reset")
+	}
+	t.clock.remove(t.w)
+	t.clock.add(t.w, d, d)
+}
@@ -0,0 +1,101 @@
+package sysdtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestClockFiresTimersWhenAdvanced(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := sysdtest.NewClock(start)
+	first := c.After(time.Second)
+	second := c.NewTimer(2 * time.Second)
+	stopped := c.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Fatal("Stop of a pending timer returned false")
+	}
+
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-first:
+		t.Fatal("timer fired before its deadline")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+	if at := <-first; !at.Equal(start.Add(time.Second)) {
+		t.Errorf("timer fired at %s, want its deadline", at)
+	}
+	c.Advance(time.Hour)
+	if at := <-second.C(); !at.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("timer fired at %s, want its deadline", at)
+	}
+	select {
+	case <-stopped.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+	if now := c.Now(); !now.Equal(start.Add(time.Hour + time.Second)) {
+		t.Errorf("clock is at %s after advancing", now)
+	}
+	if c.Waiters() != 0 {
+		t.Errorf("%d waiters left after all timers fired", c.Waiters())
+	}
+}
+
+func TestClockTicker(t *testing.T) {
+	c := sysdtest.NewClock(time.Now())
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 0; i < 3; i++ {
+		c.Advance(time.Second)
+		select {
+		case <-ticker.C():
+		default:
+			t.Fatalf("tick %d missing", i)
+		}
+	}
+
+	// ticks are dropped while the receiver is behind
+	c.Advance(5 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("ticks queued for a slow receiver")
+	default:
+	}
+
+	ticker.Reset(time.Minute)
+	c.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("reset ticker fired before its new interval")
+	default:
+	}
+	c.Advance(time.Second)
+	select {
+	case <-ticker.C():
+	default:
+		t.Fatal("reset ticker did not fire after its new interval")
+	}
+}
+
+func TestClockBlockUntil(t *testing.T) {
+	c := sysdtest.NewClock(time.Now())
+	fired := make(chan struct{})
+	go func() {
+		<-c.After(time.Minute)
+		close(fired)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	select {
+	case <-fired:
+	case <-time.After(sysdtest.WaitTimeout):
+		t.Fatal("timer waited on before BlockUntil returned did not fire")
+	}
+}
@@ -0,0 +1,188 @@
+package sysdtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+var _ sysd.App = &FakeApp{}
+
+// FakeApp is an app whose behavior is scripted by the test: failing starts, flapping health
+// and slow shutdowns. it runs until its context is done
+type FakeApp struct {
+	name  string
+	clock sysd.Clock
+
+	mu            sync.Mutex
+	startErrs     []error
+	statusErrs    []error
+	status        error
+	stopDelay     time.Duration
+	panicOnStart  any
+	starts        int
+	statusChecks  int
+	running       bool
+	cause         error
+	runningChange chan struct{}
+}
+
+// NewFakeApp returns a healthy fake app, its delays use the real clock
+func NewFakeApp(name string) *FakeApp {
+	return &FakeApp{name: name, clock: sysd.RealClock, runningChange: make(chan struct{})}
+}
+
+// Name implements sysd.App
+func (a *FakeApp) Name() string {
+	return a.name
+}
+
+// FailStarts makes the next n starts return err right away
+func (a *FakeApp) FailStarts(n int, err error) *FakeApp {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		a.startErrs = append(a.startErrs, err)
+	}
+	return a
+}
+
+// PanicOnStart makes the next start panic with v
+func (a *FakeApp) PanicOnStart(v any) *FakeApp {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.panicOnStart = v
+	return a
+}
+
+// SetStatus sets the error returned by the status checks, nil is healthy
+func (a *FakeApp) SetStatus(err error) *FakeApp {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.status = err
+	return a
+}
+
+// FlapStatus makes the next status checks return the errors in order, then SetStatus applies again
+func (a *FakeApp) FlapStatus(errs ...error) *FakeApp {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.statusErrs = append(a.statusErrs, errs...)
+	return a
+}
+
+// SetStopDelay makes the app take d to return once its context is done, measured by its clock
+func (a *FakeApp) SetStopDelay(d time.Duration) *FakeApp {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stopDelay = d
+	return a
+}
+
+// Starts returns how many times the app was started
+func (a *FakeApp) Starts() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.starts
+}
+
+// StatusChecks returns how many times the app status was checked
+func (a *FakeApp) StatusChecks() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.statusChecks
+}
+
+// Cause returns why the last run of the app was cancelled, see context.Cause
+func (a *FakeApp) Cause() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cause
+}
+
+// Running returns true while the app is in Start and has not failed
+func (a *FakeApp) Running() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.running
+}
+
+// Start implements sysd.App
+func (a *FakeApp) Start(ctx context.Context) error {
+	a.mu.Lock()
+	a.starts++
+	if v := a.panicOnStart; v != nil {
+		a.panicOnStart = nil
+		a.mu.Unlock()
+		panic(v)
+	}
+	if len(a.startErrs) > 0 {
+		err := a.startErrs[0]
+		a.startErrs = a.startErrs[1:]
+		a.mu.Unlock()
+		return err
+	}
+	a.setRunning(true)
+	a.mu.Unlock()
+
+	<-ctx.Done()
+
+	a.mu.Lock()
+	a.cause = context.Cause(ctx)
+	delay := a.stopDelay
+	a.mu.Unlock()
+	if delay > 0 {
+		<-a.clock.After(delay)
+	}
+
+	a.mu.Lock()
+	a.setRunning(false)
+	a.mu.Unlock()
+	return nil
+}
+
+// Status implements sysd.App
+func (a *FakeApp) Status(_ context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.statusChecks++
+	if len(a.statusErrs) > 0 {
+		err := a.statusErrs[0]
+		a.statusErrs = a.statusErrs[1:]
+		return err
+	}
+	return a.status
+}
+
+// WaitRunning blocks until the app is running or the timeout expires, it returns false on timeout
+func (a *FakeApp) WaitRunning(timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		a.mu.Lock()
+		running, changed := a.running, a.runningChange
+		a.mu.Unlock()
+		if running {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// setRunning records whether the app runs, a.mu must be held
+func (a *FakeApp) setRunning(running bool) {
+	a.running = running
+	close(a.runningChange)
+	a.runningChange = make(chan struct{})
+}
@@ -0,0 +1,69 @@
+package sysdtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestFakeAppScriptedStartsAndStatus(t *testing.T) {
+	failed, unhealthy, down := errors.New("failed"), errors.New("unhealthy"), errors.New("down")
+	app := sysdtest.NewFakeApp("app").FailStarts(2, failed).FlapStatus(unhealthy, nil).SetStatus(down)
+
+	for i := 0; i < 2; i++ {
+		if err := app.Start(context.Background()); !errors.Is(err, failed) {
+			t.Fatalf("start %d returned %v, want %v", i, err, failed)
+		}
+	}
+	for i, want := range []error{unhealthy, nil, down, down} {
+		if err := app.Status(context.Background()); !errors.Is(err, want) {
+			t.Errorf("status check %d returned %v, want %v", i, err, want)
+		}
+	}
+	if app.Starts() != 2 || app.StatusChecks() != 4 {
+		t.Errorf("app started %d times and checked %d times, want 2 and 4", app.Starts(), app.StatusChecks())
+	}
+	if app.Running() {
+		t.Error("app running after failed starts")
+	}
+}
+
+func TestFakeAppRunsUntilCancelled(t *testing.T) {
+	app := sysdtest.NewFakeApp("app")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.Start(ctx) }()
+
+	if !app.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app not running")
+	}
+	cancel(sysd.ErrAppStopped)
+	if err := <-done; err != nil {
+		t.Fatalf("Start returned %v", err)
+	}
+	if app.Running() {
+		t.Error("app running after Start returned")
+	}
+	if !errors.Is(app.Cause(), sysd.ErrAppStopped) {
+		t.Errorf("cause is %v, want %v", app.Cause(), sysd.ErrAppStopped)
+	}
+}
+
+func TestHarnessAppStopDelayUsesClock(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	app := h.NewApp("app").SetStopDelay(10 * time.Second)
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+
+	// Stop advances the fake clock through the stop delay instead of sleeping
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop returned %v", err)
+	}
+	if app.Running() {
+		t.Error("app still running after Stop")
+	}
+}
@@ -0,0 +1,194 @@
+package sysdtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+// WaitTimeout is how long the Wait helpers wait, in real time, before failing the test
+var WaitTimeout = 5 * time.Second
+
+// pollInterval is how often WaitForState checks the app state
+const pollInterval = 5 * time.Millisecond
+
+// Harness runs a systemd service on a fake clock for a test, it is stopped when the test ends
+type Harness struct {
+	t       testing.TB
+	Systemd *sysd.Systemd
+	Clock   *Clock
+
+	events <-chan sysd.Event
+	cancel context.CancelFunc
+	// done is closed once Start returned err
+	done chan struct{}
+	err  error
+}
+
+// NewHarness returns a harness with a systemd service configured with the options, using a fake
+// clock and logging to the test log
+func NewHarness(t testing.TB, opts ...sysd.Option) *Harness {
+	t.Helper()
+
+	clock := NewClock(time.Now())
+	logger := &testLogger{t: t}
+	opts = append([]sysd.Option{sysd.WithClock(clock), sysd.WithLogger(logger)}, opts...)
+	h := &Harness{t: t, Systemd: sysd.New(opts...), Clock: clock}
+	h.events = h.Systemd.Subscribe()
+	t.Cleanup(func() {
+		h.Stop()
+		logger.close()
+	})
+	return h
+}
+
+// NewApp returns a fake app using the harness clock, added to the systemd service with the options
+func (h *Harness) NewApp(name string, opts ...sysd.AddOption) *FakeApp {
+	h.t.Helper()
+
+	app := NewFakeApp(name)
+	app.clock = h.Clock
+	if err := h.Systemd.Add(app, opts...); err != nil {
+		h.t.Fatalf("add app %q: %v", name, err)
+	}
+	return app
+}
+
+// Start starts the systemd service in the background
+func (h *Harness) Start() {
+	h.t.Helper()
+
+	if h.done != nil {
+		h.t.Fatal("harness already started")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	h.cancel, h.done = cancel, done
+	go func() {
+		h.err = h.Systemd.Start(ctx)
+		close(done)
+	}()
+}
+
+// Returned returns true if Start has returned
+func (h *Harness) Returned() bool {
+	if h.done == nil {
+		return false
+	}
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop cancels the systemd service and returns what Start returned, advancing the clock
+// through the shutdown timeouts if the apps take long to stop
+func (h *Harness) Stop() error {
+	if h.done == nil {
+		return nil
+	}
+	h.cancel()
+
+	deadline := time.After(WaitTimeout)
+	for {
+		select {
+		case <-h.done:
+			return h.err
+		case <-deadline:
+			h.t.Errorf("systemd service did not stop in %s", WaitTimeout)
+			return nil
+		case <-time.After(pollInterval):
+			h.Clock.Advance(time.Second)
+		}
+	}
+}
+
+// Wait waits for Start to return on its own, e.g. after a fatal failure, and returns its error
+func (h *Harness) Wait() error {
+	h.t.Helper()
+
+	if h.done == nil {
+		h.t.Fatal("harness not started")
+	}
+	select {
+	case <-h.done:
+		return h.err
+	case <-time.After(WaitTimeout):
+		h.t.Fatalf("systemd service did not return in %s", WaitTimeout)
+		return nil
+	}
+}
+
+// WaitForState waits until the app is in the state, failing the test otherwise
+func (h *Harness) WaitForState(app string, state sysd.AppState) {
+	h.t.Helper()
+	WaitForState(h.t, h.Systemd, app, state)
+}
+
+// WaitForEvent waits for an event of the type about the app, empty for events about the service,
+// skipping other events. it fails the test if none comes
+func (h *Harness) WaitForEvent(typ sysd.EventType, app string) sysd.Event {
+	h.t.Helper()
+
+	deadline := time.After(WaitTimeout)
+	for {
+		select {
+		case e := <-h.events:
+			if e.Type == typ && e.App == app {
+				return e
+			}
+		case <-deadline:
+			h.t.Fatalf("no %s event of app %q in %s", typ, app, WaitTimeout)
+			return sysd.Event{}
+		}
+	}
+}
+
+// WaitForState waits until the app of the systemd service is in the state, failing the test
+// if it is not within WaitTimeout
+func WaitForState(t testing.TB, s *sysd.Systemd, app string, state sysd.AppState) {
+	t.Helper()
+
+	deadline := time.Now().Add(WaitTimeout)
+	last := sysd.AppState(-1)
+	for time.Now().Before(deadline) {
+		for _, status := range s.Snapshot() {
+			if status.Name == app {
+				last = status.State
+			}
+		}
+		if last == state {
+			return
+		}
+		time.Sleep(pollInterval)
+	}
+	t.Fatalf("app %q is %s, not %s after %s", app, last, state, WaitTimeout)
+}
+
+// testLogger writes the systemd logs to the test log until the test ends, apps which are
+// still stopping must not log to a finished test
+type testLogger struct {
+	t      testing.TB
+	mu     sync.Mutex
+	closed bool
+}
+
+func (l *testLogger) Println(v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.closed {
+		l.t.Log(v...)
+	}
+}
+
+func (l *testLogger) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+}
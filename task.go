@@ -0,0 +1,109 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+)
+
+// Task is a unit which runs to completion, like a database migration or cache priming,
+// instead of running until it is stopped like an App
+type Task interface {
+	// Name returns the name of the task, unique across tasks and apps
+	Name() string
+	// Run runs the task to completion
+	Run(ctx context.Context) error
+}
+
+type funcTask struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// TaskFunc returns a Task running the given function
+func TaskFunc(name string, run func(ctx context.Context) error) Task {
+	return &funcTask{name: name, run: run}
+}
+
+func (f *funcTask) Name() string {
+	return f.name
+}
+
+func (f *funcTask) Run(ctx context.Context) error {
+	return f.run(ctx)
+}
+
+// taskApp runs a task as an app which is never health checked
+type taskApp struct {
+	Task
+}
+
+func (t *taskApp) Start(ctx context.Context) error {
+	return t.Run(ctx)
+}
+
+func (t *taskApp) Status(context.Context) error {
+	return nil
+}
+
+// AddTask adds a task which runs on every Start before the apps of its priority, and of later
+// priorities, are started. tasks are not health checked, a failed task shuts down all apps
+// unless configured otherwise with WithOnFailure, e.g. to retry it
+func (s *Systemd) AddTask(task Task, opts ...AddOption) error {
+	opts = append([]AddOption{WithOnFailure(OnFailureShutdownAll), asTask}, opts...)
+	return s.Add(&taskApp{Task: task}, opts...)
+}
+
+func asTask(app *appItem) {
+	app.task = true
+}
+
+// resetTasks marks the tasks as pending so apps do not see the result of an earlier run
+func (s *Systemd) resetTasks(apps []*appItem) {
+	for _, app := range apps {
+		if app.task {
+			s.setState(app, AppPending, nil)
+		}
+	}
+}
+
+// waitForTasks holds the app until the tasks of earlier priorities, and of its own
+// priority for apps, have finished
+func (s *Systemd) waitForTasks(ctx context.Context, app *appItem) error {
+	s.mu.RLock()
+	var tasks []*appItem
+	for _, other := range s.apps {
+		if !other.task || other == app {
+			continue
+		}
+		if other.priority < app.priority || (!app.task && other.priority == app.priority) {
+			tasks = append(tasks, other)
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ticker := s.clock.NewTicker(dependencyCheckInterval)
+	defer ticker.Stop()
+
+	for _, task := range tasks {
+		for !s.taskFinished(task) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C():
+			}
+		}
+	}
+	return nil
+}
+
+// taskFinished returns true if the task has run successfully, or failed and its failure is ignored.
+// apps after a failed task keep waiting, the task is either restarted or shuts down all apps
+func (s *Systemd) taskFinished(task *appItem) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return task.state == AppStopped || (task.state == AppFailed && errors.Is(task.lastErr, ErrAppIgnored))
+}
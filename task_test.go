@@ -0,0 +1,97 @@
+package sysd_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestTaskRunsBeforeAppsOfItsPriority(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	release := make(chan struct{})
+	migrate := sysd.TaskFunc("migrate", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-release:
+			return nil
+		}
+	})
+	if err := h.Systemd.AddTask(migrate, sysd.WithPriority(1)); err != nil {
+		t.Fatal(err)
+	}
+	early := h.NewApp("early", sysd.WithPriority(0))
+	api := h.NewApp("api", sysd.WithPriority(1))
+	h.Start()
+
+	if !early.WaitRunning(sysdtest.WaitTimeout) {
+		t.Fatal("app of an earlier priority is not running")
+	}
+	for i := 0; i < 10; i++ {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if api.Starts() != 0 {
+		t.Fatal("app started before the task of its priority finished")
+	}
+
+	close(release)
+	h.WaitForState("migrate", sysd.AppStopped)
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !api.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !api.Running() {
+		t.Fatal("app not started after the task finished")
+	}
+	if h.Returned() {
+		t.Fatal("Start returned once the task finished")
+	}
+}
+
+func TestFailedTaskShutsDownAllApps(t *testing.T) {
+	failed := errors.New("migration failed")
+	h := sysdtest.NewHarness(t)
+	if err := h.Systemd.AddTask(sysd.TaskFunc("migrate", func(context.Context) error { return failed })); err != nil {
+		t.Fatal(err)
+	}
+	h.NewApp("api")
+	h.Start()
+
+	if err := h.Wait(); !errors.Is(err, failed) {
+		t.Fatalf("Start returned %v, want the task error", err)
+	}
+}
+
+func TestTaskRetriedWithOnFailure(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	runs := 0
+	task := sysd.TaskFunc("prime", func(context.Context) error {
+		runs++
+		if runs < 3 {
+			return errors.New("cache not reachable")
+		}
+		return nil
+	})
+	if err := h.Systemd.AddTask(task, sysd.WithOnFailure(sysd.OnFailureRestart.Retry(3).RetryTimeout(time.Second))); err != nil {
+		t.Fatal(err)
+	}
+	api := h.NewApp("api")
+	h.Start()
+
+	for deadline := time.Now().Add(sysdtest.WaitTimeout); !api.Running() && time.Now().Before(deadline); {
+		h.Clock.Advance(time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !api.Running() {
+		t.Fatal("app not started after the task succeeded")
+	}
+	h.Stop()
+	if runs != 3 {
+		t.Fatalf("task ran %d times, want 3", runs)
+	}
+}
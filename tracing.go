@@ -0,0 +1,39 @@
+package sysd
+
+import "context"
+
+// Operation is a lifecycle operation of the systemd service reported to a Tracer
+type Operation string
+
+const (
+	// OperationStart is a single start of an app, until it is running or returns from Start
+	OperationStart Operation = "start"
+	// OperationRestart is a restart of an app after a failure or by RestartApp,
+	// retried starts are restarts too
+	OperationRestart Operation = "restart"
+	// OperationStatusCheck is a single status check of an app
+	OperationStatusCheck Operation = "status_check"
+	// OperationShutdown is the shutdown of all apps
+	OperationShutdown Operation = "shutdown"
+)
+
+// Tracer traces the lifecycle operations of the systemd service,
+// see the github.com/mirzakhany/sysd/otel module for an OpenTelemetry implementation
+type Tracer interface {
+	// StartSpan starts a span for the operation, app is empty for operations of the whole service.
+	// end is called once with the error the operation failed with, if any
+	StartSpan(ctx context.Context, op Operation, app string) (_ context.Context, end func(err error))
+}
+
+// SetTracer sets the tracer of lifecycle operations, nil disables tracing
+func (s *Systemd) SetTracer(t Tracer) {
+	s.tracer = t
+}
+
+// startSpan starts a span with the configured tracer, if any
+func (s *Systemd) startSpan(ctx context.Context, op Operation, app string) (context.Context, func(err error)) {
+	if s.tracer == nil {
+		return ctx, func(error) {}
+	}
+	return s.tracer.StartSpan(ctx, op, app)
+}
@@ -0,0 +1,162 @@
+package sysd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// upgradeFdsEnv holds the names of the listeners passed to the new process, one per fd starting at 3
+	upgradeFdsEnv = "SYSD_UPGRADE_FDS"
+	// upgradeReadyFdEnv holds the fd of the pipe the new process reports ready on
+	upgradeReadyFdEnv = "SYSD_UPGRADE_READY_FD"
+)
+
+// ErrUpgradeFailed is returned by Upgrade if the new process exits before reporting ready
+var ErrUpgradeFailed = errors.New("upgrade failed")
+
+// notifyUpgradeReady reports the process ready to the process which started it on Upgrade, if any
+func notifyUpgradeReady() error {
+	fd := os.Getenv(upgradeReadyFdEnv)
+	_ = os.Unsetenv(upgradeReadyFdEnv)
+	if fd == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(fd)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", upgradeReadyFdEnv, err)
+	}
+
+	f := os.NewFile(uintptr(n), "upgrade-ready")
+	defer f.Close()
+	_, err = f.Write([]byte{1})
+	return err
+}
+
+// Upgrade starts the current executable again, handing over the listeners of apps implementing
+// ListenerOwner. once the new process reports all its apps ready, this process is shut down
+// gracefully so in-flight work is drained. the service manager is told the new process is the main
+// one, systemd units need NotifyAccess=all to accept the states of the new process. if the context
+// is done first the new process is killed
+func (s *Systemd) Upgrade(ctx context.Context) error {
+	s.mu.RLock()
+	run := s.run
+	s.mu.RUnlock()
+
+	if run == nil {
+		return ErrNotRunning
+	}
+
+	names, files, err := s.listenerFiles()
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, w)
+	cmd.Env = append(os.Environ(),
+		upgradeFdsEnv+"="+strings.Join(names, ","),
+		upgradeReadyFdEnv+"="+strconv.Itoa(3+len(files)),
+	)
+
+	s.logger.Info("Upgrading, starting %s with %d listeners", path, len(files))
+	err = cmd.Start()
+	// the new process holds its own end, reads fail once it exits without reporting ready
+	_ = w.Close()
+	if err != nil {
+		return err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		ready <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return ctx.Err()
+	case err := <-ready:
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("new process exited before reporting ready")
+			}
+			_ = cmd.Wait()
+			return fmt.Errorf("%w: %v", ErrUpgradeFailed, err)
+		}
+	}
+
+	pid := cmd.Process.Pid
+	s.logger.Info("Upgrade process %d is ready, shutting down", pid)
+	// the service manager must track the new process, or it stops the service once this one exits
+	s.notify("MAINPID=" + strconv.Itoa(pid))
+	s.upgraded.Store(true)
+	_ = cmd.Process.Release()
+	requestShutdown(run.ctx, fmt.Errorf("%w: upgraded to process %d", ErrShutdownRequested, pid))
+	return nil
+}
+
+// listenerFiles returns the names and duplicated fds of the listeners of all apps, sorted by name
+func (s *Systemd) listenerFiles() ([]string, []*os.File, error) {
+	listeners := make(map[string]net.Listener)
+	for _, app := range s.appList() {
+		owner, ok := app.App.(ListenerOwner)
+		if !ok {
+			continue
+		}
+		for name, l := range owner.Listeners() {
+			if _, ok := listeners[name]; ok {
+				return nil, nil, fmt.Errorf("duplicate listener %q of app %q", name, app.Name())
+			}
+			listeners[name] = l
+		}
+	}
+
+	names := make([]string, 0, len(listeners))
+	for name := range listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]*os.File, 0, len(names))
+	for _, name := range names {
+		filer, ok := listeners[name].(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, files, fmt.Errorf("listener %q can not be handed over", name)
+		}
+		f, err := filer.File()
+		if err != nil {
+			return nil, files, fmt.Errorf("listener %q: %w", name, err)
+		}
+		files = append(files, f)
+	}
+	return names, files, nil
+}
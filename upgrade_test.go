@@ -0,0 +1,139 @@
+package sysd_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+)
+
+// pidServer is an app answering every connection on its listener with the pid of its process,
+// closing served once it answered the first one
+type pidServer struct {
+	l      net.Listener
+	served chan struct{}
+}
+
+func (a *pidServer) Name() string { return "server" }
+
+func (a *pidServer) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		_ = a.l.Close()
+	}()
+	for {
+		conn, err := a.l.Accept()
+		if err != nil {
+			return nil
+		}
+		_, _ = conn.Write([]byte(strconv.Itoa(os.Getpid())))
+		_ = conn.Close()
+		if a.served != nil {
+			close(a.served)
+			a.served = nil
+		}
+	}
+}
+
+func (a *pidServer) Status(context.Context) error { return nil }
+
+func (a *pidServer) Listeners() map[string]net.Listener {
+	return map[string]net.Listener{"http": a.l}
+}
+
+// upgradedProcess runs in the process started by Upgrade, serving on the inherited listener
+// until it answered a connection
+func upgradedProcess() {
+	time.AfterFunc(10*time.Second, func() { os.Exit(6) })
+	l, ok := sysd.InheritedListener("http")
+	if !ok {
+		os.Exit(2)
+	}
+	served := make(chan struct{})
+	s := sysd.New()
+	if err := s.Add(&pidServer{l: l, served: served}); err != nil {
+		os.Exit(3)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		os.Exit(4)
+	}
+	<-served
+	os.Exit(0)
+}
+
+// upgradingProcess runs a service owning a listener, upgrades it and prints the pid of the process
+// answering on the listener once this one shut down
+func upgradingProcess() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.Exit(2)
+	}
+	addr := l.Addr().String()
+	s := sysd.New()
+	if err := s.Add(&pidServer{l: l}); err != nil {
+		os.Exit(3)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Run(ctx); err != nil {
+		os.Exit(4)
+	}
+	for !s.Ready(ctx) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := s.Upgrade(ctx); err != nil {
+		fmt.Println(err)
+		os.Exit(5)
+	}
+	_ = s.Wait()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(6)
+	}
+	pid, _ := io.ReadAll(conn)
+	fmt.Printf("served by %s\n", pid)
+	os.Exit(0)
+}
+
+// TestUpgradeHandsOverListeners checks in child processes that the process started by Upgrade serves on the
+// listeners of the old one, and that the service manager is told it is the main process
+func TestUpgradeHandsOverListeners(t *testing.T) {
+	switch {
+	case os.Getenv("SYSD_UPGRADE_FDS") != "":
+		upgradedProcess()
+	case os.Getenv("SYSD_TEST_UPGRADING") != "":
+		upgradingProcess()
+	}
+
+	conn := listenNotify(t)
+	// Upgrade starts the executable with the same arguments, so the new process runs this test only
+	cmd := exec.Command(os.Args[0], "-test.run=^TestUpgradeHandsOverListeners$")
+	cmd.Env = append(os.Environ(), "SYSD_TEST_UPGRADING=1")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("upgrading process failed with %v: %s", err, out)
+	}
+	pid, ok := strings.CutPrefix(strings.TrimSpace(string(out)), "served by ")
+	if !ok || pid == strconv.Itoa(cmd.Process.Pid) {
+		t.Fatalf("upgrading process printed %q, want the listener served by the new process", out)
+	}
+
+	states := readNotify(t, conn, 100*time.Millisecond)
+	if count(states, "MAINPID="+pid) != 1 {
+		t.Errorf("service manager was told %q, want MAINPID=%s", states, pid)
+	}
+	// the old process leaves the service manager to the new one once it handed over
+	if count(states, "STOPPING=1") != 0 {
+		t.Errorf("service manager was told %q, want no STOPPING=1 of the old process", states)
+	}
+}
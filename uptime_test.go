@@ -0,0 +1,41 @@
+package sysd_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+func TestUptime(t *testing.T) {
+	h := sysdtest.NewHarness(t)
+	h.NewApp("app")
+
+	if _, err := h.Systemd.Uptime("missing"); err != sysd.ErrAppNotExists {
+		t.Fatalf("Uptime of a missing app returned %v, want ErrAppNotExists", err)
+	}
+	if uptime, _ := h.Systemd.Uptime("app"); uptime != 0 {
+		t.Fatalf("uptime before start is %s, want 0", uptime)
+	}
+
+	h.Start()
+	h.WaitForState("app", sysd.AppRunning)
+	h.Clock.Advance(10 * time.Second)
+	if uptime, _ := h.Systemd.Uptime("app"); uptime != 10*time.Second {
+		t.Fatalf("uptime is %s, want 10s", uptime)
+	}
+
+	if err := h.Systemd.RestartApp(context.Background(), "app"); err != nil {
+		t.Fatal(err)
+	}
+	h.WaitForState("app", sysd.AppRunning)
+	if uptime, _ := h.Systemd.Uptime("app"); uptime != 0 {
+		t.Fatalf("uptime after restart is %s, want 0", uptime)
+	}
+	h.Clock.Advance(3 * time.Second)
+	if uptime, _ := h.Systemd.Uptime("app"); uptime != 3*time.Second {
+		t.Fatalf("uptime is %s, want 3s", uptime)
+	}
+}
@@ -0,0 +1,67 @@
+package sysd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	// ErrPriorityConflict is reported by Validate for an app depending on an app with a higher
+	// priority value, which would start after it by priority
+	ErrPriorityConflict = errors.New("priority conflict")
+
+	// ErrNoRetries is reported by Validate for an app with a restart policy allowing no restarts
+	ErrNoRetries = errors.New("restart policy without retries")
+)
+
+// Validate checks the configuration of the apps without starting anything, e.g. in CI. it reports
+// duplicate names, unknown dependencies, cycles, priorities conflicting with dependencies and
+// restart policies which never restart, all problems are joined in the returned error
+func (s *Systemd) Validate() error {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.apps))
+	for name := range s.apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	seen := make(map[string]string, len(names))
+	unknownDeps := false
+	for _, name := range names {
+		app := s.apps[name]
+		if actual := app.App.Name(); actual != name {
+			errs = append(errs, fmt.Errorf("app %q is named %q now: %w", name, actual, ErrAppAlreadyExists))
+		} else if other, ok := seen[actual]; ok {
+			errs = append(errs, fmt.Errorf("apps %q and %q: %w", other, name, ErrAppAlreadyExists))
+		}
+		seen[app.App.Name()] = name
+
+		for _, depName := range app.dependsOn {
+			dep, ok := s.apps[depName]
+			if !ok {
+				unknownDeps = true
+				errs = append(errs, fmt.Errorf("dependency %q of app %q: %w", depName, name, ErrAppNotExists))
+				continue
+			}
+			if dep.priority > app.priority {
+				errs = append(errs, fmt.Errorf("app %q with priority %d depends on %q with priority %d: %w",
+					name, app.priority, depName, dep.priority, ErrPriorityConflict))
+			}
+		}
+
+		if onFailure := app.onFailure; onFailure.policy == nil && onFailure.Equal(OnFailureRestart) && onFailure.retry <= 1 {
+			errs = append(errs, fmt.Errorf("app %q retries %d times: %w", name, onFailure.retry, ErrNoRetries))
+		}
+	}
+	s.mu.RUnlock()
+
+	// unknown dependencies are reported above, checkDependencies would stop at the first one
+	if !unknownDeps {
+		if err := s.checkDependencies(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,61 @@
+package sysd_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mirzakhany/sysd"
+	"github.com/mirzakhany/sysd/sysdtest"
+)
+
+// renamedApp is a fake app whose name can change after it was added
+type renamedApp struct {
+	*sysdtest.FakeApp
+	name string
+}
+
+func (a *renamedApp) Name() string { return a.name }
+
+func TestValidate(t *testing.T) {
+	s := sysd.New()
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate of an empty service returned %v", err)
+	}
+
+	add := func(app sysd.App, opts ...sysd.AddOption) {
+		if err := s.Add(app, opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(sysdtest.NewFakeApp("db"), sysd.WithPriority(2))
+	add(sysdtest.NewFakeApp("api"), sysd.WithPriority(1), sysd.WithDependsOn("db"))
+	add(sysdtest.NewFakeApp("cache"))
+	add(sysdtest.NewFakeApp("worker"), sysd.WithDependsOn("cache"))
+	add(sysdtest.NewFakeApp("cron"), sysd.WithOnFailure(sysd.OnFailureRestart.Retry(1)))
+	renamed := &renamedApp{FakeApp: sysdtest.NewFakeApp("jobs"), name: "jobs"}
+	add(renamed)
+	renamed.name = "cron"
+	if err := s.Remove("cache", false); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.Validate()
+	for _, want := range []error{sysd.ErrPriorityConflict, sysd.ErrAppNotExists, sysd.ErrNoRetries, sysd.ErrAppAlreadyExists} {
+		if !errors.Is(err, want) {
+			t.Errorf("Validate returned %v, want %v reported", err, want)
+		}
+	}
+}
+
+func TestValidateCleanConfiguration(t *testing.T) {
+	s := sysd.New()
+	if err := s.Add(sysdtest.NewFakeApp("db")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(sysdtest.NewFakeApp("api"), sysd.WithPriority(1), sysd.WithDependsOn("db")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Validate returned %v", err)
+	}
+}